@@ -0,0 +1,249 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// CacheBackend is generic storage for cached responses
+type CacheBackend interface {
+	// Get returns cache entry with given key
+	Get(key string) (*CacheEntry, bool)
+
+	// Set saves cache entry with given key
+	Set(key string, entry *CacheEntry) error
+
+	// Delete removes cache entry with given key
+	Delete(key string) error
+}
+
+// CacheEntry contains cached API response
+type CacheEntry struct {
+	Data    json.RawMessage `json:"data"`    // raw response data
+	Created time.Time       `json:"created"` // date when response was received
+	Expiry  time.Time       `json:"expiry"`  // date when response will expire
+}
+
+// CacheStats contains cache usage statistics
+type CacheStats struct {
+	Hits   uint64 // number of requests served from cache
+	Misses uint64 // number of requests which wasn't found in cache
+}
+
+// Cache is client-side cache for API responses
+type Cache struct {
+	Backend CacheBackend  // cache storage
+	MaxAge  time.Duration // maximum age of cached responses (0 = until CacheExpiryTime)
+
+	hits   uint64
+	misses uint64
+}
+
+// MemoryCacheBackend is cache backend which keeps responses in memory
+type MemoryCacheBackend struct {
+	entries map[string]*CacheEntry
+	mx      *sync.RWMutex
+}
+
+// DiskCacheBackend is cache backend which keeps responses in files
+type DiskCacheBackend struct {
+	dir string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewMemoryCache creates new cache with in-memory backend
+func NewMemoryCache(maxAge time.Duration) *Cache {
+	return &Cache{
+		Backend: &MemoryCacheBackend{
+			entries: make(map[string]*CacheEntry),
+			mx:      &sync.RWMutex{},
+		},
+		MaxAge: maxAge,
+	}
+}
+
+// NewDiskCache creates new cache with on-disk backend
+func NewDiskCache(dir string, maxAge time.Duration) (*Cache, error) {
+	err := os.MkdirAll(dir, 0750)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{Backend: &DiskCacheBackend{dir}, MaxAge: maxAge}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get decodes cached response to given struct, returns true if valid
+// response was found
+func (c *Cache) Get(key string, result interface{}) bool {
+	return c.get(key, 0, result)
+}
+
+// Set saves response to cache, expiry is response expiration date (may be empty)
+func (c *Cache) Set(key string, data []byte, expiry time.Time) error {
+	now := time.Now()
+
+	if c.MaxAge > 0 && (expiry.IsZero() || now.Add(c.MaxAge).Before(expiry)) {
+		expiry = now.Add(c.MaxAge)
+	}
+
+	if expiry.IsZero() || !expiry.After(now) {
+		return nil
+	}
+
+	return c.Backend.Set(key, &CacheEntry{Data: data, Created: now, Expiry: expiry})
+}
+
+// Stats returns cache usage statistics
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns cache entry with given key
+func (b *MemoryCacheBackend) Get(key string) (*CacheEntry, bool) {
+	b.mx.RLock()
+	entry, ok := b.entries[key]
+	b.mx.RUnlock()
+
+	return entry, ok
+}
+
+// Set saves cache entry with given key and removes all expired entries
+func (b *MemoryCacheBackend) Set(key string, entry *CacheEntry) error {
+	now := time.Now()
+
+	b.mx.Lock()
+
+	for k, e := range b.entries {
+		if !e.Expiry.After(now) {
+			delete(b.entries, k)
+		}
+	}
+
+	b.entries[key] = entry
+	b.mx.Unlock()
+
+	return nil
+}
+
+// Delete removes cache entry with given key
+func (b *MemoryCacheBackend) Delete(key string) error {
+	b.mx.Lock()
+	delete(b.entries, key)
+	b.mx.Unlock()
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns cache entry with given key
+func (b *DiskCacheBackend) Get(key string) (*CacheEntry, bool) {
+	data, err := ioutil.ReadFile(b.getEntryFile(key))
+
+	if err != nil {
+		return nil, false
+	}
+
+	entry := &CacheEntry{}
+
+	if json.Unmarshal(data, entry) != nil {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Set saves cache entry with given key
+func (b *DiskCacheBackend) Set(key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	file := b.getEntryFile(key)
+	err = ioutil.WriteFile(file+".tmp", data, 0640)
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file+".tmp", file)
+}
+
+// Delete removes cache entry with given key
+func (b *DiskCacheBackend) Delete(key string) error {
+	err := os.Remove(b.getEntryFile(key))
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// getEntryFile returns path to file with cache entry
+func (b *DiskCacheBackend) getEntryFile(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(b.dir, hex.EncodeToString(hash[:])+".json")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// get decodes cached response if it's not older than maxAge (if set)
+func (c *Cache) get(key string, maxAge time.Duration, result interface{}) bool {
+	entry, ok := c.Backend.Get(key)
+
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return false
+	}
+
+	now := time.Now()
+
+	if !entry.Expiry.After(now) {
+		c.Backend.Delete(key)
+		atomic.AddUint64(&c.misses, 1)
+		return false
+	}
+
+	if maxAge > 0 && entry.Created.Add(maxAge).Before(now) {
+		atomic.AddUint64(&c.misses, 1)
+		return false
+	}
+
+	if json.Unmarshal(entry.Data, result) != nil {
+		c.Backend.Delete(key)
+		atomic.AddUint64(&c.misses, 1)
+		return false
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+
+	return true
+}
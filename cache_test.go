@@ -0,0 +1,79 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *SSLLabsSuite) TestMemoryCache(c *check.C) {
+	testCache(c, NewMemoryCache(0))
+}
+
+func (s *SSLLabsSuite) TestDiskCache(c *check.C) {
+	cache, err := NewDiskCache(c.MkDir(), 0)
+
+	c.Assert(err, check.IsNil)
+
+	testCache(c, cache)
+}
+
+func (s *SSLLabsSuite) TestCacheMaxAge(c *check.C) {
+	cache := NewMemoryCache(time.Minute)
+	info := &AnalyzeInfo{}
+
+	c.Assert(cache.Set("test", []byte(`{"host":"test.com"}`), time.Time{}), check.IsNil)
+	c.Assert(cache.Get("test", info), check.Equals, true)
+
+	entry, _ := cache.Backend.Get("test")
+	entry.Created = time.Now().Add(-2 * time.Hour)
+
+	c.Assert(cache.get("test", time.Hour, info), check.Equals, false)
+
+	entry.Expiry = time.Now().Add(-time.Second)
+
+	c.Assert(cache.Get("test", info), check.Equals, false)
+	c.Assert(cache.Stats(), check.DeepEquals, CacheStats{Hits: 1, Misses: 2})
+}
+
+func (s *SSLLabsSuite) TestMemoryCacheSweep(c *check.C) {
+	cache := NewMemoryCache(0)
+	backend := cache.Backend.(*MemoryCacheBackend)
+
+	backend.Set("expired", &CacheEntry{Expiry: time.Now().Add(-time.Second)})
+	backend.Set("valid", &CacheEntry{Expiry: time.Now().Add(time.Hour)})
+
+	c.Assert(backend.entries, check.HasLen, 1)
+	c.Assert(backend.entries["valid"], check.NotNil)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func testCache(c *check.C, cache *Cache) {
+	info := &AnalyzeInfo{}
+
+	c.Assert(cache.Get("test", info), check.Equals, false)
+
+	c.Assert(cache.Set("test", []byte(`{"host":"test.com"}`), time.Now().Add(time.Hour)), check.IsNil)
+	c.Assert(cache.Set("expired", []byte(`{"host":"test.com"}`), time.Now().Add(-time.Hour)), check.IsNil)
+	c.Assert(cache.Set("noexpiry", []byte(`{"host":"test.com"}`), time.Time{}), check.IsNil)
+
+	c.Assert(cache.Get("test", info), check.Equals, true)
+	c.Assert(info.Host, check.Equals, "test.com")
+	c.Assert(cache.Get("expired", info), check.Equals, false)
+	c.Assert(cache.Get("noexpiry", info), check.Equals, false)
+
+	c.Assert(cache.Backend.Delete("test"), check.IsNil)
+	c.Assert(cache.Get("test", info), check.Equals, false)
+
+	c.Assert(cache.Stats(), check.DeepEquals, CacheStats{Hits: 1, Misses: 4})
+}
@@ -0,0 +1,247 @@
+// Package cassette provides transport for recording and replaying API responses
+package cassette
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	MODE_REPLAY = "replay" // serve responses only from cassette
+	MODE_RECORD = "record" // send requests and save responses to cassette
+	MODE_AUTO   = "auto"   // replay if cassette has responses, record otherwise
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Transport is generic transport for requests
+type Transport interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Recorder is transport which records and replays request/response pairs
+type Recorder struct {
+	Transport Transport // transport for sending real requests (optional)
+
+	dir       string
+	mode      string
+	replaying bool
+	counters  map[string]int
+	mx        *sync.Mutex
+}
+
+// Interaction contains recorded request/response pair
+type Interaction struct {
+	Request  *Request  `json:"request"`
+	Response *Response `json:"response"`
+}
+
+// Request contains recorded request info
+type Request struct {
+	Method string `json:"method"`
+	URI    string `json:"uri"`
+}
+
+// Response contains recorded response info
+type Response struct {
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType"`
+	Body        string `json:"body"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// nameRegex is regexp for removing unsafe symbols from file names
+var nameRegex = regexp.MustCompile(`[^a-zA-Z0-9.=_-]+`)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new recorder which keeps interactions in given directory
+func New(dir, mode string) (*Recorder, error) {
+	switch mode {
+	case MODE_REPLAY, MODE_RECORD, MODE_AUTO:
+	default:
+		return nil, fmt.Errorf("Unknown cassette mode \"%s\"", mode)
+	}
+
+	replaying := mode == MODE_REPLAY
+
+	if mode == MODE_AUTO {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		replaying = len(files) != 0
+	}
+
+	if !replaying {
+		err := os.MkdirAll(dir, 0750)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Recorder{
+		dir:       dir,
+		mode:      mode,
+		replaying: replaying,
+		counters:  make(map[string]int),
+		mx:        &sync.Mutex{},
+	}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Mode returns recorder mode
+func (r *Recorder) Mode() string {
+	return r.mode
+}
+
+// IsReplaying returns true if recorder serves responses from cassette
+func (r *Recorder) IsReplaying() bool {
+	return r.replaying
+}
+
+// Do sends request or replays recorded response
+func (r *Recorder) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	key := getInteractionKey(req)
+	seq := r.counters[key]
+
+	r.counters[key]++
+
+	if r.replaying {
+		return r.replay(key, seq, resp)
+	}
+
+	return r.record(key, seq, req, resp)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// replay reads recorded response from cassette; if request was sent more times
+// than it was recorded, the last recorded response is used
+func (r *Recorder) replay(key string, seq int, resp *fasthttp.Response) error {
+	var file string
+
+	for i := seq; i >= 0; i-- {
+		file = r.getInteractionFile(key, i)
+
+		if _, err := os.Stat(file); err == nil {
+			break
+		}
+
+		file = ""
+	}
+
+	if file == "" {
+		return fmt.Errorf("Cassette doesn't contain response for %s", key)
+	}
+
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return err
+	}
+
+	interaction := &Interaction{}
+	err = json.Unmarshal(data, interaction)
+
+	if err != nil {
+		return fmt.Errorf("Can't decode interaction %s: %v", file, err)
+	}
+
+	resp.SetStatusCode(interaction.Response.StatusCode)
+	resp.Header.SetContentType(interaction.Response.ContentType)
+	resp.SetBodyString(interaction.Response.Body)
+
+	return nil
+}
+
+// record sends request using real transport and saves response to cassette
+func (r *Recorder) record(key string, seq int, req *fasthttp.Request, resp *fasthttp.Response) error {
+	err := r.getTransport().Do(req, resp)
+
+	if err != nil {
+		return err
+	}
+
+	interaction := &Interaction{
+		Request: &Request{
+			Method: string(req.Header.Method()),
+			URI:    string(req.RequestURI()),
+		},
+		Response: &Response{
+			StatusCode:  resp.StatusCode(),
+			ContentType: string(resp.Header.ContentType()),
+			Body:        string(resp.Body()),
+		},
+	}
+
+	data, err := json.MarshalIndent(interaction, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.getInteractionFile(key, seq), data, 0640)
+}
+
+// getTransport returns transport for sending real requests
+func (r *Recorder) getTransport() Transport {
+	if r.Transport == nil {
+		r.Transport = &fasthttp.Client{
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+	}
+
+	return r.Transport
+}
+
+// getInteractionFile returns path to file with interaction
+func (r *Recorder) getInteractionFile(key string, seq int) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%s-%03d.json", key, seq))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getInteractionKey returns unique key for request
+func getInteractionKey(req *fasthttp.Request) string {
+	uri := req.URI()
+	name := string(uri.Path())
+
+	if len(uri.QueryString()) != 0 {
+		name += "_" + string(uri.QueryString())
+	}
+
+	name = strings.Trim(nameRegex.ReplaceAllString(name, "_"), "_")
+
+	if len(name) > 96 {
+		name = name[:96]
+	}
+
+	hash := sha1.Sum([]byte(string(req.Header.Method()) + " " + string(uri.RequestURI())))
+
+	return name + "-" + hex.EncodeToString(hash[:4])
+}
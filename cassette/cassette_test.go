@@ -0,0 +1,105 @@
+package cassette
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type CassetteSuite struct{}
+
+type fakeTransport struct {
+	calls int
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = check.Suite(&CassetteSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *CassetteSuite) TestErrors(c *check.C) {
+	_, err := New(c.MkDir(), "unknown")
+	c.Assert(err, check.NotNil)
+
+	rec, err := New(c.MkDir(), MODE_REPLAY)
+	c.Assert(err, check.IsNil)
+	c.Assert(rec.Mode(), check.Equals, MODE_REPLAY)
+
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/info"), check.Equals, "")
+}
+
+func (s *CassetteSuite) TestRecordReplay(c *check.C) {
+	dir := c.MkDir()
+	transport := &fakeTransport{}
+
+	rec, err := New(dir, MODE_AUTO)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(rec.IsReplaying(), check.Equals, false)
+
+	rec.Transport = transport
+
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/info"), check.Equals, `{"call":1}`)
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/analyze?host=test.com"), check.Equals, `{"call":2}`)
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/analyze?host=test.com"), check.Equals, `{"call":3}`)
+	c.Assert(transport.calls, check.Equals, 3)
+
+	rec, err = New(dir, MODE_AUTO)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(rec.IsReplaying(), check.Equals, true)
+
+	rec.Transport = transport
+
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/info"), check.Equals, `{"call":1}`)
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/analyze?host=test.com"), check.Equals, `{"call":2}`)
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/analyze?host=test.com"), check.Equals, `{"call":3}`)
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/analyze?host=test.com"), check.Equals, `{"call":3}`)
+	c.Assert(doRequest(rec, "https://api.ssllabs.com/api/v3/analyze?host=unknown.com"), check.Equals, "")
+	c.Assert(transport.calls, check.Equals, 3)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (t *fakeTransport) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	t.calls++
+
+	resp.SetStatusCode(200)
+	resp.Header.SetContentType("application/json")
+	resp.SetBodyString(fmt.Sprintf(`{"call":%d}`, t.calls))
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func doRequest(t Transport, uri string) string {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(uri)
+
+	if t.Do(req, resp) != nil {
+		return ""
+	}
+
+	return string(resp.Body())
+}
@@ -0,0 +1,265 @@
+package main
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/local"
+	"pkg.re/essentialkaos/sslscan.v12/report"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	PROBLEM_API_ERROR        = "api-error"
+	PROBLEM_ASSESSMENT_ERROR = "assessment-error"
+	PROBLEM_GRADE            = "grade"
+	PROBLEM_VULN             = "vuln"
+	PROBLEM_CERT_EXPIRY      = "cert-expiry"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// checkRules contains rules for "check" command
+type checkRules struct {
+	minGrade   string
+	vuln       bool
+	certExpiry time.Duration
+}
+
+// checkProblem contains info about failed check
+type checkProblem struct {
+	Host    string `json:"host"`
+	IP      string `json:"ip,omitempty"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// checkResult contains "check" command result
+type checkResult struct {
+	Passed   bool            `json:"passed"`
+	Hosts    int             `json:"hosts"`
+	Problems []*checkProblem `json:"problems"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// problemExitCodes contains exit codes for problems ordered by priority
+var problemExitCodes = []struct {
+	problem  string
+	exitCode int
+}{
+	{PROBLEM_API_ERROR, EC_ERROR},
+	{PROBLEM_ASSESSMENT_ERROR, EC_ASSESSMENT_ERROR},
+	{PROBLEM_GRADE, EC_GRADE},
+	{PROBLEM_VULN, EC_VULN},
+	{PROBLEM_CERT_EXPIRY, EC_CERT_EXPIRY},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdCheck is handler for "check" command
+func cmdCheck(opts *options, args []string) int {
+	rules, err := parseCheckRules(opts.minGrade, opts.failOn)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_USAGE
+	}
+
+	// Local scanner doesn't grade endpoints, so grade check always fails
+	if rules.minGrade != "" && opts.backend == local.SCANNER_LOCAL {
+		printError("Option -min-grade can't be used with local backend")
+		return EC_USAGE
+	}
+
+	scanner, err := newScanner(opts)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	result := &checkResult{Hosts: len(args), Problems: []*checkProblem{}}
+
+	for _, host := range args {
+		info, err := scanner.Scan(host, opts.params)
+
+		if err != nil {
+			result.Problems = append(result.Problems, &checkProblem{
+				Host: host, Type: PROBLEM_API_ERROR, Message: err.Error(),
+			})
+
+			continue
+		}
+
+		result.Problems = append(result.Problems, checkHost(info, rules, time.Now())...)
+	}
+
+	result.Passed = len(result.Problems) == 0
+
+	err = render(opts, result, func() { printCheckResult(result) })
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	return getCheckExitCode(result.Problems)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseCheckRules parses minimal grade and comma-separated list of failure
+// conditions (e.g. "vuln,cert-expiry<30d")
+func parseCheckRules(minGrade, failOn string) (*checkRules, error) {
+	rules := &checkRules{minGrade: minGrade}
+
+	if minGrade != "" && !sslscan.IsValidGrade(minGrade) {
+		return nil, fmt.Errorf("Unknown grade \"%s\"", minGrade)
+	}
+
+	for _, cond := range strings.Split(failOn, ",") {
+		cond = strings.TrimSpace(cond)
+
+		switch {
+		case cond == "":
+			continue
+
+		case cond == PROBLEM_VULN:
+			rules.vuln = true
+
+		case strings.HasPrefix(cond, PROBLEM_CERT_EXPIRY+"<"):
+			dur, err := parseDuration(strings.TrimPrefix(cond, PROBLEM_CERT_EXPIRY+"<"))
+
+			if err != nil {
+				return nil, fmt.Errorf("Can't parse condition \"%s\": %v", cond, err)
+			}
+
+			rules.certExpiry = dur
+
+		default:
+			return nil, fmt.Errorf("Unknown condition \"%s\"", cond)
+		}
+	}
+
+	return rules, nil
+}
+
+// parseDuration parses duration with support of days (e.g. "30d")
+func parseDuration(value string) (time.Duration, error) {
+	if !strings.HasSuffix(value, "d") {
+		return time.ParseDuration(value)
+	}
+
+	days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid number of days \"%s\"", value)
+	}
+
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// checkHost checks assessment results using given rules
+func checkHost(info *sslscan.AnalyzeInfo, rules *checkRules, now time.Time) []*checkProblem {
+	var result []*checkProblem
+
+	if info.Status == sslscan.STATUS_ERROR {
+		return append(result, &checkProblem{
+			Host: info.Host, Type: PROBLEM_ASSESSMENT_ERROR,
+			Message: "Assessment failed: " + formatValue(info.StatusMessage),
+		})
+	}
+
+	for _, endpoint := range info.Endpoints {
+		addProblem := func(problemType, message string, a ...interface{}) {
+			result = append(result, &checkProblem{
+				Host: info.Host, IP: endpoint.IPAdress,
+				Type: problemType, Message: fmt.Sprintf(message, a...),
+			})
+		}
+
+		if rules.minGrade != "" && sslscan.CompareGrades(endpoint.Grade, rules.minGrade) < 0 {
+			if endpoint.Grade == "" {
+				addProblem(PROBLEM_GRADE, "No grade (%s)", formatValue(endpoint.StatusMessage))
+			} else {
+				addProblem(PROBLEM_GRADE, "Grade %s is lower than %s", endpoint.Grade, rules.minGrade)
+			}
+		}
+
+		if endpoint.Details == nil {
+			continue
+		}
+
+		if rules.vuln {
+			for _, vuln := range sslscan.FindVulnerabilities(endpoint.Details) {
+				addProblem(PROBLEM_VULN, "Vulnerable to %s (%v)", vuln.Name, vuln.Value)
+			}
+		}
+
+		if rules.certExpiry > 0 {
+			for _, cert := range report.LeafCerts(info, endpoint) {
+				notAfter := time.Unix(0, cert.NotAfter*int64(time.Millisecond))
+				left := notAfter.Sub(now)
+
+				if left >= rules.certExpiry {
+					continue
+				}
+
+				if left < 0 {
+					addProblem(PROBLEM_CERT_EXPIRY, "Certificate \"%s\" expired %d days ago", report.CertName(cert), int(-left.Hours()/24))
+				} else {
+					addProblem(PROBLEM_CERT_EXPIRY, "Certificate \"%s\" expires in %d days", report.CertName(cert), int(left.Hours()/24))
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// getCheckExitCode returns exit code for the most important problem
+func getCheckExitCode(problems []*checkProblem) int {
+	for _, pe := range problemExitCodes {
+		for _, problem := range problems {
+			if problem.Type == pe.problem {
+				return pe.exitCode
+			}
+		}
+	}
+
+	return EC_OK
+}
+
+// printCheckResult prints check result with summary
+func printCheckResult(result *checkResult) {
+	if result.Passed {
+		fmt.Fprintf(output, "OK: all checks passed for %d host(s)\n", result.Hosts)
+		return
+	}
+
+	w := newTabWriter()
+
+	for _, problem := range result.Problems {
+		fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\n",
+			problem.Host, formatValue(problem.IP),
+			problem.Type, problem.Message,
+		)
+	}
+
+	w.Flush()
+
+	fmt.Fprintf(output, "\nFAIL: %d problem(s) found\n", len(result.Problems))
+}
@@ -0,0 +1,399 @@
+// Command sslscan is command-line tool for working with SSLLabs public API
+package main
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/exporter"
+	"pkg.re/essentialkaos/sslscan.v12/local"
+	"pkg.re/essentialkaos/sslscan.v12/report"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	APP  = "sslscan-cli"
+	DESC = "Command-line tool for working with SSLLabs public API"
+)
+
+const (
+	CMD_INFO     = "info"
+	CMD_SCAN     = "scan"
+	CMD_ENDPOINT = "endpoint"
+	CMD_CHECK    = "check"
+	CMD_EXPORTER = "exporter"
+	CMD_HELP     = "help"
+	CMD_VERSION  = "version"
+)
+
+const (
+	FORMAT_TABLE    = "table"
+	FORMAT_JSON     = "json"
+	FORMAT_JUNIT    = "junit"
+	FORMAT_SARIF    = "sarif"
+	FORMAT_HTML     = "html"
+	FORMAT_MARKDOWN = "markdown"
+	FORMAT_TEXT     = "text"
+	FORMAT_CSV      = "csv"
+	FORMAT_TSV      = "tsv"
+)
+
+const (
+	EC_OK               = 0 // all checks passed
+	EC_ERROR            = 1 // API error
+	EC_USAGE            = 2 // invalid command usage
+	EC_ASSESSMENT_ERROR = 3 // assessment finished with ERROR status
+	EC_GRADE            = 4 // grade is lower than required
+	EC_VULN             = 5 // vulnerability found
+	EC_CERT_EXPIRY      = 6 // certificate expires soon
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// options contains command-line options
+type options struct {
+	format     string
+	url        string
+	interval   time.Duration
+	detailed   bool
+	noProgress bool
+	verbosity  int
+	columns    string
+	listen     string
+	backend    string
+	port       int
+	caBundle   string
+	simClients string
+	startTLS   string
+	period     time.Duration
+	timeout    time.Duration
+	minGrade   string
+	failOn     string
+	params     sslscan.AnalyzeParams
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses command-line arguments and runs command
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return EC_USAGE
+	}
+
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case CMD_INFO:
+		return runCommand(cmd, args, 0, cmdInfo)
+	case CMD_SCAN:
+		return runCommand(cmd, args, 1, cmdScan)
+	case CMD_ENDPOINT:
+		return runCommand(cmd, args, 2, cmdEndpoint)
+	case CMD_CHECK:
+		return runCommand(cmd, args, 1, cmdCheck)
+	case CMD_EXPORTER:
+		return runCommand(cmd, args, 1, cmdExporter)
+	case CMD_VERSION, "-v", "--version":
+		fmt.Printf("%s %s\n", APP, sslscan.VERSION)
+		return EC_OK
+	case CMD_HELP, "-h", "--help":
+		printUsage()
+		return EC_OK
+	}
+
+	printError("Unknown command \"%s\"", cmd)
+	printUsage()
+
+	return EC_USAGE
+}
+
+// runCommand parses command options and runs command handler
+func runCommand(cmd string, args []string, minArgs int, handler func(*options, []string) int) int {
+	opts := &options{}
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+
+	fs.StringVar(&opts.format, "format", FORMAT_TABLE, "Output format (table, json, or junit, sarif, html, markdown, text, csv and tsv for scan command)")
+	fs.StringVar(&opts.url, "url", "", "Custom API URL")
+	fs.Float64Var(&sslscan.RequestTimeout, "timeout", sslscan.RequestTimeout, "Request timeout in seconds")
+
+	if cmd != CMD_INFO {
+		fs.BoolVar(&opts.params.Public, "public", false, "Publish results on the public results boards")
+		fs.BoolVar(&opts.params.StartNew, "start-new", false, "Start new assessment even if cached results are available")
+		fs.BoolVar(&opts.params.FromCache, "from-cache", false, "Deliver cached assessment results if available")
+		fs.IntVar(&opts.params.MaxAge, "max-age", 0, "Maximum report age, in hours, if retrieving from cache")
+		fs.BoolVar(&opts.params.IgnoreMismatch, "ignore-mismatch", false, "Proceed with assessments even when the server certificate doesn't match the assessment hostname")
+	}
+
+	if cmd != CMD_INFO {
+		fs.DurationVar(&opts.interval, "interval", 5*time.Second, "Status polling interval")
+	}
+
+	if cmd == CMD_SCAN || cmd == CMD_CHECK || cmd == CMD_EXPORTER {
+		fs.StringVar(&opts.backend, "backend", sslscan.SCANNER_SSLLABS, "Assessment backend (ssllabs or local)")
+		fs.IntVar(&opts.port, "port", 0, "Server port for local backend")
+		fs.StringVar(&opts.caBundle, "ca-bundle", "", "PEM bundle with private root certificates for local backend")
+		fs.StringVar(&opts.startTLS, "starttls", "", "Application protocol for STARTTLS with local backend (smtp, imap, pop3, ftp, ldap, xmpp or postgres)")
+		fs.StringVar(&opts.simClients, "sim-clients", "", "JSON file with additional simulated clients for local backend")
+	}
+
+	if cmd == CMD_SCAN || cmd == CMD_CHECK {
+		fs.BoolVar(&opts.noProgress, "no-progress", false, "Disable progress bar")
+	}
+
+	switch cmd {
+	case CMD_SCAN:
+		fs.BoolVar(&opts.detailed, "detailed", true, "Retrieve detailed endpoint info")
+		fs.IntVar(&opts.verbosity, "verbosity", report.VERBOSITY_NORMAL, "Verbosity of markdown and text reports (1-3)")
+		fs.StringVar(&opts.columns, "columns", "", "Comma-separated list of columns for csv and tsv output (e.g. ip=endpoint.ipAddress,details.forwardSecrecy)")
+	case CMD_CHECK:
+		fs.StringVar(&opts.minGrade, "min-grade", "", "Minimal required grade (e.g. A)")
+		fs.StringVar(&opts.failOn, "fail-on", "", "Comma-separated list of failure conditions (vuln, cert-expiry<{duration})")
+		opts.detailed = true
+	case CMD_EXPORTER:
+		fs.StringVar(&opts.listen, "listen", ":9211", "Address for metrics HTTP server")
+		fs.DurationVar(&opts.period, "period", 6*time.Hour, "Interval between assessments of all hosts")
+		fs.DurationVar(&opts.timeout, "scan-timeout", time.Hour, "Max duration of single host assessment")
+	}
+
+	err := fs.Parse(args)
+
+	if err != nil {
+		return EC_USAGE
+	}
+
+	if opts.backend != "" && opts.backend != sslscan.SCANNER_SSLLABS && opts.backend != local.SCANNER_LOCAL {
+		printError("Unsupported backend \"%s\"", opts.backend)
+		return EC_USAGE
+	}
+
+	if !isFormatSupported(cmd, opts.format) {
+		printError("Unsupported output format \"%s\"", opts.format)
+		return EC_USAGE
+	}
+
+	if fs.NArg() < minArgs {
+		printError("Command \"%s\" requires at least %d argument(s)", cmd, minArgs)
+		fs.Usage()
+		return EC_USAGE
+	}
+
+	return handler(opts, fs.Args())
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// cmdInfo is handler for "info" command
+func cmdInfo(opts *options, args []string) int {
+	api, err := newAPI(opts)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	err = render(opts, api.Info, func() { printInfo(api.Info) })
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	return EC_OK
+}
+
+// cmdScan is handler for "scan" command
+func cmdScan(opts *options, args []string) int {
+	if opts.columns != "" {
+		_, err := report.ParseColumns(opts.columns)
+
+		if err != nil {
+			printError("%v", err)
+			return EC_USAGE
+		}
+	}
+
+	scanner, err := newScanner(opts)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	var results []*sslscan.AnalyzeInfo
+
+	ec := EC_OK
+
+	for _, host := range args {
+		info, err := scanner.Scan(host, opts.params)
+
+		if err != nil {
+			printError("Can't check %s: %v", host, err)
+			ec = EC_ERROR
+			continue
+		}
+
+		results = append(results, info)
+	}
+
+	err = renderResults(opts, results)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	return ec
+}
+
+// cmdEndpoint is handler for "endpoint" command
+func cmdEndpoint(opts *options, args []string) int {
+	api, err := newAPI(opts)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	progress, err := api.Analyze(args[0], opts.params)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	// Endpoint data is available only after assessment is completed
+	for {
+		info, err := progress.Info(false, opts.params.FromCache)
+
+		if err != nil {
+			printError("%v", err)
+			return EC_ERROR
+		}
+
+		if info.Status == sslscan.STATUS_ERROR {
+			printError("Can't check %s: %s", args[0], info.StatusMessage)
+			return EC_ERROR
+		}
+
+		if info.Status == sslscan.STATUS_READY {
+			break
+		}
+
+		time.Sleep(opts.interval)
+	}
+
+	info, err := progress.GetEndpointInfo(args[1], opts.params.FromCache)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	err = render(opts, info, func() { printEndpointInfo(info) })
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	return EC_OK
+}
+
+// cmdExporter is handler for "exporter" command
+func cmdExporter(opts *options, args []string) int {
+	opts.noProgress = true
+
+	scanner, err := newScanner(opts)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	exp := exporter.New(scanner, args)
+	exp.Params = opts.params
+	exp.Interval = opts.period
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exp)
+
+	go exp.Run(nil)
+
+	fmt.Fprintf(os.Stderr, "Serving metrics on %s/metrics\n", opts.listen)
+
+	err = http.ListenAndServe(opts.listen, mux)
+
+	if err != nil {
+		printError("%v", err)
+		return EC_ERROR
+	}
+
+	return EC_OK
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newAPI creates new API client
+func newAPI(opts *options) (*sslscan.API, error) {
+	return sslscan.NewAPIWithOptions(APP, sslscan.VERSION, sslscan.Options{URL: opts.url})
+}
+
+// isFormatSupported returns true if command supports given output format
+func isFormatSupported(cmd, format string) bool {
+	switch format {
+	case FORMAT_TABLE, FORMAT_JSON:
+		return true
+	case FORMAT_JUNIT, FORMAT_SARIF, FORMAT_HTML, FORMAT_MARKDOWN, FORMAT_TEXT, FORMAT_CSV, FORMAT_TSV:
+		return cmd == CMD_SCAN
+	}
+
+	return false
+}
+
+// printUsage prints usage info
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "%s %s - %s\n\n", APP, sslscan.VERSION, DESC)
+	fmt.Fprintln(os.Stderr, "Usage: sslscan {command} {options} {args}")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "  info              Show engine and criteria versions and client limits")
+	fmt.Fprintln(os.Stderr, "  scan host…        Run assessment and wait for results")
+	fmt.Fprintln(os.Stderr, "  endpoint host ip  Wait for assessment and show detailed endpoint info")
+	fmt.Fprintln(os.Stderr, "  check host…       Run assessment and check results (for CI)")
+	fmt.Fprintln(os.Stderr, "  exporter host…    Periodically assess hosts and serve Prometheus metrics")
+	fmt.Fprintln(os.Stderr, "  version           Show version")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Run \"sslscan {command} -h\" for command options.")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Exit codes of \"check\" command:")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "  0  All checks passed")
+	fmt.Fprintln(os.Stderr, "  1  API error")
+	fmt.Fprintln(os.Stderr, "  3  Assessment finished with ERROR status")
+	fmt.Fprintln(os.Stderr, "  4  Grade is lower than required")
+	fmt.Fprintln(os.Stderr, "  5  Vulnerability found")
+	fmt.Fprintln(os.Stderr, "  6  Certificate expires soon")
+}
+
+// printError prints error message to stderr
+func printError(f string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Error: "+f+"\n", a...)
+}
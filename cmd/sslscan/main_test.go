@@ -0,0 +1,308 @@
+package main
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/sslscantest"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type CLISuite struct {
+	srv *sslscantest.Server
+	buf *bytes.Buffer
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = check.Suite(&CLISuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *CLISuite) SetUpTest(c *check.C) {
+	s.srv = sslscantest.NewServer()
+	s.srv.ProgressSteps = 1
+	s.buf = &bytes.Buffer{}
+
+	output = s.buf
+
+	err := s.srv.AddHostFromFiles("essentialkaos.com", "../../responses/v3-2.1.3-2009q.json")
+
+	c.Assert(err, check.IsNil)
+}
+
+func (s *CLISuite) TearDownTest(c *check.C) {
+	s.srv.Close()
+	output = os.Stdout
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *CLISuite) TestUsage(c *check.C) {
+	c.Assert(run(nil), check.Equals, EC_USAGE)
+	c.Assert(run([]string{"unknown"}), check.Equals, EC_USAGE)
+	c.Assert(run([]string{"help"}), check.Equals, EC_OK)
+	c.Assert(run([]string{"scan"}), check.Equals, EC_USAGE)
+	c.Assert(run([]string{"info", "-format", "xml"}), check.Equals, EC_USAGE)
+	c.Assert(run([]string{"info", "-unknown"}), check.Equals, EC_USAGE)
+	c.Assert(run([]string{"exporter", "-listen", ":0"}), check.Equals, EC_USAGE)
+	c.Assert(run([]string{"scan", "-backend", "unknown", "essentialkaos.com"}), check.Equals, EC_USAGE)
+}
+
+func (s *CLISuite) TestInfo(c *check.C) {
+	c.Assert(run([]string{"info", "-url", s.srv.URL}), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, "(?s)Engine version: +2.1.5.*")
+
+	s.buf.Reset()
+
+	c.Assert(run([]string{"info", "-url", s.srv.URL, "-format", "json"}), check.Equals, EC_OK)
+
+	info := &sslscan.Info{}
+
+	c.Assert(json.Unmarshal(s.buf.Bytes(), info), check.IsNil)
+	c.Assert(info.CriteriaVersion, check.Equals, "2009q")
+}
+
+func (s *CLISuite) TestScan(c *check.C) {
+	args := []string{"scan", "-url", s.srv.URL, "-interval", "1ms", "-no-progress"}
+
+	c.Assert(run(append(args, "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, "(?s).*essentialkaos.com +5.79.108.150 +curie.kaos.cc +A\\+ +TLS 1.2, TLS 1.3 +READY.*")
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-format", "json", "-start-new", "essentialkaos.com", "unknown.com")), check.Equals, EC_OK)
+
+	var results []*sslscan.AnalyzeInfo
+
+	c.Assert(json.Unmarshal(s.buf.Bytes(), &results), check.IsNil)
+	c.Assert(results, check.HasLen, 2)
+	c.Assert(results[0].Endpoints[0].Details, check.NotNil)
+	c.Assert(results[1].Status, check.Equals, sslscan.STATUS_ERROR)
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-format", "junit", "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, `(?s).*<testsuite name="essentialkaos.com:443" tests="20".*`)
+	c.Assert(run([]string{"info", "-format", "junit"}), check.Equals, EC_USAGE)
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-format", "sarif", "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, `(?s).*"version": "2.1.0".*`)
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-format", "html", "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, `(?s)<!DOCTYPE html>.*<title>SSL Report: essentialkaos.com</title>.*`)
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-format", "markdown", "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, "(?s)# essentialkaos.com:443\n.*- \\*\\*Grade:\\*\\* A\\+\n.*")
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-format", "text", "-verbosity", "1", "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, "(?s)HOST +IP +GRADE.*\nessentialkaos.com +5.79.108.150 +A\\+ .*")
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-format", "tsv", "-columns", "ip=endpoint.ipAddress,grade=endpoint.grade", "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Equals, "ip\tgrade\n5.79.108.150\tA+\n")
+	c.Assert(run(append(args, "-format", "csv", "-columns", "unknown", "essentialkaos.com")), check.Equals, EC_USAGE)
+
+	s.srv.InjectError(503, 1)
+
+	c.Assert(run(append(args, "essentialkaos.com")), check.Equals, EC_ERROR)
+}
+
+func (s *CLISuite) TestLocalScan(c *check.C) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	port := strconv.Itoa(srv.Listener.Addr().(*net.TCPAddr).Port)
+
+	c.Assert(run([]string{"scan", "-backend", "local", "-port", port, "-format", "json", "127.0.0.1"}), check.Equals, EC_OK)
+
+	var results []*sslscan.AnalyzeInfo
+
+	c.Assert(json.Unmarshal(s.buf.Bytes(), &results), check.IsNil)
+	c.Assert(results, check.HasLen, 1)
+	c.Assert(results[0].Endpoints[0].Details.Protocols, check.Not(check.HasLen), 0)
+
+	bundle := c.MkDir() + "/ca.pem"
+	ioutil.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+
+	s.buf.Reset()
+
+	c.Assert(run([]string{"scan", "-backend", "local", "-port", port, "-ca-bundle", bundle, "-format", "json", "127.0.0.1"}), check.Equals, EC_OK)
+	c.Assert(json.Unmarshal(s.buf.Bytes(), &results), check.IsNil)
+
+	trust := results[0].Endpoints[0].Details.CertChains[0].TrustPaths[0].Trust
+
+	c.Assert(trust, check.HasLen, 2)
+	c.Assert(trust[1].RootStore, check.Equals, ROOT_STORE_PRIVATE)
+	c.Assert(trust[1].IsTrusted, check.Equals, true)
+
+	c.Assert(run([]string{"scan", "-backend", "local", "-ca-bundle", bundle + ".unknown", "127.0.0.1"}), check.Equals, EC_ERROR)
+
+	clients := c.MkDir() + "/clients.json"
+	ioutil.WriteFile(clients, []byte(`[{"id":1000,"name":"MyApp","protocols":[771],"suites":["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"],"groups":["x25519"]}]`), 0644)
+
+	s.buf.Reset()
+
+	c.Assert(run([]string{"scan", "-backend", "local", "-port", port, "-sim-clients", clients, "-format", "json", "127.0.0.1"}), check.Equals, EC_OK)
+	c.Assert(json.Unmarshal(s.buf.Bytes(), &results), check.IsNil)
+
+	sims := results[0].Endpoints[0].Details.SIMS.Results
+	sim := sims[len(sims)-1]
+
+	c.Assert(sim.Client.ID, check.Equals, 1000)
+	c.Assert(sim.ErrorCode, check.Equals, 0)
+	c.Assert(sim.ProtocolID, check.Equals, sslscan.PROTOCOL_TLS12)
+
+	c.Assert(run([]string{"scan", "-backend", "local", "-sim-clients", clients + ".unknown", "127.0.0.1"}), check.Equals, EC_ERROR)
+}
+
+func (s *CLISuite) TestLocalStartTLS(c *check.C) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	c.Assert(err, check.IsNil)
+
+	defer listener.Close()
+
+	// Minimal PostgreSQL server which accepts SSLRequest
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				conn.Read(make([]byte, 8))
+				conn.Write([]byte("S"))
+
+				tls.Server(conn, srv.TLS).Handshake()
+			}()
+		}
+	}()
+
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	c.Assert(run([]string{"scan", "-backend", "local", "-starttls", "postgres", "-port", port, "-format", "json", "127.0.0.1"}), check.Equals, EC_OK)
+
+	var results []*sslscan.AnalyzeInfo
+
+	c.Assert(json.Unmarshal(s.buf.Bytes(), &results), check.IsNil)
+	c.Assert(results, check.HasLen, 1)
+	c.Assert(results[0].Protocol, check.Equals, "postgres")
+	c.Assert(results[0].Endpoints[0].Details.Protocols, check.Not(check.HasLen), 0)
+
+	c.Assert(run([]string{"scan", "-backend", "local", "-starttls", "gopher", "127.0.0.1"}), check.Equals, EC_ERROR)
+}
+
+func (s *CLISuite) TestEndpoint(c *check.C) {
+	s.srv.ProgressSteps = 3
+
+	args := []string{"endpoint", "-url", s.srv.URL, "-interval", "1ms"}
+
+	c.Assert(run(append(args, "essentialkaos.com", "5.79.108.150")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Matches, "(?s).*Suite: +TLS 1.2 TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 \\(256 bits\\).*")
+
+	c.Assert(run(append(args, "essentialkaos.com", "127.0.0.1")), check.Equals, EC_ERROR)
+	c.Assert(run(append(args, "unknown.com", "127.0.0.1")), check.Equals, EC_ERROR)
+}
+
+func (s *CLISuite) TestCheck(c *check.C) {
+	args := []string{"check", "-url", s.srv.URL, "-interval", "1ms", "-no-progress"}
+
+	c.Assert(run(append(args, "-min-grade", "A", "-fail-on", "vuln", "essentialkaos.com")), check.Equals, EC_OK)
+	c.Assert(s.buf.String(), check.Equals, "OK: all checks passed for 1 host(s)\n")
+
+	c.Assert(run(append(args, "-min-grade", "Z", "essentialkaos.com")), check.Equals, EC_USAGE)
+	c.Assert(run(append(args, "-fail-on", "cert-expiry<X", "essentialkaos.com")), check.Equals, EC_USAGE)
+	c.Assert(run(append(args, "-fail-on", "unknown", "essentialkaos.com")), check.Equals, EC_USAGE)
+	c.Assert(run(append(args, "-backend", "local", "-min-grade", "A", "127.0.0.1")), check.Equals, EC_USAGE)
+
+	endpoint, err := sslscantest.LoadEndpoint("../../responses/v3-2.1.3-2009q.json")
+
+	c.Assert(err, check.IsNil)
+
+	endpoint.Grade = "B"
+	endpoint.Details.Poodle = true
+
+	s.srv.AddHost(&sslscan.AnalyzeInfo{
+		Host:      "bad.com",
+		Status:    sslscan.STATUS_READY,
+		Endpoints: []*sslscan.EndpointInfo{endpoint},
+		Certs: []*sslscan.Cert{
+			{
+				ID:          endpoint.Details.CertChains[0].CertIDs[0],
+				CommonNames: []string{"bad.com"},
+				NotAfter:    time.Now().Add(240*time.Hour).Unix() * 1000,
+			},
+			{
+				ID:          endpoint.Details.CertChains[0].CertIDs[1],
+				CommonNames: []string{"Bad CA"},
+				NotAfter:    time.Now().Add(-240*time.Hour).Unix() * 1000,
+			},
+		},
+	})
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-fail-on", "cert-expiry<30d", "bad.com")), check.Equals, EC_CERT_EXPIRY)
+	c.Assert(s.buf.String(), check.Matches, "(?s).*cert-expiry +Certificate \"bad.com\" expires in 9 days.*")
+	c.Assert(s.buf.String(), check.Not(check.Matches), "(?s).*Bad CA.*")
+
+	c.Assert(run(append(args, "-fail-on", "vuln,cert-expiry<30d", "bad.com")), check.Equals, EC_VULN)
+	c.Assert(run(append(args, "-min-grade", "A", "-fail-on", "vuln", "bad.com")), check.Equals, EC_GRADE)
+
+	s.buf.Reset()
+
+	c.Assert(run(append(args, "-min-grade", "A", "-format", "json", "bad.com", "unknown.com")), check.Equals, EC_ASSESSMENT_ERROR)
+
+	result := &checkResult{}
+
+	c.Assert(json.Unmarshal(s.buf.Bytes(), result), check.IsNil)
+	c.Assert(result.Passed, check.Equals, false)
+	c.Assert(result.Problems, check.HasLen, 2)
+	c.Assert(result.Problems[0].Message, check.Equals, "Grade B is lower than A")
+	c.Assert(result.Problems[1].Type, check.Equals, PROBLEM_ASSESSMENT_ERROR)
+
+	s.srv.InjectError(503, 1)
+
+	c.Assert(run(append(args, "-min-grade", "A", "bad.com")), check.Equals, EC_ERROR)
+}
@@ -0,0 +1,207 @@
+package main
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/report"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// output is writer for command output
+var output io.Writer = os.Stdout
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// render prints data as JSON or using given table printer
+func render(opts *options, data interface{}, printTable func()) error {
+	if opts.format == FORMAT_JSON {
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	}
+
+	printTable()
+
+	return nil
+}
+
+// renderResults prints assessments results in given format
+func renderResults(opts *options, results []*sslscan.AnalyzeInfo) error {
+	switch opts.format {
+	case FORMAT_JUNIT:
+		return report.WriteJUnit(output, results, nil)
+	case FORMAT_SARIF:
+		return report.WriteSARIF(output, results, nil)
+	case FORMAT_HTML:
+		return report.WriteHTML(output, results, nil)
+	case FORMAT_MARKDOWN:
+		return report.WriteMarkdown(output, results, &report.Options{Verbosity: opts.verbosity})
+	case FORMAT_CSV, FORMAT_TSV:
+		return renderFlat(opts, results)
+	case FORMAT_TEXT:
+		return report.WriteText(output, results, &report.Options{
+			Verbosity: opts.verbosity,
+			NoColor:   output != os.Stdout || !isTerminal(os.Stdout),
+		})
+	}
+
+	return render(opts, results, func() { printAnalyzeInfo(results) })
+}
+
+// renderFlat prints assessments results as CSV or TSV
+func renderFlat(opts *options, results []*sslscan.AnalyzeInfo) error {
+	var err error
+
+	ropts := &report.Options{}
+
+	if opts.columns != "" {
+		ropts.Columns, err = report.ParseColumns(opts.columns)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.format == FORMAT_TSV {
+		return report.WriteTSV(output, results, ropts)
+	}
+
+	return report.WriteCSV(output, results, ropts)
+}
+
+// printInfo prints API info as table
+func printInfo(info *sslscan.Info) {
+	w := newTabWriter()
+
+	fmt.Fprintf(w, "Engine version:\t%s\n", info.EngineVersion)
+	fmt.Fprintf(w, "Criteria version:\t%s\n", info.CriteriaVersion)
+	fmt.Fprintf(w, "Max assessments:\t%d\n", info.MaxAssessments)
+	fmt.Fprintf(w, "Current assessments:\t%d\n", info.CurrentAssessments)
+	fmt.Fprintf(w, "New assessment cool-off:\t%s\n", time.Duration(info.NewAssessmentCoolOff)*time.Millisecond)
+
+	for _, msg := range info.Messages {
+		fmt.Fprintf(w, "Message:\t%s\n", msg)
+	}
+
+	w.Flush()
+}
+
+// printAnalyzeInfo prints assessments results as table
+func printAnalyzeInfo(results []*sslscan.AnalyzeInfo) {
+	w := newTabWriter()
+
+	fmt.Fprintln(w, "HOST\tIP\tSERVER NAME\tGRADE\tPROTOCOLS\tSTATUS")
+
+	for _, info := range results {
+		if info.Status == sslscan.STATUS_ERROR || len(info.Endpoints) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t%s\n", info.Host, formatStatus(info.Status, info.StatusMessage))
+			continue
+		}
+
+		for _, endpoint := range info.Endpoints {
+			fmt.Fprintf(
+				w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				info.Host,
+				endpoint.IPAdress,
+				formatValue(endpoint.ServerName),
+				formatValue(endpoint.Grade),
+				formatProtocols(endpoint.Details),
+				formatStatus(info.Status, endpoint.StatusMessage),
+			)
+		}
+	}
+
+	w.Flush()
+}
+
+// printEndpointInfo prints endpoint info as table
+func printEndpointInfo(info *sslscan.EndpointInfo) {
+	w := newTabWriter()
+
+	fmt.Fprintf(w, "IP:\t%s\n", info.IPAdress)
+	fmt.Fprintf(w, "Server name:\t%s\n", formatValue(info.ServerName))
+	fmt.Fprintf(w, "Grade:\t%s\n", formatValue(info.Grade))
+	fmt.Fprintf(w, "Grade (trust ignored):\t%s\n", formatValue(info.GradeTrustIgnored))
+	fmt.Fprintf(w, "Has warnings:\t%t\n", info.HasWarnings)
+	fmt.Fprintf(w, "Is exceptional:\t%t\n", info.IsExceptional)
+	fmt.Fprintf(w, "Status:\t%s\n", formatValue(info.StatusMessage))
+
+	if info.Details != nil {
+		d := info.Details
+
+		fmt.Fprintf(w, "Protocols:\t%s\n", formatProtocols(d))
+		fmt.Fprintf(w, "Server signature:\t%s\n", formatValue(d.ServerSignature))
+		fmt.Fprintf(w, "ALPN:\t%s\n", formatValue(d.ALPNProtocols))
+		fmt.Fprintf(w, "Forward secrecy:\t%d\n", d.ForwardSecrecy)
+		fmt.Fprintf(w, "OCSP stapling:\t%t\n", d.OCSPStapling)
+		fmt.Fprintf(w, "Heartbleed:\t%t\n", d.Heartbleed)
+		fmt.Fprintf(w, "POODLE:\t%t\n", d.Poodle)
+
+		if d.HSTSPolicy != nil {
+			fmt.Fprintf(w, "HSTS:\t%s\n", d.HSTSPolicy.Status)
+		}
+
+		for _, ps := range d.Suites {
+			for _, suite := range ps.List {
+				fmt.Fprintf(w, "Suite:\t%s %s (%d bits)\n", sslscan.ProtocolName(ps.Protocol), suite.Name, suite.CipherStrength)
+			}
+		}
+	}
+
+	w.Flush()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newTabWriter creates new writer for tables
+func newTabWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(output, 0, 4, 2, ' ', 0)
+}
+
+// formatProtocols returns list of supported protocols
+func formatProtocols(details *sslscan.EndpointDetails) string {
+	if details == nil || len(details.Protocols) == 0 {
+		return "-"
+	}
+
+	var result []string
+
+	for _, p := range details.Protocols {
+		result = append(result, p.Name+" "+p.Version)
+	}
+
+	return strings.Join(result, ", ")
+}
+
+// formatStatus returns status with message
+func formatStatus(status, message string) string {
+	if message == "" || status == sslscan.STATUS_READY {
+		return status
+	}
+
+	return status + " (" + message + ")"
+}
+
+// formatValue returns "-" for empty values
+func formatValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
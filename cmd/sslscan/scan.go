@@ -0,0 +1,144 @@
+package main
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/local"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// PROGRESS_BAR_SIZE is progress bar width in symbols
+const PROGRESS_BAR_SIZE = 30
+
+// ROOT_STORE_PRIVATE is name of root store with certificates from CA bundle
+const ROOT_STORE_PRIVATE = "Private"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newScanner creates new scanner for backend from options
+func newScanner(opts *options) (sslscan.Scanner, error) {
+	if opts.backend == local.SCANNER_LOCAL {
+		scanner := local.New()
+		scanner.Timeout = time.Duration(sslscan.RequestTimeout * float64(time.Second))
+
+		if opts.startTLS != "" {
+			if !local.IsStartTLSSupported(opts.startTLS) {
+				return nil, fmt.Errorf("Unsupported STARTTLS protocol %s", opts.startTLS)
+			}
+
+			scanner.StartTLS = opts.startTLS
+			scanner.Port = local.GetStartTLSPort(opts.startTLS)
+		}
+
+		if opts.port > 0 {
+			scanner.Port = opts.port
+		}
+
+		if opts.caBundle != "" {
+			store, err := local.LoadRootStore(ROOT_STORE_PRIVATE, opts.caBundle)
+
+			if err != nil {
+				return nil, err
+			}
+
+			scanner.RootStores = []*local.RootStore{local.SystemRootStore(), store}
+		}
+
+		if opts.simClients != "" {
+			clients, err := local.LoadClients(opts.simClients)
+
+			if err != nil {
+				return nil, err
+			}
+
+			scanner.Clients = append(append([]*local.ClientProfile{}, local.DefaultClients...), clients...)
+		}
+
+		return scanner, nil
+	}
+
+	api, err := newAPI(opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := sslscan.NewScanner(api)
+	scanner.Interval = opts.interval
+	scanner.Timeout = opts.timeout
+	scanner.Detailed = opts.detailed
+
+	if !opts.noProgress && isTerminal(os.Stderr) {
+		scanner.Progress = printProgress
+	}
+
+	return scanner, nil
+}
+
+// printProgress prints assessment progress bar
+func printProgress(host string, info *sslscan.AnalyzeInfo) {
+	p := getProgress(info)
+	filled := p * PROGRESS_BAR_SIZE / 100
+
+	fmt.Fprintf(
+		os.Stderr, "\r%s [%s%s] %3d%% %-12s",
+		host,
+		strings.Repeat("■", filled),
+		strings.Repeat("·", PROGRESS_BAR_SIZE-filled),
+		p, info.Status,
+	)
+
+	if info.Status == sslscan.STATUS_READY || info.Status == sslscan.STATUS_ERROR {
+		fmt.Fprintln(os.Stderr, "")
+	}
+}
+
+// getProgress returns total assessment progress in percents
+func getProgress(info *sslscan.AnalyzeInfo) int {
+	switch info.Status {
+	case sslscan.STATUS_READY, sslscan.STATUS_ERROR:
+		return 100
+	case sslscan.STATUS_DNS:
+		return 0
+	}
+
+	if len(info.Endpoints) == 0 {
+		return 0
+	}
+
+	var total int
+
+	for _, endpoint := range info.Endpoints {
+		switch {
+		case endpoint.Progress > 0:
+			total += endpoint.Progress
+		case endpoint.Grade != "" || endpoint.StatusMessage == "Ready":
+			total += 100
+		}
+	}
+
+	return total / len(info.Endpoints)
+}
+
+// isTerminal returns true if given file is a terminal
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
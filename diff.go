@@ -0,0 +1,403 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	CHANGE_ADDED   = "added"
+	CHANGE_REMOVED = "removed"
+	CHANGE_CHANGED = "changed"
+)
+
+const (
+	DIFF_FIELD_ENDPOINT      = "endpoint"
+	DIFF_FIELD_GRADE         = "grade"
+	DIFF_FIELD_PROTOCOL      = "protocol"
+	DIFF_FIELD_SUITE         = "suite"
+	DIFF_FIELD_NAMED_GROUP   = "namedGroup"
+	DIFF_FIELD_CERT_CHAIN    = "certChain"
+	DIFF_FIELD_HSTS          = "hstsPolicy"
+	DIFF_FIELD_HPKP          = "hpkpPolicy"
+	DIFF_FIELD_HPKP_RO       = "hpkpRoPolicy"
+	DIFF_FIELD_VULNERABILITY = "vulnerability"
+	DIFF_FIELD_SIM           = "sim"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Change contains info about one difference between two assessments
+type Change struct {
+	Endpoint string // endpoint IP address
+	Field    string // changed field (DIFF_FIELD_*)
+	Type     string // change type (CHANGE_*)
+	Subject  string // changed item (e.g. protocol name, suite name or vulnerability name)
+	Old      string // previous value
+	New      string // current value
+}
+
+// Changes is slice with changes
+type Changes []*Change
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Diff compares two assessments of the same host endpoint by endpoint
+func Diff(prev, cur *AnalyzeInfo) Changes {
+	var changes Changes
+
+	if prev == nil || cur == nil {
+		return changes
+	}
+
+	prevEndpoints := mapEndpoints(prev.Endpoints)
+	curEndpoints := mapEndpoints(cur.Endpoints)
+
+	ips := make(map[string]bool)
+
+	for ip := range prevEndpoints {
+		ips[ip] = true
+	}
+
+	for ip := range curEndpoints {
+		ips[ip] = true
+	}
+
+	for _, ip := range sortedKeys(ips) {
+		p, c := prevEndpoints[ip], curEndpoints[ip]
+
+		switch {
+		case p == nil:
+			changes = append(changes, &Change{ip, DIFF_FIELD_ENDPOINT, CHANGE_ADDED, ip, "", c.Grade})
+		case c == nil:
+			changes = append(changes, &Change{ip, DIFF_FIELD_ENDPOINT, CHANGE_REMOVED, ip, p.Grade, ""})
+		default:
+			changes = append(changes, DiffEndpoints(p, c)...)
+		}
+	}
+
+	return changes
+}
+
+// DiffEndpoints compares two assessments of the same endpoint
+func DiffEndpoints(prev, cur *EndpointInfo) Changes {
+	var changes Changes
+
+	if prev == nil || cur == nil {
+		return changes
+	}
+
+	ip := cur.IPAdress
+
+	if prev.Grade != cur.Grade {
+		changes = append(changes, &Change{ip, DIFF_FIELD_GRADE, CHANGE_CHANGED, "", prev.Grade, cur.Grade})
+	}
+
+	if prev.Details == nil || cur.Details == nil {
+		return changes
+	}
+
+	pd, cd := prev.Details, cur.Details
+
+	changes = append(changes, diffSets(ip, DIFF_FIELD_PROTOCOL, protocolsSet(pd), protocolsSet(cd))...)
+	changes = append(changes, diffSets(ip, DIFF_FIELD_SUITE, suitesSet(pd), suitesSet(cd))...)
+	changes = append(changes, diffSets(ip, DIFF_FIELD_NAMED_GROUP, namedGroupsSet(pd), namedGroupsSet(cd))...)
+	changes = append(changes, diffSets(ip, DIFF_FIELD_CERT_CHAIN, certChainsSet(pd), certChainsSet(cd))...)
+
+	changes = append(changes, diffValue(ip, DIFF_FIELD_HSTS, "", hstsStatus(pd.HSTSPolicy), hstsStatus(cd.HSTSPolicy))...)
+	changes = append(changes, diffValue(ip, DIFF_FIELD_HPKP, "", hpkpStatus(pd.HPKPPolicy), hpkpStatus(cd.HPKPPolicy))...)
+	changes = append(changes, diffValue(ip, DIFF_FIELD_HPKP_RO, "", hpkpStatus(pd.HPKPRoPolicy), hpkpStatus(cd.HPKPRoPolicy))...)
+
+	for _, vf := range vulnFields {
+		changes = append(changes, diffValue(
+			ip, DIFF_FIELD_VULNERABILITY, vf.name,
+			fmt.Sprint(vf.value(pd)), fmt.Sprint(vf.value(cd)),
+		)...)
+	}
+
+	changes = append(changes, diffSIMS(ip, pd.SIMS, cd.SIMS)...)
+
+	return changes
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// String returns human-readable representation of change
+func (c *Change) String() string {
+	var subject string
+
+	if c.Subject != "" {
+		subject = " " + c.Subject
+	}
+
+	switch c.Type {
+	case CHANGE_ADDED:
+		return fmt.Sprintf("[%s] %s%s added", c.Endpoint, c.Field, subject)
+	case CHANGE_REMOVED:
+		return fmt.Sprintf("[%s] %s%s removed", c.Endpoint, c.Field, subject)
+	}
+
+	return fmt.Sprintf(
+		"[%s] %s%s changed from %s to %s",
+		c.Endpoint, c.Field, subject,
+		formatDiffValue(c.Old), formatDiffValue(c.New),
+	)
+}
+
+// String returns human-readable representation of all changes
+func (c Changes) String() string {
+	var result []string
+
+	for _, change := range c {
+		result = append(result, change.String())
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// Has returns true if changes contain change for given field
+func (c Changes) Has(field string) bool {
+	for _, change := range c {
+		if change.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// diffSets returns changes between two sets of items
+func diffSets(ip, field string, prev, cur map[string]bool) Changes {
+	var changes Changes
+
+	items := make(map[string]bool)
+
+	for item := range prev {
+		items[item] = true
+	}
+
+	for item := range cur {
+		items[item] = true
+	}
+
+	for _, item := range sortedKeys(items) {
+		switch {
+		case !prev[item]:
+			changes = append(changes, &Change{ip, field, CHANGE_ADDED, item, "", ""})
+		case !cur[item]:
+			changes = append(changes, &Change{ip, field, CHANGE_REMOVED, item, "", ""})
+		}
+	}
+
+	return changes
+}
+
+// diffValue returns change if values are different
+func diffValue(ip, field, subject, prev, cur string) Changes {
+	if prev == cur {
+		return nil
+	}
+
+	return Changes{&Change{ip, field, CHANGE_CHANGED, subject, prev, cur}}
+}
+
+// diffSIMS returns changes in handshake simulation results
+func diffSIMS(ip string, prev, cur *SIMS) Changes {
+	var changes Changes
+
+	prevResults, curResults := mapSIMS(prev), mapSIMS(cur)
+
+	ids := make(map[string]bool)
+
+	for id := range prevResults {
+		ids[id] = true
+	}
+
+	for id := range curResults {
+		ids[id] = true
+	}
+
+	for _, id := range sortedKeys(ids) {
+		p, c := prevResults[id], curResults[id]
+
+		switch {
+		case p == nil:
+			changes = append(changes, &Change{ip, DIFF_FIELD_SIM, CHANGE_ADDED, formatSimClient(c.Client), "", formatSimOutcome(c)})
+		case c == nil:
+			changes = append(changes, &Change{ip, DIFF_FIELD_SIM, CHANGE_REMOVED, formatSimClient(p.Client), formatSimOutcome(p), ""})
+		case formatSimOutcome(p) != formatSimOutcome(c):
+			changes = append(changes, &Change{ip, DIFF_FIELD_SIM, CHANGE_CHANGED, formatSimClient(c.Client), formatSimOutcome(p), formatSimOutcome(c)})
+		}
+	}
+
+	return changes
+}
+
+// mapEndpoints returns map ip → endpoint
+func mapEndpoints(endpoints []*EndpointInfo) map[string]*EndpointInfo {
+	result := make(map[string]*EndpointInfo)
+
+	for _, endpoint := range endpoints {
+		if endpoint != nil {
+			result[endpoint.IPAdress] = endpoint
+		}
+	}
+
+	return result
+}
+
+// mapSIMS returns map client ID → simulation result
+func mapSIMS(sims *SIMS) map[string]*SIM {
+	result := make(map[string]*SIM)
+
+	if sims == nil {
+		return result
+	}
+
+	for _, sim := range sims.Results {
+		if sim == nil || sim.Client == nil {
+			continue
+		}
+
+		result[strconv.Itoa(sim.Client.ID)] = sim
+	}
+
+	return result
+}
+
+// protocolsSet returns set with names of supported protocols
+func protocolsSet(d *EndpointDetails) map[string]bool {
+	result := make(map[string]bool)
+
+	for _, p := range d.Protocols {
+		if p != nil {
+			result[p.Name+" "+p.Version] = true
+		}
+	}
+
+	return result
+}
+
+// suitesSet returns set with supported suites in format "protocol: suite"
+func suitesSet(d *EndpointDetails) map[string]bool {
+	result := make(map[string]bool)
+
+	for _, ps := range d.Suites {
+		if ps == nil {
+			continue
+		}
+
+		for _, s := range ps.List {
+			if s != nil {
+				result[ProtocolName(ps.Protocol)+": "+s.Name] = true
+			}
+		}
+	}
+
+	return result
+}
+
+// namedGroupsSet returns set with names of supported named groups
+func namedGroupsSet(d *EndpointDetails) map[string]bool {
+	result := make(map[string]bool)
+
+	if d.NamedGroups == nil {
+		return result
+	}
+
+	for _, g := range d.NamedGroups.List {
+		result[g.Name] = true
+	}
+
+	return result
+}
+
+// certChainsSet returns set with certificate chains IDs
+func certChainsSet(d *EndpointDetails) map[string]bool {
+	result := make(map[string]bool)
+
+	for _, chain := range d.CertChains {
+		if chain != nil {
+			result[chain.ID] = true
+		}
+	}
+
+	return result
+}
+
+// hstsStatus returns HSTS policy status
+func hstsStatus(policy *HSTSPolicy) string {
+	if policy == nil {
+		return ""
+	}
+
+	return policy.Status
+}
+
+// hpkpStatus returns HPKP policy status
+func hpkpStatus(policy *HPKPPolicy) string {
+	if policy == nil {
+		return ""
+	}
+
+	return policy.Status
+}
+
+// formatSimClient returns simulated client name
+func formatSimClient(client *SimClient) string {
+	result := client.Name
+
+	if client.Version != "" {
+		result += " " + client.Version
+	}
+
+	if client.Platform != "" {
+		result += " / " + client.Platform
+	}
+
+	return result
+}
+
+// formatSimOutcome returns handshake simulation outcome
+func formatSimOutcome(sim *SIM) string {
+	if sim.ErrorCode != 0 {
+		return "failed"
+	}
+
+	return ProtocolName(sim.ProtocolID) + " " + sim.SuiteName
+}
+
+// formatDiffValue formats value for human-readable output
+func formatDiffValue(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+
+	return value
+}
+
+// sortedKeys returns sorted slice with items of set
+func sortedKeys(set map[string]bool) []string {
+	var result []string
+
+	for k := range set {
+		result = append(result, k)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
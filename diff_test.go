@@ -0,0 +1,106 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *SSLLabsSuite) TestDiff(c *check.C) {
+	prev := &AnalyzeInfo{Host: "essentialkaos.com", Endpoints: []*EndpointInfo{
+		loadEndpointFixture(c, "responses/v3-2.1.3-2009q.json"),
+		{IPAdress: "127.0.0.1", Grade: "A"},
+	}}
+
+	cur := &AnalyzeInfo{Host: "essentialkaos.com", Endpoints: []*EndpointInfo{
+		loadEndpointFixture(c, "responses/v3-2.1.3-2009q.json"),
+		{IPAdress: "127.0.0.2", Grade: "B"},
+	}}
+
+	c.Assert(Diff(prev, cur), check.HasLen, 2)
+	c.Assert(Diff(nil, cur), check.HasLen, 0)
+
+	ep := cur.Endpoints[0]
+	ep.Grade = "B"
+	ep.Details.Protocols = ep.Details.Protocols[:1]
+	ep.Details.Suites[0].List = append(ep.Details.Suites[0].List, &Suite{Name: "TLS_RSA_WITH_AES_128_CBC_SHA"})
+	ep.Details.CertChains[0].ID = "abcd"
+	ep.Details.HSTSPolicy.Status = HSTS_STATUS_ABSENT
+	ep.Details.Heartbleed = true
+	ep.Details.SIMS.Results[5].ErrorCode = 1
+
+	changes := Diff(prev, cur)
+
+	c.Assert(changes.Has(DIFF_FIELD_ENDPOINT), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_GRADE), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_PROTOCOL), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_SUITE), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_CERT_CHAIN), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_HSTS), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_VULNERABILITY), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_SIM), check.Equals, true)
+	c.Assert(changes.Has(DIFF_FIELD_NAMED_GROUP), check.Equals, false)
+
+	var grade, protocol, suite *Change
+
+	for _, change := range changes {
+		switch change.Field {
+		case DIFF_FIELD_GRADE:
+			grade = change
+		case DIFF_FIELD_PROTOCOL:
+			protocol = change
+		case DIFF_FIELD_SUITE:
+			suite = change
+		}
+	}
+
+	c.Assert(grade.String(), check.Equals, "[5.79.108.150] grade changed from A+ to B")
+	c.Assert(protocol.String(), check.Equals, "[5.79.108.150] protocol TLS 1.3 removed")
+	c.Assert(suite.String(), check.Equals, "[5.79.108.150] suite TLS 1.2: TLS_RSA_WITH_AES_128_CBC_SHA added")
+	c.Assert(changes.String(), check.Not(check.Equals), "")
+
+	c.Assert(sortedKeys(map[string]bool{"b": true, "c": true, "a": true}), check.DeepEquals, []string{"a", "b", "c"})
+	c.Assert(sortedKeys(nil), check.IsNil)
+}
+
+func (s *SSLLabsSuite) TestDiffSIMSDuplicateClients(c *check.C) {
+	prev := &SIMS{Results: []*SIM{
+		{Client: &SimClient{ID: 1, Name: "Android", Version: "4.4.2"}, ProtocolID: 771, SuiteName: "TLS_RSA_WITH_AES_128_CBC_SHA"},
+		{Client: &SimClient{ID: 2, Name: "Android", Version: "4.4.2"}, ProtocolID: 771, SuiteName: "TLS_RSA_WITH_AES_128_CBC_SHA"},
+	}}
+
+	cur := &SIMS{Results: []*SIM{
+		{Client: &SimClient{ID: 1, Name: "Android", Version: "4.4.2"}, ProtocolID: 771, SuiteName: "TLS_RSA_WITH_AES_128_CBC_SHA"},
+		{Client: &SimClient{ID: 2, Name: "Android", Version: "4.4.2"}, ErrorCode: 1},
+	}}
+
+	changes := diffSIMS("127.0.0.1", prev, cur)
+
+	c.Assert(changes, check.HasLen, 1)
+	c.Assert(changes[0].Subject, check.Equals, "Android 4.4.2")
+	c.Assert(changes[0].New, check.Equals, "failed")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func loadEndpointFixture(c *check.C, file string) *EndpointInfo {
+	data, err := ioutil.ReadFile(file)
+
+	c.Assert(err, check.IsNil)
+
+	info := &EndpointInfo{}
+
+	c.Assert(json.Unmarshal(data, info), check.IsNil)
+
+	return info
+}
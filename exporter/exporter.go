@@ -0,0 +1,356 @@
+// Package exporter provides Prometheus exporter for TLS posture metrics
+package exporter
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/report"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// METRICS_PREFIX is prefix for all metrics names
+const METRICS_PREFIX = "sslscan_"
+
+const (
+	TYPE_GAUGE   = "gauge"
+	TYPE_COUNTER = "counter"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Exporter periodically assesses hosts and exposes results as Prometheus metrics
+type Exporter struct {
+	Scanner  sslscan.Scanner
+	Hosts    []string
+	Params   sslscan.AnalyzeParams
+	Interval time.Duration // interval between assessments of all hosts
+
+	results map[string]*result
+	errors  map[string]uint64
+	mx      sync.RWMutex
+}
+
+// result contains info about last assessment of host
+type result struct {
+	info     *sslscan.AnalyzeInfo
+	err      error
+	finished time.Time
+}
+
+// metricsWriter writes metrics in Prometheus text format
+type metricsWriter struct {
+	w *bufio.Writer
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// protocols contains all protocols for protocol support metric
+var protocols = []int{
+	sslscan.PROTOCOL_SSL2, sslscan.PROTOCOL_SSL3,
+	sslscan.PROTOCOL_TLS10, sslscan.PROTOCOL_TLS11,
+	sslscan.PROTOCOL_TLS12, sslscan.PROTOCOL_TLS13,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new exporter for given hosts
+func New(scanner sslscan.Scanner, hosts []string) *Exporter {
+	return &Exporter{
+		Scanner:  scanner,
+		Hosts:    hosts,
+		Interval: 6 * time.Hour,
+
+		results: make(map[string]*result),
+		errors:  make(map[string]uint64),
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Run assesses all hosts one by one with configured interval until stop channel
+// is closed. Scanner must limit duration of single assessment (e.g. using
+// APIScanner.Timeout), otherwise one stuck host blocks metrics of all others.
+func (e *Exporter) Run(stop <-chan struct{}) {
+	for {
+		for _, host := range e.Hosts {
+			select {
+			case <-stop:
+				return
+			default:
+				e.Assess(host)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(e.Interval):
+		}
+	}
+}
+
+// Assess runs assessment for given host and saves its result
+func (e *Exporter) Assess(host string) error {
+	info, err := e.Scanner.Scan(host, e.Params)
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if err != nil {
+		e.errors[getErrorCode(err)]++
+	}
+
+	prev := e.results[host]
+
+	// Keep previous successful result to not lose metrics because
+	// of temporary API errors
+	if err != nil && prev != nil {
+		prev.err = err
+		return err
+	}
+
+	e.results[host] = &result{info: info, err: err, finished: time.Now()}
+
+	return err
+}
+
+// ServeHTTP is handler for /metrics requests
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteMetrics(w)
+}
+
+// WriteMetrics writes all metrics in Prometheus text format
+func (e *Exporter) WriteMetrics(w io.Writer) error {
+	e.mx.RLock()
+	defer e.mx.RUnlock()
+
+	mw := &metricsWriter{bufio.NewWriter(w)}
+	hosts := e.getSortedHosts()
+	now := time.Now()
+
+	mw.family("assessment_success", TYPE_GAUGE, "Whether the last assessment of host was successful")
+
+	for _, host := range hosts {
+		r := e.results[host]
+		mw.sample("assessment_success", boolToFloat(r.err == nil && r.info.Status == sslscan.STATUS_READY), "host", host)
+	}
+
+	mw.family("assessment_timestamp_seconds", TYPE_GAUGE, "Time when the last assessment was completed")
+
+	e.forEachInfo(hosts, func(host string, info *sslscan.AnalyzeInfo) {
+		mw.sample("assessment_timestamp_seconds", msToSeconds(info.TestTime), "host", host)
+	})
+
+	mw.family("assessment_age_seconds", TYPE_GAUGE, "Age of the last assessment results")
+
+	e.forEachInfo(hosts, func(host string, info *sslscan.AnalyzeInfo) {
+		mw.sample("assessment_age_seconds", now.Sub(time.Unix(0, info.TestTime*int64(time.Millisecond))).Seconds(), "host", host)
+	})
+
+	mw.family("assessment_duration_seconds", TYPE_GAUGE, "Duration of endpoint assessment")
+
+	e.forEachEndpoint(hosts, func(host string, info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo) {
+		mw.sample("assessment_duration_seconds", float64(endpoint.Duration)/1000, "host", host, "ip", endpoint.IPAdress)
+	})
+
+	mw.family("grade", TYPE_GAUGE, "Endpoint grade (A+ = 9, A = 8, A- = 7, B = 6, C = 5, D = 4, E = 3, F = 2, T = 1, M = 0, no grade = -1)")
+
+	e.forEachEndpoint(hosts, func(host string, info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo) {
+		mw.sample("grade", float64(sslscan.GradeScore(endpoint.Grade)), "host", host, "ip", endpoint.IPAdress)
+	})
+
+	mw.family("cert_expiry_timestamp_seconds", TYPE_GAUGE, "Time when the leaf certificate expires")
+
+	e.forEachEndpoint(hosts, func(host string, info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo) {
+		for _, cert := range report.LeafCerts(info, endpoint) {
+			mw.sample(
+				"cert_expiry_timestamp_seconds", msToSeconds(cert.NotAfter),
+				"host", host, "ip", endpoint.IPAdress, "serial", cert.SerialNumber,
+			)
+		}
+	})
+
+	mw.family("protocol_supported", TYPE_GAUGE, "Whether the protocol version is supported by endpoint")
+
+	e.forEachDetails(hosts, func(host string, endpoint *sslscan.EndpointInfo, d *sslscan.EndpointDetails) {
+		supported := make(map[int]bool)
+
+		for _, p := range d.Protocols {
+			supported[p.ID] = true
+		}
+
+		for _, id := range protocols {
+			mw.sample(
+				"protocol_supported", boolToFloat(supported[id]),
+				"host", host, "ip", endpoint.IPAdress, "protocol", sslscan.ProtocolName(id),
+			)
+		}
+	})
+
+	mw.family("vulnerable", TYPE_GAUGE, "Whether the endpoint is vulnerable")
+
+	e.forEachDetails(hosts, func(host string, endpoint *sslscan.EndpointInfo, d *sslscan.EndpointDetails) {
+		for _, vuln := range sslscan.CheckVulnerabilities(d) {
+			mw.sample(
+				"vulnerable", boolToFloat(vuln.Vulnerable),
+				"host", host, "ip", endpoint.IPAdress, "vulnerability", vuln.Name,
+			)
+		}
+	})
+
+	mw.family("hsts_max_age_seconds", TYPE_GAUGE, "HSTS policy max-age (0 if policy is not present)")
+
+	e.forEachDetails(hosts, func(host string, endpoint *sslscan.EndpointInfo, d *sslscan.EndpointDetails) {
+		var maxAge int64
+
+		if d.HSTSPolicy != nil && d.HSTSPolicy.Status == sslscan.HSTS_STATUS_PRESENT {
+			maxAge = d.HSTSPolicy.MaxAge
+		}
+
+		mw.sample("hsts_max_age_seconds", float64(maxAge), "host", host, "ip", endpoint.IPAdress)
+	})
+
+	mw.family("api_errors_total", TYPE_COUNTER, "Number of API errors by HTTP status code")
+
+	for _, code := range e.getSortedErrorCodes() {
+		mw.sample("api_errors_total", float64(e.errors[code]), "code", code)
+	}
+
+	return mw.w.Flush()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getSortedHosts returns sorted list of hosts with results
+func (e *Exporter) getSortedHosts() []string {
+	var result []string
+
+	for host := range e.results {
+		result = append(result, host)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// getSortedErrorCodes returns sorted list of error codes
+func (e *Exporter) getSortedErrorCodes() []string {
+	var result []string
+
+	for code := range e.errors {
+		result = append(result, code)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// forEachInfo calls handler for every successful assessment
+func (e *Exporter) forEachInfo(hosts []string, handler func(host string, info *sslscan.AnalyzeInfo)) {
+	for _, host := range hosts {
+		info := e.results[host].info
+
+		if info != nil && info.Status == sslscan.STATUS_READY {
+			handler(host, info)
+		}
+	}
+}
+
+// forEachEndpoint calls handler for every endpoint
+func (e *Exporter) forEachEndpoint(hosts []string, handler func(host string, info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo)) {
+	e.forEachInfo(hosts, func(host string, info *sslscan.AnalyzeInfo) {
+		for _, endpoint := range info.Endpoints {
+			handler(host, info, endpoint)
+		}
+	})
+}
+
+// forEachDetails calls handler for every endpoint with details
+func (e *Exporter) forEachDetails(hosts []string, handler func(host string, endpoint *sslscan.EndpointInfo, d *sslscan.EndpointDetails)) {
+	e.forEachEndpoint(hosts, func(host string, info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo) {
+		if endpoint.Details != nil {
+			handler(host, endpoint, endpoint.Details)
+		}
+	})
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// family writes metric family header
+func (mw *metricsWriter) family(name, metricType, help string) {
+	fmt.Fprintf(mw.w, "# HELP %s%s %s\n", METRICS_PREFIX, name, help)
+	fmt.Fprintf(mw.w, "# TYPE %s%s %s\n", METRICS_PREFIX, name, metricType)
+}
+
+// sample writes metric sample with given labels (name-value pairs)
+func (mw *metricsWriter) sample(name string, value float64, labels ...string) {
+	mw.w.WriteString(METRICS_PREFIX + name)
+
+	if len(labels) != 0 {
+		mw.w.WriteString("{")
+
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				mw.w.WriteString(",")
+			}
+
+			mw.w.WriteString(labels[i] + "=\"" + escapeLabel(labels[i+1]) + "\"")
+		}
+
+		mw.w.WriteString("}")
+	}
+
+	mw.w.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getErrorCode returns code for API errors metric
+func getErrorCode(err error) string {
+	if apiErr, ok := err.(*sslscan.APIError); ok {
+		return strconv.Itoa(apiErr.StatusCode)
+	}
+
+	return "error"
+}
+
+// escapeLabel escapes label value
+func escapeLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+// msToSeconds converts timestamp in milliseconds to seconds
+func msToSeconds(ms int64) float64 {
+	return float64(ms) / 1000
+}
+
+// boolToFloat converts boolean to metric value
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
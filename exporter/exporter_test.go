@@ -0,0 +1,160 @@
+package exporter
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/sslscantest"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type ExporterSuite struct {
+	srv *sslscantest.Server
+	exp *Exporter
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = check.Suite(&ExporterSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ExporterSuite) SetUpTest(c *check.C) {
+	s.srv = sslscantest.NewServer()
+	s.srv.ProgressSteps = 1
+
+	endpoint, err := sslscantest.LoadEndpoint("../responses/v3-2.1.3-2009q.json")
+
+	c.Assert(err, check.IsNil)
+
+	s.srv.AddHost(&sslscan.AnalyzeInfo{
+		Host:      "essentialkaos.com",
+		Port:      443,
+		Status:    sslscan.STATUS_READY,
+		Endpoints: []*sslscan.EndpointInfo{endpoint},
+		Certs: []*sslscan.Cert{
+			{
+				ID:           endpoint.Details.CertChains[0].CertIDs[0],
+				SerialNumber: "04a3f2b5",
+				NotAfter:     1606780799000,
+			},
+		},
+	})
+
+	api, err := s.srv.NewAPI("Exporter", "1.0.0")
+
+	c.Assert(err, check.IsNil)
+
+	scanner := sslscan.NewScanner(api)
+	scanner.Interval = time.Millisecond
+
+	s.exp = New(scanner, []string{"essentialkaos.com", "unknown.com"})
+}
+
+func (s *ExporterSuite) TearDownTest(c *check.C) {
+	s.srv.Close()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ExporterSuite) TestMetrics(c *check.C) {
+	c.Assert(s.exp.Assess("essentialkaos.com"), check.IsNil)
+	c.Assert(s.exp.Assess("unknown.com"), check.IsNil)
+
+	s.srv.InjectError(503, 1)
+
+	c.Assert(s.exp.Assess("essentialkaos.com"), check.ErrorMatches, "API return HTTP code 503")
+
+	metrics := s.getMetrics(c)
+
+	for _, expected := range []string{
+		"# HELP sslscan_grade Endpoint grade (A+ = 9, A = 8, A- = 7, B = 6, C = 5, D = 4, E = 3, F = 2, T = 1, M = 0, no grade = -1)\n# TYPE sslscan_grade gauge\n",
+		`sslscan_assessment_success{host="essentialkaos.com"} 0`,
+		`sslscan_assessment_success{host="unknown.com"} 0`,
+		`sslscan_assessment_duration_seconds{host="essentialkaos.com",ip="5.79.108.150"} 77.571`,
+		`sslscan_grade{host="essentialkaos.com",ip="5.79.108.150"} 9`,
+		`sslscan_cert_expiry_timestamp_seconds{host="essentialkaos.com",ip="5.79.108.150",serial="04a3f2b5"} 1.606780799e+09`,
+		`sslscan_protocol_supported{host="essentialkaos.com",ip="5.79.108.150",protocol="TLS 1.0"} 0`,
+		`sslscan_protocol_supported{host="essentialkaos.com",ip="5.79.108.150",protocol="TLS 1.3"} 1`,
+		`sslscan_vulnerable{host="essentialkaos.com",ip="5.79.108.150",vulnerability="heartbleed"} 0`,
+		`sslscan_hsts_max_age_seconds{host="essentialkaos.com",ip="5.79.108.150"} 3.1536e+07`,
+		"# TYPE sslscan_api_errors_total counter\n" + `sslscan_api_errors_total{code="503"} 1`,
+	} {
+		c.Assert(strings.Contains(metrics, expected), check.Equals, true, check.Commentf("Metrics don't contain %q", expected))
+	}
+
+	c.Assert(metrics, check.Matches, `(?s).*sslscan_assessment_age_seconds\{host="essentialkaos.com"\} -?\d.*`)
+	c.Assert(strings.Contains(metrics, `sslscan_grade{host="unknown.com"`), check.Equals, false)
+
+	c.Assert(s.exp.Assess("essentialkaos.com"), check.IsNil)
+	c.Assert(strings.Contains(s.getMetrics(c), `sslscan_assessment_success{host="essentialkaos.com"} 1`), check.Equals, true)
+}
+
+func (s *ExporterSuite) TestRun(c *check.C) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	s.exp.Interval = time.Hour
+
+	go func() {
+		s.exp.Run(stop)
+		close(done)
+	}()
+
+	for i := 0; i < 100 && !strings.Contains(s.getMetrics(c), `sslscan_assessment_success{host="unknown.com"}`); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		c.Fatal("Exporter is not stopped")
+	}
+
+	c.Assert(strings.Contains(s.getMetrics(c), `sslscan_assessment_success{host="essentialkaos.com"} 1`), check.Equals, true)
+}
+
+func (s *ExporterSuite) TestLabelEscaping(c *check.C) {
+	c.Assert(escapeLabel("a\"b\\c\nd"), check.Equals, `a\"b\\c\nd`)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getMetrics returns metrics served by exporter
+func (s *ExporterSuite) getMetrics(c *check.C) string {
+	srv := httptest.NewServer(s.exp)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+
+	c.Assert(err, check.IsNil)
+
+	defer resp.Body.Close()
+
+	c.Assert(resp.Header.Get("Content-Type"), check.Equals, "text/plain; version=0.0.4; charset=utf-8")
+
+	data, err := ioutil.ReadAll(resp.Body)
+
+	c.Assert(err, check.IsNil)
+
+	return string(data)
+}
@@ -0,0 +1,448 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// fieldsCache contains cached JSON field names for model structs
+var fieldsCache = &sync.Map{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// UnmarshalJSON decodes Info and keeps unknown fields in Extra
+func (i *Info) UnmarshalJSON(data []byte) error {
+	type plain Info
+	return unmarshalWithExtra(data, (*plain)(i), &i.Extra)
+}
+
+// MarshalJSON encodes Info with fields from Extra
+func (i Info) MarshalJSON() ([]byte, error) {
+	type plain Info
+	return marshalWithExtra((*plain)(&i), i.Extra)
+}
+
+// UnmarshalJSON decodes AnalyzeInfo and keeps unknown fields in Extra
+func (a *AnalyzeInfo) UnmarshalJSON(data []byte) error {
+	type plain AnalyzeInfo
+	return unmarshalWithExtra(data, (*plain)(a), &a.Extra)
+}
+
+// MarshalJSON encodes AnalyzeInfo with fields from Extra
+func (a AnalyzeInfo) MarshalJSON() ([]byte, error) {
+	type plain AnalyzeInfo
+	return marshalWithExtra((*plain)(&a), a.Extra)
+}
+
+// UnmarshalJSON decodes EndpointInfo and keeps unknown fields in Extra
+func (e *EndpointInfo) UnmarshalJSON(data []byte) error {
+	type plain EndpointInfo
+	return unmarshalWithExtra(data, (*plain)(e), &e.Extra)
+}
+
+// MarshalJSON encodes EndpointInfo with fields from Extra
+func (e EndpointInfo) MarshalJSON() ([]byte, error) {
+	type plain EndpointInfo
+	return marshalWithExtra((*plain)(&e), e.Extra)
+}
+
+// UnmarshalJSON decodes EndpointDetails and keeps unknown fields in Extra
+func (e *EndpointDetails) UnmarshalJSON(data []byte) error {
+	type plain EndpointDetails
+	return unmarshalWithExtra(data, (*plain)(e), &e.Extra)
+}
+
+// MarshalJSON encodes EndpointDetails with fields from Extra
+func (e EndpointDetails) MarshalJSON() ([]byte, error) {
+	type plain EndpointDetails
+	return marshalWithExtra((*plain)(&e), e.Extra)
+}
+
+// UnmarshalJSON decodes Cert and keeps unknown fields in Extra
+func (c *Cert) UnmarshalJSON(data []byte) error {
+	type plain Cert
+	return unmarshalWithExtra(data, (*plain)(c), &c.Extra)
+}
+
+// MarshalJSON encodes Cert with fields from Extra
+func (c Cert) MarshalJSON() ([]byte, error) {
+	type plain Cert
+	return marshalWithExtra((*plain)(&c), c.Extra)
+}
+
+// UnmarshalJSON decodes ChainCert and keeps unknown fields in Extra
+func (c *ChainCert) UnmarshalJSON(data []byte) error {
+	type plain ChainCert
+	return unmarshalWithExtra(data, (*plain)(c), &c.Extra)
+}
+
+// MarshalJSON encodes ChainCert with fields from Extra
+func (c ChainCert) MarshalJSON() ([]byte, error) {
+	type plain ChainCert
+	return marshalWithExtra((*plain)(&c), c.Extra)
+}
+
+// UnmarshalJSON decodes TrustPath and keeps unknown fields in Extra
+func (t *TrustPath) UnmarshalJSON(data []byte) error {
+	type plain TrustPath
+	return unmarshalWithExtra(data, (*plain)(t), &t.Extra)
+}
+
+// MarshalJSON encodes TrustPath with fields from Extra
+func (t TrustPath) MarshalJSON() ([]byte, error) {
+	type plain TrustPath
+	return marshalWithExtra((*plain)(&t), t.Extra)
+}
+
+// UnmarshalJSON decodes TrustStore and keeps unknown fields in Extra
+func (t *TrustStore) UnmarshalJSON(data []byte) error {
+	type plain TrustStore
+	return unmarshalWithExtra(data, (*plain)(t), &t.Extra)
+}
+
+// MarshalJSON encodes TrustStore with fields from Extra
+func (t TrustStore) MarshalJSON() ([]byte, error) {
+	type plain TrustStore
+	return marshalWithExtra((*plain)(&t), t.Extra)
+}
+
+// UnmarshalJSON decodes NamedGroups and keeps unknown fields in Extra
+func (n *NamedGroups) UnmarshalJSON(data []byte) error {
+	type plain NamedGroups
+	return unmarshalWithExtra(data, (*plain)(n), &n.Extra)
+}
+
+// MarshalJSON encodes NamedGroups with fields from Extra
+func (n NamedGroups) MarshalJSON() ([]byte, error) {
+	type plain NamedGroups
+	return marshalWithExtra((*plain)(&n), n.Extra)
+}
+
+// UnmarshalJSON decodes NamedGroup and keeps unknown fields in Extra
+func (n *NamedGroup) UnmarshalJSON(data []byte) error {
+	type plain NamedGroup
+	return unmarshalWithExtra(data, (*plain)(n), &n.Extra)
+}
+
+// MarshalJSON encodes NamedGroup with fields from Extra
+func (n NamedGroup) MarshalJSON() ([]byte, error) {
+	type plain NamedGroup
+	return marshalWithExtra((*plain)(&n), n.Extra)
+}
+
+// UnmarshalJSON decodes Protocol and keeps unknown fields in Extra
+func (p *Protocol) UnmarshalJSON(data []byte) error {
+	type plain Protocol
+	return unmarshalWithExtra(data, (*plain)(p), &p.Extra)
+}
+
+// MarshalJSON encodes Protocol with fields from Extra
+func (p Protocol) MarshalJSON() ([]byte, error) {
+	type plain Protocol
+	return marshalWithExtra((*plain)(&p), p.Extra)
+}
+
+// UnmarshalJSON decodes ProtocolSuites and keeps unknown fields in Extra
+func (p *ProtocolSuites) UnmarshalJSON(data []byte) error {
+	type plain ProtocolSuites
+	return unmarshalWithExtra(data, (*plain)(p), &p.Extra)
+}
+
+// MarshalJSON encodes ProtocolSuites with fields from Extra
+func (p ProtocolSuites) MarshalJSON() ([]byte, error) {
+	type plain ProtocolSuites
+	return marshalWithExtra((*plain)(&p), p.Extra)
+}
+
+// UnmarshalJSON decodes Suite and keeps unknown fields in Extra
+func (s *Suite) UnmarshalJSON(data []byte) error {
+	type plain Suite
+	return unmarshalWithExtra(data, (*plain)(s), &s.Extra)
+}
+
+// MarshalJSON encodes Suite with fields from Extra
+func (s Suite) MarshalJSON() ([]byte, error) {
+	type plain Suite
+	return marshalWithExtra((*plain)(&s), s.Extra)
+}
+
+// UnmarshalJSON decodes SIMS and keeps unknown fields in Extra
+func (s *SIMS) UnmarshalJSON(data []byte) error {
+	type plain SIMS
+	return unmarshalWithExtra(data, (*plain)(s), &s.Extra)
+}
+
+// MarshalJSON encodes SIMS with fields from Extra
+func (s SIMS) MarshalJSON() ([]byte, error) {
+	type plain SIMS
+	return marshalWithExtra((*plain)(&s), s.Extra)
+}
+
+// UnmarshalJSON decodes SIM and keeps unknown fields in Extra
+func (s *SIM) UnmarshalJSON(data []byte) error {
+	type plain SIM
+	return unmarshalWithExtra(data, (*plain)(s), &s.Extra)
+}
+
+// MarshalJSON encodes SIM with fields from Extra
+func (s SIM) MarshalJSON() ([]byte, error) {
+	type plain SIM
+	return marshalWithExtra((*plain)(&s), s.Extra)
+}
+
+// UnmarshalJSON decodes SimClient and keeps unknown fields in Extra
+func (s *SimClient) UnmarshalJSON(data []byte) error {
+	type plain SimClient
+	return unmarshalWithExtra(data, (*plain)(s), &s.Extra)
+}
+
+// MarshalJSON encodes SimClient with fields from Extra
+func (s SimClient) MarshalJSON() ([]byte, error) {
+	type plain SimClient
+	return marshalWithExtra((*plain)(&s), s.Extra)
+}
+
+// UnmarshalJSON decodes HSTSPolicy and keeps unknown fields in Extra
+func (h *HSTSPolicy) UnmarshalJSON(data []byte) error {
+	type plain HSTSPolicy
+	return unmarshalWithExtra(data, (*plain)(h), &h.Extra)
+}
+
+// MarshalJSON encodes HSTSPolicy with fields from Extra
+func (h HSTSPolicy) MarshalJSON() ([]byte, error) {
+	type plain HSTSPolicy
+	return marshalWithExtra((*plain)(&h), h.Extra)
+}
+
+// UnmarshalJSON decodes HSTSPreload and keeps unknown fields in Extra
+func (h *HSTSPreload) UnmarshalJSON(data []byte) error {
+	type plain HSTSPreload
+	return unmarshalWithExtra(data, (*plain)(h), &h.Extra)
+}
+
+// MarshalJSON encodes HSTSPreload with fields from Extra
+func (h HSTSPreload) MarshalJSON() ([]byte, error) {
+	type plain HSTSPreload
+	return marshalWithExtra((*plain)(&h), h.Extra)
+}
+
+// UnmarshalJSON decodes HPKPPolicy and keeps unknown fields in Extra
+func (h *HPKPPolicy) UnmarshalJSON(data []byte) error {
+	type plain HPKPPolicy
+	return unmarshalWithExtra(data, (*plain)(h), &h.Extra)
+}
+
+// MarshalJSON encodes HPKPPolicy with fields from Extra
+func (h HPKPPolicy) MarshalJSON() ([]byte, error) {
+	type plain HPKPPolicy
+	return marshalWithExtra((*plain)(&h), h.Extra)
+}
+
+// UnmarshalJSON decodes SPKPPolicy and keeps unknown fields in Extra
+func (p *SPKPPolicy) UnmarshalJSON(data []byte) error {
+	type plain SPKPPolicy
+	return unmarshalWithExtra(data, (*plain)(p), &p.Extra)
+}
+
+// MarshalJSON encodes SPKPPolicy with fields from Extra
+func (p SPKPPolicy) MarshalJSON() ([]byte, error) {
+	type plain SPKPPolicy
+	return marshalWithExtra((*plain)(&p), p.Extra)
+}
+
+// UnmarshalJSON decodes Pin and keeps unknown fields in Extra
+func (p *Pin) UnmarshalJSON(data []byte) error {
+	type plain Pin
+	return unmarshalWithExtra(data, (*plain)(p), &p.Extra)
+}
+
+// MarshalJSON encodes Pin with fields from Extra
+func (p Pin) MarshalJSON() ([]byte, error) {
+	type plain Pin
+	return marshalWithExtra((*plain)(&p), p.Extra)
+}
+
+// UnmarshalJSON decodes Directive and keeps unknown fields in Extra
+func (d *Directive) UnmarshalJSON(data []byte) error {
+	type plain Directive
+	return unmarshalWithExtra(data, (*plain)(d), &d.Extra)
+}
+
+// MarshalJSON encodes Directive with fields from Extra
+func (d Directive) MarshalJSON() ([]byte, error) {
+	type plain Directive
+	return marshalWithExtra((*plain)(&d), d.Extra)
+}
+
+// UnmarshalJSON decodes DrownHost and keeps unknown fields in Extra
+func (d *DrownHost) UnmarshalJSON(data []byte) error {
+	type plain DrownHost
+	return unmarshalWithExtra(data, (*plain)(d), &d.Extra)
+}
+
+// MarshalJSON encodes DrownHost with fields from Extra
+func (d DrownHost) MarshalJSON() ([]byte, error) {
+	type plain DrownHost
+	return marshalWithExtra((*plain)(&d), d.Extra)
+}
+
+// UnmarshalJSON decodes CAAPolicy and keeps unknown fields in Extra
+func (c *CAAPolicy) UnmarshalJSON(data []byte) error {
+	type plain CAAPolicy
+	return unmarshalWithExtra(data, (*plain)(c), &c.Extra)
+}
+
+// MarshalJSON encodes CAAPolicy with fields from Extra
+func (c CAAPolicy) MarshalJSON() ([]byte, error) {
+	type plain CAAPolicy
+	return marshalWithExtra((*plain)(&c), c.Extra)
+}
+
+// UnmarshalJSON decodes CAARecord and keeps unknown fields in Extra
+func (c *CAARecord) UnmarshalJSON(data []byte) error {
+	type plain CAARecord
+	return unmarshalWithExtra(data, (*plain)(c), &c.Extra)
+}
+
+// MarshalJSON encodes CAARecord with fields from Extra
+func (c CAARecord) MarshalJSON() ([]byte, error) {
+	type plain CAARecord
+	return marshalWithExtra((*plain)(&c), c.Extra)
+}
+
+// UnmarshalJSON decodes HTTPTransaction and keeps unknown fields in Extra
+func (h *HTTPTransaction) UnmarshalJSON(data []byte) error {
+	type plain HTTPTransaction
+	return unmarshalWithExtra(data, (*plain)(h), &h.Extra)
+}
+
+// MarshalJSON encodes HTTPTransaction with fields from Extra
+func (h HTTPTransaction) MarshalJSON() ([]byte, error) {
+	type plain HTTPTransaction
+	return marshalWithExtra((*plain)(&h), h.Extra)
+}
+
+// UnmarshalJSON decodes HTTPHeader and keeps unknown fields in Extra
+func (h *HTTPHeader) UnmarshalJSON(data []byte) error {
+	type plain HTTPHeader
+	return unmarshalWithExtra(data, (*plain)(h), &h.Extra)
+}
+
+// MarshalJSON encodes HTTPHeader with fields from Extra
+func (h HTTPHeader) MarshalJSON() ([]byte, error) {
+	type plain HTTPHeader
+	return marshalWithExtra((*plain)(&h), h.Extra)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// unmarshalWithExtra decodes JSON data to struct and saves all fields which
+// are not modelled by struct to extra map
+func unmarshalWithExtra(data []byte, v interface{}, extra *map[string]json.RawMessage) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	err := json.Unmarshal(data, v)
+
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+
+	err = json.Unmarshal(data, &fields)
+
+	if err != nil {
+		return err
+	}
+
+	known := getKnownFields(reflect.TypeOf(v).Elem())
+
+	for name := range fields {
+		if known[strings.ToLower(name)] {
+			delete(fields, name)
+		}
+	}
+
+	if len(fields) == 0 {
+		*extra = nil
+	} else {
+		*extra = fields
+	}
+
+	return nil
+}
+
+// marshalWithExtra encodes struct to JSON and appends fields from extra map
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	known := getKnownFields(reflect.TypeOf(v).Elem())
+
+	var names []string
+
+	for name := range extra {
+		if !known[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return data, nil
+	}
+
+	sort.Strings(names)
+
+	buf := bytes.NewBuffer(data[:len(data)-1])
+
+	for i, name := range names {
+		if i != 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(name)
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[name])
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// getKnownFields returns set with lowercased JSON names of struct fields
+func getKnownFields(t reflect.Type) map[string]bool {
+	if known, ok := fieldsCache.Load(t); ok {
+		return known.(map[string]bool)
+	}
+
+	known := make(map[string]bool)
+
+	for name := range getJSONFields(t) {
+		known[strings.ToLower(name)] = true
+	}
+
+	fieldsCache.Store(t, known)
+
+	return known
+}
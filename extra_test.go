@@ -0,0 +1,125 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *SSLLabsSuite) TestExtraFields(c *check.C) {
+	info := &AnalyzeInfo{}
+	data := `{"host":"test.com","newField":{"a":1},"endpoints":[{"ipAddress":"127.0.0.1","details":{"protocols":[{"id":771,"x":true}]}}]}`
+
+	c.Assert(json.Unmarshal([]byte(data), info), check.IsNil)
+	c.Assert(info.Host, check.Equals, "test.com")
+	c.Assert(string(info.Extra["newField"]), check.Equals, `{"a":1}`)
+	c.Assert(info.Endpoints[0].Extra, check.IsNil)
+	c.Assert(string(info.Endpoints[0].Details.Protocols[0].Extra["x"]), check.Equals, "true")
+
+	result, err := json.Marshal(info)
+
+	c.Assert(err, check.IsNil)
+
+	info2 := &AnalyzeInfo{}
+
+	c.Assert(json.Unmarshal(result, info2), check.IsNil)
+	c.Assert(info2, check.DeepEquals, info)
+
+	// Value (non-pointer) must be encoded with extra fields too
+	result, err = json.Marshal(*info.Endpoints[0].Details.Protocols[0])
+
+	c.Assert(err, check.IsNil)
+	c.Assert(string(result), check.Equals, `{"id":771,"name":"","version":"","v2SuitesDisabled":false,"q":null,"x":true}`)
+
+	result, err = json.Marshal(HTTPHeader{Extra: map[string]json.RawMessage{"name": []byte(`"ignored"`)}})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(string(result), check.Equals, `{"name":"","value":""}`)
+
+	var header *HTTPHeader
+
+	c.Assert(json.Unmarshal([]byte("null"), &header), check.IsNil)
+	c.Assert(header, check.IsNil)
+}
+
+// TestExtraRoundTrip checks that decoding and encoding of captured responses
+// doesn't lose any data
+func (s *SSLLabsSuite) TestExtraRoundTrip(c *check.C) {
+	files, err := filepath.Glob("responses/v3-*.json")
+
+	c.Assert(err, check.IsNil)
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+
+		c.Assert(err, check.IsNil)
+
+		info := &EndpointInfo{}
+
+		c.Assert(json.Unmarshal(data, info), check.IsNil)
+
+		result, err := json.Marshal(info)
+
+		c.Assert(err, check.IsNil)
+
+		var original, encoded interface{}
+
+		c.Assert(json.Unmarshal(data, &original), check.IsNil)
+		c.Assert(json.Unmarshal(result, &encoded), check.IsNil)
+
+		if !isJSONSubset(original, encoded) {
+			c.Fatalf("Data from %s was lost after round trip", file)
+		}
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// isJSONSubset returns true if all data from v1 is present in v2
+func isJSONSubset(v1, v2 interface{}) bool {
+	switch t := v1.(type) {
+	case map[string]interface{}:
+		m, ok := v2.(map[string]interface{})
+
+		if !ok {
+			return false
+		}
+
+		for k, v := range t {
+			if !isJSONSubset(v, m[k]) {
+				return false
+			}
+		}
+
+		return true
+
+	case []interface{}:
+		s, ok := v2.([]interface{})
+
+		if !ok || len(s) != len(t) {
+			return false
+		}
+
+		for i := range t {
+			if !isJSONSubset(t[i], s[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(v1, v2)
+}
@@ -0,0 +1,441 @@
+// Package history provides storage for assessments history and trend queries
+package history
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DEFAULT_PORT is port used if port of assessment or query is not set
+const DEFAULT_PORT = 443
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Store is generic history store
+type Store interface {
+	// Save saves every endpoint of completed assessment
+	Save(info *sslscan.AnalyzeInfo) error
+
+	// Find returns records matching given query sorted by test time
+	Find(query Query) ([]*Record, error)
+
+	// Prune removes records which doesn't satisfy retention policy
+	Prune() error
+}
+
+// Record contains info about one endpoint assessment
+type Record struct {
+	Host            string                `json:"host"`            // assessment host
+	Port            int                   `json:"port"`            // assessment port
+	TestTime        int64                 `json:"testTime"`        // assessment completion time, in milliseconds since 1970
+	EngineVersion   string                `json:"engineVersion"`   // assessment engine version
+	CriteriaVersion string                `json:"criteriaVersion"` // grading criteria version
+	Endpoint        *sslscan.EndpointInfo `json:"endpoint"`        // endpoint info
+	Certs           []*sslscan.Cert       `json:"certs"`           // certificates seen during assessment
+}
+
+// Query contains records filter
+type Query struct {
+	Host     string    // assessment host (required)
+	Port     int       // assessment port (optional, 443 by default)
+	Endpoint string    // endpoint IP address (optional)
+	Since    time.Time // return only records created after given date (optional)
+	Until    time.Time // return only records created before given date (optional)
+	Limit    int       // return only N last records (optional)
+}
+
+// Retention contains retention policy
+type Retention struct {
+	MaxAge     time.Duration // maximum age of records
+	MaxRecords int           // maximum number of records per endpoint
+}
+
+// FileStore is history store which keeps records as JSON files
+type FileStore struct {
+	dir       string
+	retention Retention
+	mx        *sync.RWMutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewFileStore creates new file store in given directory
+func NewFileStore(dir string, retention Retention) (*FileStore, error) {
+	err := os.MkdirAll(dir, 0750)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{dir: dir, retention: retention, mx: &sync.RWMutex{}}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Time returns assessment completion time
+func (r *Record) Time() time.Time {
+	return time.Unix(0, r.TestTime*int64(time.Millisecond))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Save saves every endpoint of completed assessment
+func (s *FileStore) Save(info *sslscan.AnalyzeInfo) error {
+	switch {
+	case info == nil:
+		return fmt.Errorf("Assessment info is nil")
+	case info.Status != sslscan.STATUS_READY:
+		return fmt.Errorf("Only completed assessments can be saved (status is %s)", info.Status)
+	case info.Host == "":
+		return fmt.Errorf("Assessment host is empty")
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, endpoint := range info.Endpoints {
+		if endpoint == nil {
+			continue
+		}
+
+		record := &Record{
+			Host:            info.Host,
+			Port:            info.Port,
+			TestTime:        info.TestTime,
+			EngineVersion:   info.EngineVersion,
+			CriteriaVersion: info.CriteriaVersion,
+			Endpoint:        endpoint,
+			Certs:           info.Certs,
+		}
+
+		err := s.writeRecord(record)
+
+		if err != nil {
+			return err
+		}
+
+		err = s.pruneEndpoint(info.Host, info.Port, endpoint.IPAdress)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find returns records matching given query sorted by test time
+func (s *FileStore) Find(query Query) ([]*Record, error) {
+	if query.Host == "" {
+		return nil, fmt.Errorf("Query host is empty")
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	var endpoints []string
+
+	if query.Endpoint != "" {
+		endpoints = []string{query.Endpoint}
+	} else {
+		endpoints = s.listEndpoints(query.Host, query.Port)
+	}
+
+	var result []*Record
+
+	for _, endpoint := range endpoints {
+		files, err := s.listRecords(query.Host, query.Port, endpoint)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			record, err := readRecord(file)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !isMatch(record, query) {
+				continue
+			}
+
+			result = append(result, record)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].TestTime < result[j].TestTime
+	})
+
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[len(result)-query.Limit:]
+	}
+
+	return result, nil
+}
+
+// Prune removes records which doesn't satisfy retention policy
+func (s *FileStore) Prune() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	hosts, err := ioutil.ReadDir(s.dir)
+
+	if err != nil {
+		return err
+	}
+
+	for _, host := range hosts {
+		if !host.IsDir() {
+			continue
+		}
+
+		endpoints, err := ioutil.ReadDir(filepath.Join(s.dir, host.Name()))
+
+		if err != nil {
+			return err
+		}
+
+		for _, endpoint := range endpoints {
+			if !endpoint.IsDir() {
+				continue
+			}
+
+			err = s.pruneDir(filepath.Join(s.dir, host.Name(), endpoint.Name()))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// writeRecord writes record to file
+func (s *FileStore) writeRecord(record *Record) error {
+	dir, err := s.getEndpointDir(record.Host, record.Port, record.Endpoint.IPAdress)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0750)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(dir, fmt.Sprintf("%d.json", record.TestTime))
+	tmpFile := file + ".tmp"
+
+	err = ioutil.WriteFile(tmpFile, data, 0640)
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, file)
+}
+
+// pruneEndpoint removes outdated records for given endpoint
+func (s *FileStore) pruneEndpoint(host string, port int, endpoint string) error {
+	dir, err := s.getEndpointDir(host, port, endpoint)
+
+	if err != nil {
+		return err
+	}
+
+	return s.pruneDir(dir)
+}
+
+// pruneDir removes outdated records from given directory
+func (s *FileStore) pruneDir(dir string) error {
+	if s.retention.MaxAge <= 0 && s.retention.MaxRecords <= 0 {
+		return nil
+	}
+
+	files := listRecordFiles(dir)
+
+	var expired []string
+
+	if s.retention.MaxRecords > 0 && len(files) > s.retention.MaxRecords {
+		expired = files[:len(files)-s.retention.MaxRecords]
+		files = files[len(files)-s.retention.MaxRecords:]
+	}
+
+	if s.retention.MaxAge > 0 {
+		minTime := time.Now().Add(-s.retention.MaxAge).UnixNano() / int64(time.Millisecond)
+
+		for _, file := range files {
+			if getFileTestTime(file) < minTime {
+				expired = append(expired, file)
+			}
+		}
+	}
+
+	for _, file := range expired {
+		err := os.Remove(file)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listEndpoints returns list of endpoints with saved records
+func (s *FileStore) listEndpoints(host string, port int) []string {
+	var result []string
+
+	files, _ := ioutil.ReadDir(filepath.Join(s.dir, getHostKey(host, port)))
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		endpoint, err := unescapeName(file.Name())
+
+		if err == nil {
+			result = append(result, endpoint)
+		}
+	}
+
+	return result
+}
+
+// listRecords returns list of files with records for given endpoint
+func (s *FileStore) listRecords(host string, port int, endpoint string) ([]string, error) {
+	dir, err := s.getEndpointDir(host, port, endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return listRecordFiles(dir), nil
+}
+
+// getEndpointDir returns path to directory with endpoint records
+func (s *FileStore) getEndpointDir(host string, port int, endpoint string) (string, error) {
+	if endpoint == "" || endpoint == "." || endpoint == ".." {
+		return "", fmt.Errorf("Invalid endpoint address \"%s\"", endpoint)
+	}
+
+	return filepath.Join(s.dir, getHostKey(host, port), escapeName(endpoint)), nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// readRecord reads record from file
+func readRecord(file string) (*Record, error) {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{}
+	err = json.Unmarshal(data, record)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode record %s: %v", file, err)
+	}
+
+	return record, nil
+}
+
+// listRecordFiles returns records files sorted by test time
+func listRecordFiles(dir string) []string {
+	var result []string
+
+	files, _ := ioutil.ReadDir(dir)
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		result = append(result, filepath.Join(dir, file.Name()))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return getFileTestTime(result[i]) < getFileTestTime(result[j])
+	})
+
+	return result
+}
+
+// getFileTestTime extracts test time from record file name
+func getFileTestTime(file string) int64 {
+	var testTime int64
+
+	fmt.Sscanf(strings.TrimSuffix(filepath.Base(file), ".json"), "%d", &testTime)
+
+	return testTime
+}
+
+// isMatch returns true if record matches query
+func isMatch(record *Record, query Query) bool {
+	if !query.Since.IsZero() && record.Time().Before(query.Since) {
+		return false
+	}
+
+	if !query.Until.IsZero() && record.Time().After(query.Until) {
+		return false
+	}
+
+	return true
+}
+
+// getHostKey returns name of directory with records for given host and port.
+// Port is always a part of the name, so it can't be "." or "..".
+func getHostKey(host string, port int) string {
+	if port <= 0 {
+		port = DEFAULT_PORT
+	}
+
+	return escapeName(host + ":" + strconv.Itoa(port))
+}
+
+// escapeName makes host or IP safe for using as directory name. Escaping is
+// reversible and path separators are always escaped.
+func escapeName(name string) string {
+	return url.QueryEscape(name)
+}
+
+// unescapeName restores host or IP from directory name
+func unescapeName(name string) (string, error) {
+	return url.QueryUnescape(name)
+}
@@ -0,0 +1,231 @@
+package history
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+	"sort"
+	"testing"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type HistorySuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = check.Suite(&HistorySuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *HistorySuite) TestFileStore(c *check.C) {
+	store, err := NewFileStore(c.MkDir(), Retention{MaxRecords: 3})
+
+	c.Assert(err, check.IsNil)
+
+	c.Assert(store.Save(nil), check.NotNil)
+	c.Assert(store.Save(&sslscan.AnalyzeInfo{Host: "test.com", Status: sslscan.STATUS_IN_PROGRESS}), check.NotNil)
+
+	now := time.Now()
+
+	for i, grade := range []string{"B", "A", "A", "A+", "A+"} {
+		info := genAssessment(now.Add(time.Duration(i-5)*time.Hour), grade, "cert1", "TLS_AES_128_GCM_SHA256")
+
+		if i == 3 {
+			info = genAssessment(now.Add(time.Duration(i-5)*time.Hour), grade, "cert2", "TLS_CHACHA20_POLY1305_SHA256")
+		}
+
+		c.Assert(store.Save(info), check.IsNil)
+	}
+
+	_, err = store.Find(Query{})
+	c.Assert(err, check.NotNil)
+
+	records, err := store.Find(Query{Host: "test.com"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 6)
+
+	records, err = LastScans(store, Query{Host: "test.com"}, 2)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 4)
+	c.Assert(getEndpoints(records), check.DeepEquals, []string{"127.0.0.1", "::1"})
+	c.Assert(records[0].Endpoint.Grade, check.Equals, "A+")
+	c.Assert(records[3].Endpoint.Grade, check.Equals, "A+")
+
+	records, err = store.Find(Query{Host: "test.com", Endpoint: "::1"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 3)
+
+	grades, err := GradeHistory(store, Query{Host: "test.com"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(grades, check.HasLen, 6)
+	c.Assert(grades[0].Grade, check.Equals, "A")
+
+	record, err := SuiteFirstSeen(store, Query{Host: "test.com"}, "TLS_CHACHA20_POLY1305_SHA256")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(record, check.NotNil)
+	c.Assert(record.Endpoint.Grade, check.Equals, "A+")
+
+	record, err = SuiteFirstSeen(store, Query{Host: "test.com"}, "TLS_RSA_WITH_RC4_128_SHA")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(record, check.IsNil)
+
+	rotations, err := CertRotations(store, Query{Host: "test.com"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(rotations, check.HasLen, 4)
+	c.Assert(rotations[0].OldID, check.Equals, "cert1")
+	c.Assert(rotations[0].NewID, check.Equals, "cert2")
+}
+
+func (s *HistorySuite) TestPrune(c *check.C) {
+	dir := c.MkDir()
+	store, err := NewFileStore(dir, Retention{})
+
+	c.Assert(err, check.IsNil)
+
+	now := time.Now()
+
+	c.Assert(store.Save(genAssessment(now.Add(-72*time.Hour), "A", "cert1", "")), check.IsNil)
+	c.Assert(store.Save(genAssessment(now.Add(-1*time.Hour), "A", "cert1", "")), check.IsNil)
+
+	records, _ := store.Find(Query{Host: "test.com"})
+	c.Assert(records, check.HasLen, 4)
+
+	records, _ = store.Find(Query{Host: "test.com", Since: now.Add(-2 * time.Hour)})
+	c.Assert(records, check.HasLen, 2)
+
+	records, _ = store.Find(Query{Host: "test.com", Until: now.Add(-2 * time.Hour)})
+	c.Assert(records, check.HasLen, 2)
+
+	store, err = NewFileStore(dir, Retention{MaxAge: 24 * time.Hour})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(store.Prune(), check.IsNil)
+
+	records, _ = store.Find(Query{Host: "test.com"})
+	c.Assert(records, check.HasLen, 2)
+}
+
+func (s *HistorySuite) TestStoreKeys(c *check.C) {
+	dir := c.MkDir()
+	store, err := NewFileStore(dir+"/store", Retention{})
+
+	c.Assert(err, check.IsNil)
+
+	now := time.Now()
+
+	info := genAssessment(now.Add(-time.Hour), "A", "cert1", "")
+	info.Host = "my_host.test.com"
+	c.Assert(store.Save(info), check.IsNil)
+
+	info = genAssessment(now, "B", "cert2", "")
+	info.Host, info.Port = "my_host.test.com", 8443
+	c.Assert(store.Save(info), check.IsNil)
+
+	records, err := store.Find(Query{Host: "my_host.test.com"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 2)
+	c.Assert(records[0].Endpoint.Grade, check.Equals, "A")
+	c.Assert(getEndpoints(records), check.DeepEquals, []string{"127.0.0.1", "::1"})
+
+	records, err = store.Find(Query{Host: "my_host.test.com", Port: 8443})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 2)
+	c.Assert(records[0].Endpoint.Grade, check.Equals, "B")
+
+	grades, err := GradeHistory(store, Query{Host: "my_host.test.com", Port: 8443})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(grades, check.HasLen, 2)
+	c.Assert(grades[0].Grade, check.Equals, "B")
+
+	records, err = LastScans(store, Query{Host: "my_host.test.com", Port: 8443}, 1)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 2)
+	c.Assert(records[0].Port, check.Equals, 8443)
+
+	info = genAssessment(now, "A", "cert1", "")
+	info.Host = "../../x"
+	c.Assert(store.Save(info), check.IsNil)
+
+	records, err = store.Find(Query{Host: "../../x"})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 2)
+
+	files, _ := ioutil.ReadDir(dir)
+	c.Assert(files, check.HasLen, 1)
+
+	info.Endpoints[0].IPAdress = ".."
+	c.Assert(store.Save(info), check.ErrorMatches, `Invalid endpoint address ".."`)
+
+	_, err = store.Find(Query{Host: "test.com", Endpoint: "."})
+	c.Assert(err, check.NotNil)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getEndpoints returns sorted list of unique endpoints from records
+func getEndpoints(records []*Record) []string {
+	var result []string
+
+	seen := make(map[string]bool)
+
+	for _, record := range records {
+		if !seen[record.Endpoint.IPAdress] {
+			seen[record.Endpoint.IPAdress] = true
+			result = append(result, record.Endpoint.IPAdress)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func genAssessment(t time.Time, grade, certID, suite string) *sslscan.AnalyzeInfo {
+	info := &sslscan.AnalyzeInfo{
+		Host:     "test.com",
+		Port:     443,
+		Status:   sslscan.STATUS_READY,
+		TestTime: t.UnixNano() / int64(time.Millisecond),
+	}
+
+	for _, ip := range []string{"127.0.0.1", "::1"} {
+		info.Endpoints = append(info.Endpoints, &sslscan.EndpointInfo{
+			IPAdress: ip,
+			Grade:    grade,
+			Details: &sslscan.EndpointDetails{
+				CertChains: []*sslscan.ChainCert{{ID: "chain-" + certID, CertIDs: []string{certID}}},
+				Suites: []*sslscan.ProtocolSuites{{
+					Protocol: sslscan.PROTOCOL_TLS13,
+					List:     []*sslscan.Suite{{Name: suite}},
+				}},
+			},
+		})
+	}
+
+	return info
+}
@@ -0,0 +1,174 @@
+package history
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GradePoint contains endpoint grade at some point of time
+type GradePoint struct {
+	Endpoint string
+	Time     time.Time
+	Grade    string
+}
+
+// CertRotation contains info about leaf certificate change
+type CertRotation struct {
+	Endpoint string
+	Time     time.Time
+	OldID    string
+	NewID    string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GradeHistory returns grades of all endpoints over time for assessments
+// matching given query
+func GradeHistory(store Store, query Query) ([]*GradePoint, error) {
+	records, err := store.Find(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*GradePoint
+
+	for _, record := range records {
+		result = append(result, &GradePoint{
+			Endpoint: record.Endpoint.IPAdress,
+			Time:     record.Time(),
+			Grade:    record.Endpoint.Grade,
+		})
+	}
+
+	return result, nil
+}
+
+// LastScans returns records of all endpoints from N last assessments matching
+// given query
+func LastScans(store Store, query Query, n int) ([]*Record, error) {
+	query.Limit = 0
+
+	records, err := store.Find(query)
+
+	if err != nil || n <= 0 {
+		return records, err
+	}
+
+	// Records of one assessment share the same test time
+	var scans int
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if i == len(records)-1 || records[i].TestTime != records[i+1].TestTime {
+			scans++
+		}
+
+		if scans > n {
+			return records[i+1:], nil
+		}
+	}
+
+	return records, nil
+}
+
+// SuiteFirstSeen returns the first record matching given query where given
+// cipher suite was supported, or nil if suite has never been seen
+func SuiteFirstSeen(store Store, query Query, suite string) (*Record, error) {
+	records, err := store.Find(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if hasSuite(record, suite) {
+			return record, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// CertRotations returns all leaf certificate changes for all endpoints of
+// assessments matching given query
+func CertRotations(store Store, query Query) ([]*CertRotation, error) {
+	records, err := store.Find(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*CertRotation
+
+	lastCerts := make(map[string]string)
+
+	for _, record := range records {
+		ip := record.Endpoint.IPAdress
+		certID := getLeafCertID(record)
+
+		if certID == "" {
+			continue
+		}
+
+		prevID, seen := lastCerts[ip]
+
+		if seen && prevID != certID {
+			result = append(result, &CertRotation{
+				Endpoint: ip,
+				Time:     record.Time(),
+				OldID:    prevID,
+				NewID:    certID,
+			})
+		}
+
+		lastCerts[ip] = certID
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// hasSuite returns true if record contains given suite
+func hasSuite(record *Record, suite string) bool {
+	if record.Endpoint == nil || record.Endpoint.Details == nil {
+		return false
+	}
+
+	for _, ps := range record.Endpoint.Details.Suites {
+		if ps == nil {
+			continue
+		}
+
+		for _, s := range ps.List {
+			if s != nil && s.Name == suite {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getLeafCertID returns ID of endpoint leaf certificate
+func getLeafCertID(record *Record) string {
+	if record.Endpoint == nil || record.Endpoint.Details == nil {
+		return ""
+	}
+
+	for _, chain := range record.Endpoint.Details.CertChains {
+		if chain != nil && !chain.NoSNI && len(chain.CertIDs) != 0 {
+			return chain.CertIDs[0]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,501 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ROOT_STORE_SYSTEM is name of system root store
+const ROOT_STORE_SYSTEM = "System"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// RootStore is named set of trusted root certificates
+type RootStore struct {
+	Name string
+	Pool *x509.CertPool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	oidMustStaple = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+	oidSCTList    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+)
+
+// sigAlgNames contains names of signature algorithms used by SSL Labs
+var sigAlgNames = map[x509.SignatureAlgorithm]string{
+	x509.MD5WithRSA:       "MD5withRSA",
+	x509.SHA1WithRSA:      "SHA1withRSA",
+	x509.SHA256WithRSA:    "SHA256withRSA",
+	x509.SHA384WithRSA:    "SHA384withRSA",
+	x509.SHA512WithRSA:    "SHA512withRSA",
+	x509.SHA256WithRSAPSS: "SHA256withRSAandMGF1",
+	x509.SHA384WithRSAPSS: "SHA384withRSAandMGF1",
+	x509.SHA512WithRSAPSS: "SHA512withRSAandMGF1",
+	x509.ECDSAWithSHA1:    "SHA1withECDSA",
+	x509.ECDSAWithSHA256:  "SHA256withECDSA",
+	x509.ECDSAWithSHA384:  "SHA384withECDSA",
+	x509.ECDSAWithSHA512:  "SHA512withECDSA",
+	x509.PureEd25519:      "Ed25519",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SystemRootStore returns root store with system root certificates
+func SystemRootStore() *RootStore {
+	pool, err := x509.SystemCertPool()
+
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	return &RootStore{Name: ROOT_STORE_SYSTEM, Pool: pool}
+}
+
+// NewRootStore creates root store with certificates from given PEM bundle
+func NewRootStore(name string, pemData []byte) (*RootStore, error) {
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("Bundle for root store %s doesn't contain certificates", name)
+	}
+
+	return &RootStore{Name: name, Pool: pool}, nil
+}
+
+// LoadRootStore creates root store with certificates from given PEM file
+func LoadRootStore(name, file string) (*RootStore, error) {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRootStore(name, data)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeChain retrieves certificate chains presented with and without SNI,
+// checks them for issues and validates trust paths against root stores
+func (s *Scanner) ProbeChain(t *Target, d *sslscan.EndpointDetails) error {
+	d.CertChains = nil
+
+	chain, err := s.getPeerCertificates(t, t.Host)
+
+	if err != nil && isDialError(err) {
+		return err
+	}
+
+	if chain != nil {
+		d.CertChains = append(d.CertChains, s.newChainCert(t, chain))
+	}
+
+	if isIP(t.Host) {
+		return nil
+	}
+
+	noSNIChain, err := s.getPeerCertificates(t, "")
+
+	if err != nil && isDialError(err) {
+		return err
+	}
+
+	if noSNIChain != nil && !isSameChain(chain, noSNIChain) {
+		chainCert := s.newChainCert(t, noSNIChain)
+		chainCert.NoSNI = true
+		d.CertChains = append(d.CertChains, chainCert)
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getPeerCertificates returns certificates presented by server
+func (s *Scanner) getPeerCertificates(t *Target, serverName string) ([]*x509.Certificate, error) {
+	conn, err := s.tlsDial(t, &tls.Config{ServerName: serverName})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates, nil
+}
+
+// newChainCert creates chain info for given certificates
+func (s *Scanner) newChainCert(t *Target, chain []*x509.Certificate) *sslscan.ChainCert {
+	result := &sslscan.ChainCert{ID: getChainID(chain)}
+	now := time.Now()
+
+	for _, cert := range chain {
+		result.CertIDs = append(result.CertIDs, getCertID(cert))
+	}
+
+	paths, used, verifyErr := s.verifyChain(t, chain, now)
+
+	result.TrustPaths = paths
+	result.Issues = getChainIssues(chain, used, verifyErr)
+
+	for i, cert := range chain {
+		info := newCert(cert)
+
+		if i == 0 {
+			info.Issues = getCertIssues(cert, t.Host, len(used) != 0, now)
+		}
+
+		t.addCert(info)
+	}
+
+	return result
+}
+
+// verifyChain validates chain against all root stores and returns trust paths,
+// IDs of presented certificates used in trusted paths and last validation error
+func (s *Scanner) verifyChain(t *Target, chain []*x509.Certificate, now time.Time) ([]*sslscan.TrustPath, map[string]bool, error) {
+	var paths []*sslscan.TrustPath
+	var lastErr error
+
+	used := make(map[string]bool)
+	intermediates := x509.NewCertPool()
+
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	for _, store := range s.getRootStores() {
+		verified, err := chain[0].Verify(x509.VerifyOptions{
+			Roots:         store.Pool,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+
+		if err != nil {
+			lastErr = err
+			paths = addTrust(paths, getCertIDs(chain), &sslscan.TrustStore{
+				RootStore:         store.Name,
+				TrustErrorMessage: err.Error(),
+			})
+			continue
+		}
+
+		path := verified[0]
+
+		for _, cert := range path {
+			used[getCertID(cert)] = true
+		}
+
+		// Root certificate from store isn't presented by server, so
+		// we have to add it to certificates list
+		t.addCert(newCert(path[len(path)-1]))
+
+		paths = addTrust(paths, getCertIDs(path), &sslscan.TrustStore{
+			RootStore: store.Name,
+			IsTrusted: true,
+		})
+	}
+
+	return paths, used, lastErr
+}
+
+// getRootStores returns configured root stores or system store
+func (s *Scanner) getRootStores() []*RootStore {
+	if len(s.RootStores) != 0 {
+		return s.RootStores
+	}
+
+	return []*RootStore{SystemRootStore()}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// addTrust adds trust info to path with given certificates
+func addTrust(paths []*sslscan.TrustPath, certIDs []string, trust *sslscan.TrustStore) []*sslscan.TrustPath {
+	for _, path := range paths {
+		if strings.Join(path.CertIDs, ",") == strings.Join(certIDs, ",") {
+			path.Trust = append(path.Trust, trust)
+			return paths
+		}
+	}
+
+	return append(paths, &sslscan.TrustPath{
+		CertIDs: certIDs,
+		Trust:   []*sslscan.TrustStore{trust},
+	})
+}
+
+// getChainIssues returns chain issues flags
+func getChainIssues(chain []*x509.Certificate, used map[string]bool, verifyErr error) int {
+	var issues int
+
+	seen := make(map[string]bool)
+
+	for i, cert := range chain {
+		id := getCertID(cert)
+
+		if seen[id] {
+			issues |= sslscan.CERT_CHAIN_ISSUE_DUPLICATE
+		}
+
+		seen[id] = true
+
+		if i != 0 && len(used) != 0 && !used[id] {
+			issues |= sslscan.CERT_CHAIN_ISSUE_UNUSED
+		}
+
+		if i+1 < len(chain) && !isIssuedBy(cert, chain[i+1]) && hasIssuer(cert, chain) {
+			issues |= sslscan.CERT_CHAIN_ISSUE_INCORRECT_ORDER
+		}
+	}
+
+	last := chain[len(chain)-1]
+
+	if len(chain) > 1 && isSelfSigned(last) {
+		issues |= sslscan.CERT_CHAIN_ISSUE_SELF_SIGNED_ROOT
+	}
+
+	if len(used) == 0 && !isSelfSigned(last) && !hasIssuer(last, chain) {
+		if _, ok := verifyErr.(x509.UnknownAuthorityError); ok {
+			issues |= sslscan.CERT_CHAIN_ISSUE_INCOMPLETE
+		}
+	}
+
+	return issues
+}
+
+// getCertIssues returns leaf certificate issues flags
+func getCertIssues(cert *x509.Certificate, host string, trusted bool, now time.Time) int {
+	var issues int
+
+	if !trusted {
+		issues |= sslscan.CERT_ISSUE_NO_CHAIN_OF_TRUST
+	}
+
+	if now.Before(cert.NotBefore) {
+		issues |= sslscan.CERT_ISSUE_NOT_BEFORE
+	}
+
+	if now.After(cert.NotAfter) {
+		issues |= sslscan.CERT_ISSUE_NOT_AFTER
+	}
+
+	if host != "" && cert.VerifyHostname(host) != nil {
+		issues |= sslscan.CERT_ISSUE_HOSTNAME_MISMATCH
+	}
+
+	if isSelfSigned(cert) {
+		issues |= sslscan.CERT_ISSUE_SELF_SIGNED
+	}
+
+	switch cert.SignatureAlgorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		issues |= sslscan.CERT_ISSUE_INSECURE_SIGNATURE
+	}
+
+	if _, strength := getKeyInfo(cert); strength < 2048 {
+		issues |= sslscan.CERT_ISSUE_INSECURE_KEY
+	}
+
+	return issues
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newCert creates certificate info using SSL Labs conventions
+func newCert(cert *x509.Certificate) *sslscan.Cert {
+	sha1Hash := sha1.Sum(cert.Raw)
+	pin := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	result := &sslscan.Cert{
+		ID:            getCertID(cert),
+		Subject:       formatName(cert.RawSubject),
+		SerialNumber:  hex.EncodeToString(cert.SerialNumber.Bytes()),
+		AltNames:      cert.DNSNames,
+		NotBefore:     toMs(cert.NotBefore),
+		NotAfter:      toMs(cert.NotAfter),
+		IssuerSubject: formatName(cert.RawIssuer),
+		SigAlg:        sigAlgNames[cert.SignatureAlgorithm],
+		CRLURIs:       cert.CRLDistributionPoints,
+		OCSPURIs:      cert.OCSPServer,
+		SHA1Hash:      hex.EncodeToString(sha1Hash[:]),
+		SHA256Hash:    getCertID(cert),
+		PINSHA256:     base64.StdEncoding.EncodeToString(pin[:]),
+		Raw:           string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+	}
+
+	if cert.Subject.CommonName != "" {
+		result.CommonNames = []string{cert.Subject.CommonName}
+	}
+
+	result.KeyAlg, result.KeySize = getKeyAlg(cert)
+	_, result.KeyStrength = getKeyInfo(cert)
+
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidMustStaple):
+			result.MustStaple = true
+		case ext.Id.Equal(oidSCTList):
+			result.SCT = true
+		}
+	}
+
+	return result
+}
+
+// addCert adds certificate to list of collected certificates
+func (t *Target) addCert(cert *sslscan.Cert) {
+	for _, c := range t.Certs {
+		if c.ID == cert.ID {
+			if cert.Issues != 0 {
+				c.Issues = cert.Issues
+			}
+
+			return
+		}
+	}
+
+	t.Certs = append(t.Certs, cert)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getKeyAlg returns certificate key algorithm and size
+func getKeyAlg(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "EC", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "EdDSA", 256
+	}
+
+	return cert.PublicKeyAlgorithm.String(), 0
+}
+
+// getKeyInfo returns certificate key size and strength in RSA-equivalent bits
+func getKeyInfo(cert *x509.Certificate) (int, int) {
+	alg, size := getKeyAlg(cert)
+
+	if alg == "RSA" {
+		return size, size
+	}
+
+	switch {
+	case size >= 512:
+		return size, 15360
+	case size >= 384:
+		return size, 7680
+	case size >= 256:
+		return size, 3072
+	case size >= 224:
+		return size, 2048
+	}
+
+	return size, 1024
+}
+
+// getCertID returns certificate ID (SHA256 hash of DER-encoded certificate)
+func getCertID(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// getCertIDs returns IDs of all given certificates
+func getCertIDs(chain []*x509.Certificate) []string {
+	var result []string
+
+	for _, cert := range chain {
+		result = append(result, getCertID(cert))
+	}
+
+	return result
+}
+
+// getChainID returns chain ID (SHA256 hash of all certificates in chain)
+func getChainID(chain []*x509.Certificate) string {
+	hash := sha256.New()
+
+	for _, cert := range chain {
+		hash.Write(cert.Raw)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// formatName formats DER-encoded distinguished name as SSL Labs does
+// (e.g. "CN=Let's Encrypt Authority X3, O=Let's Encrypt, C=US")
+func formatName(raw []byte) string {
+	var seq pkix.RDNSequence
+
+	_, err := asn1.Unmarshal(raw, &seq)
+
+	if err != nil {
+		return ""
+	}
+
+	var result []string
+
+	for i := len(seq) - 1; i >= 0; i-- {
+		result = append(result, pkix.RDNSequence{seq[i]}.String())
+	}
+
+	return strings.Join(result, ", ")
+}
+
+// isSameChain returns true if both chains contain the same certificates
+func isSameChain(c1, c2 []*x509.Certificate) bool {
+	return len(c1) != 0 && getChainID(c1) == getChainID(c2)
+}
+
+// isSelfSigned returns true if certificate is self-signed
+func isSelfSigned(cert *x509.Certificate) bool {
+	return isIssuedBy(cert, cert)
+}
+
+// isIssuedBy returns true if certificate is signed by given issuer
+func isIssuedBy(cert, issuer *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, issuer.RawSubject) &&
+		cert.CheckSignatureFrom(issuer) == nil
+}
+
+// hasIssuer returns true if chain contains issuer of given certificate
+func hasIssuer(cert *x509.Certificate, chain []*x509.Certificate) bool {
+	for _, c := range chain {
+		if c != cert && isIssuedBy(cert, c) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,311 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// testPKI contains test CA hierarchy
+type testPKI struct {
+	Root     *x509.Certificate
+	Inter    *x509.Certificate
+	Leaf     *x509.Certificate
+	Other    *x509.Certificate
+	LeafKey  crypto.Signer
+	InterKey crypto.Signer
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestChain(c *check.C) {
+	pki := newTestPKI(c)
+
+	cases := []struct {
+		chain   []*x509.Certificate
+		issues  int
+		trusted bool
+	}{
+		{[]*x509.Certificate{pki.Leaf, pki.Inter}, 0, true},
+		{[]*x509.Certificate{pki.Leaf}, sslscan.CERT_CHAIN_ISSUE_INCOMPLETE, false},
+		{[]*x509.Certificate{pki.Leaf, pki.Inter, pki.Inter}, sslscan.CERT_CHAIN_ISSUE_DUPLICATE, true},
+		{[]*x509.Certificate{pki.Leaf, pki.Root, pki.Inter}, sslscan.CERT_CHAIN_ISSUE_INCORRECT_ORDER, true},
+		{[]*x509.Certificate{pki.Leaf, pki.Inter, pki.Root}, sslscan.CERT_CHAIN_ISSUE_SELF_SIGNED_ROOT, true},
+		{[]*x509.Certificate{pki.Leaf, pki.Inter, pki.Other}, sslscan.CERT_CHAIN_ISSUE_UNUSED | sslscan.CERT_CHAIN_ISSUE_SELF_SIGNED_ROOT, true},
+	}
+
+	for _, tc := range cases {
+		srv := startServer(c, &tls.Config{
+			Certificates: []tls.Certificate{newTestChain(tc.chain, pki.LeafKey)},
+		})
+
+		target := srv.Target()
+		details := &sslscan.EndpointDetails{}
+		scanner := newTestScanner(srv)
+		scanner.RootStores = []*RootStore{
+			{Name: "Private", Pool: newTestPool(pki.Root)},
+			{Name: "Other", Pool: newTestPool(pki.Other)},
+		}
+
+		c.Assert(scanner.ProbeChain(target, details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.CertChains, check.HasLen, 1)
+
+		chain := details.CertChains[0]
+
+		c.Assert(chain.NoSNI, check.Equals, false)
+		c.Assert(chain.Issues, check.Equals, tc.issues)
+		c.Assert(chain.CertIDs, check.DeepEquals, getCertIDs(tc.chain))
+
+		leaf := target.Certs[0]
+
+		if !tc.trusted {
+			c.Assert(chain.TrustPaths, check.HasLen, 1)
+			c.Assert(chain.TrustPaths[0].Trust, check.HasLen, 2)
+			c.Assert(chain.TrustPaths[0].Trust[0].IsTrusted, check.Equals, false)
+			c.Assert(chain.TrustPaths[0].Trust[0].TrustErrorMessage, check.Not(check.Equals), "")
+			c.Assert(leaf.Issues, check.Equals, sslscan.CERT_ISSUE_NO_CHAIN_OF_TRUST)
+			continue
+		}
+
+		c.Assert(chain.TrustPaths[0].CertIDs, check.DeepEquals, getCertIDs([]*x509.Certificate{pki.Leaf, pki.Inter, pki.Root}))
+		c.Assert(chain.TrustPaths[0].Trust[0], check.DeepEquals, &sslscan.TrustStore{RootStore: "Private", IsTrusted: true})
+		c.Assert(getTrustedStores(chain.TrustPaths), check.DeepEquals, map[string]bool{"Private": true, "Other": false})
+		c.Assert(leaf.Issues, check.Equals, 0)
+		c.Assert(findTestCert(target.Certs, pki.Root), check.NotNil)
+	}
+}
+
+func (s *LocalSuite) TestChainNoSNI(c *check.C) {
+	pki := newTestPKI(c)
+	sniChain := newTestChain([]*x509.Certificate{pki.Leaf, pki.Inter}, pki.LeafKey)
+
+	srv := startServer(c, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == "localhost" {
+				return &sniChain, nil
+			}
+
+			return &s.cert, nil
+		},
+	})
+
+	defer srv.Close()
+
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+	scanner.RootStores = []*RootStore{{Name: "Private", Pool: newTestPool(pki.Root)}}
+
+	c.Assert(scanner.ProbeChain(srv.Target(), details), check.IsNil)
+	c.Assert(details.CertChains, check.HasLen, 2)
+	c.Assert(details.CertChains[0].NoSNI, check.Equals, false)
+	c.Assert(details.CertChains[0].CertIDs, check.HasLen, 2)
+	c.Assert(details.CertChains[1].NoSNI, check.Equals, true)
+	c.Assert(details.CertChains[1].CertIDs, check.HasLen, 1)
+
+	srv.Close()
+
+	c.Assert(scanner.ProbeChain(srv.Target(), details), check.NotNil)
+}
+
+func (s *LocalSuite) TestCertInfo(c *check.C) {
+	pki := newTestPKI(c)
+	cert := newCert(pki.Leaf)
+
+	c.Assert(cert.ID, check.HasLen, 64)
+	c.Assert(cert.SHA256Hash, check.Equals, cert.ID)
+	c.Assert(cert.SHA1Hash, check.HasLen, 40)
+	c.Assert(cert.PINSHA256, check.HasLen, 44)
+	c.Assert(cert.Subject, check.Equals, "CN=localhost, O=Test, C=US")
+	c.Assert(cert.IssuerSubject, check.Equals, "CN=Test Intermediate CA, O=Test, C=US")
+	c.Assert(cert.CommonNames, check.DeepEquals, []string{"localhost"})
+	c.Assert(cert.AltNames, check.DeepEquals, []string{"localhost"})
+	c.Assert(cert.SerialNumber, check.Equals, "03")
+	c.Assert(cert.SigAlg, check.Equals, "SHA256withECDSA")
+	c.Assert(cert.KeyAlg, check.Equals, "EC")
+	c.Assert(cert.KeySize, check.Equals, 256)
+	c.Assert(cert.KeyStrength, check.Equals, 3072)
+	c.Assert(cert.NotAfter, check.Equals, toMs(pki.Leaf.NotAfter))
+	c.Assert(cert.Raw, check.Matches, "-----BEGIN CERTIFICATE-----\n(?s).*")
+
+	c.Assert(formatName([]byte{1, 2, 3}), check.Equals, "")
+	c.Assert(getCertIssues(pki.Leaf, "example.com", true, time.Now().Add(-48*time.Hour)), check.Equals,
+		sslscan.CERT_ISSUE_NOT_BEFORE|sslscan.CERT_ISSUE_HOSTNAME_MISMATCH)
+	c.Assert(getCertIssues(pki.Root, "", true, time.Now().Add(48*time.Hour)), check.Equals,
+		sslscan.CERT_ISSUE_NOT_AFTER|sslscan.CERT_ISSUE_SELF_SIGNED)
+}
+
+func (s *LocalSuite) TestRootStore(c *check.C) {
+	pki := newTestPKI(c)
+	dir := c.MkDir()
+
+	ioutil.WriteFile(dir+"/ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pki.Root.Raw}), 0644)
+	ioutil.WriteFile(dir+"/empty.pem", []byte("test"), 0644)
+
+	store, err := LoadRootStore("Private", dir+"/ca.pem")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(store.Name, check.Equals, "Private")
+
+	_, err = pki.Inter.Verify(x509.VerifyOptions{Roots: store.Pool})
+
+	c.Assert(err, check.IsNil)
+
+	_, err = LoadRootStore("Private", dir+"/empty.pem")
+
+	c.Assert(err, check.ErrorMatches, "Bundle for root store Private doesn't contain certificates")
+
+	_, err = LoadRootStore("Private", dir+"/unknown.pem")
+
+	c.Assert(err, check.NotNil)
+
+	stores := New().getRootStores()
+
+	c.Assert(stores, check.HasLen, 1)
+	c.Assert(stores[0].Name, check.Equals, ROOT_STORE_SYSTEM)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newTestPKI creates root CA, intermediate CA, leaf certificate issued by
+// intermediate CA and unrelated self-signed CA
+func newTestPKI(c *check.C) *testPKI {
+	pki := &testPKI{}
+
+	rootKey, intermKey, otherKey := newTestKey(c), newTestKey(c), newTestKey(c)
+	leafKey := newTestKey(c)
+
+	pki.Root = newTestIssuedCert(c, newTestCATemplate(1, "Test Root CA"), nil, rootKey, rootKey)
+	pki.Inter = newTestIssuedCert(c, newTestCATemplate(2, "Test Intermediate CA"), pki.Root, intermKey, rootKey)
+	pki.Other = newTestIssuedCert(c, newTestCATemplate(4, "Other Root CA"), nil, otherKey, otherKey)
+
+	pki.Leaf = newTestIssuedCert(c, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "localhost", Organization: []string{"Test"}, Country: []string{"US"}},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, pki.Inter, leafKey, intermKey)
+
+	pki.LeafKey = leafKey
+	pki.InterKey = intermKey
+
+	return pki
+}
+
+// newTestCATemplate creates template for CA certificate
+func newTestCATemplate(serial int64, name string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"Test"}, Country: []string{"US"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+}
+
+// newTestIssuedCert creates certificate signed by given issuer (or self-signed
+// certificate if issuer is nil)
+func newTestIssuedCert(c *check.C, template, issuer *x509.Certificate, key, issuerKey *ecdsa.PrivateKey) *x509.Certificate {
+	if issuer == nil {
+		issuer = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+
+	if err != nil {
+		c.Fatalf("Can't create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+
+	if err != nil {
+		c.Fatalf("Can't parse certificate: %v", err)
+	}
+
+	return cert
+}
+
+// newTestKey generates ECDSA key
+func newTestKey(c *check.C) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		c.Fatalf("Can't generate key: %v", err)
+	}
+
+	return key
+}
+
+// newTestChain creates TLS certificate with given chain
+func newTestChain(chain []*x509.Certificate, key crypto.Signer) tls.Certificate {
+	result := tls.Certificate{PrivateKey: key}
+
+	for _, cert := range chain {
+		result.Certificate = append(result.Certificate, cert.Raw)
+	}
+
+	return result
+}
+
+// newTestPool creates pool with given certificates
+func newTestPool(certs ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+
+	return pool
+}
+
+// getTrustedStores returns trust status for every root store
+func getTrustedStores(paths []*sslscan.TrustPath) map[string]bool {
+	result := make(map[string]bool)
+
+	for _, path := range paths {
+		for _, trust := range path.Trust {
+			result[trust.RootStore] = trust.IsTrusted
+		}
+	}
+
+	return result
+}
+
+// findTestCert finds info about given certificate
+func findTestCert(certs []*sslscan.Cert, cert *x509.Certificate) *sslscan.Cert {
+	for _, c := range certs {
+		if c.ID == getCertID(cert) {
+			return c
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	protocolsTLS10to12 = []int{sslscan.PROTOCOL_TLS10, sslscan.PROTOCOL_TLS11, sslscan.PROTOCOL_TLS12}
+	protocolsTLS10to13 = []int{sslscan.PROTOCOL_TLS10, sslscan.PROTOCOL_TLS11, sslscan.PROTOCOL_TLS12, sslscan.PROTOCOL_TLS13}
+	protocolsTLS12to13 = []int{sslscan.PROTOCOL_TLS12, sslscan.PROTOCOL_TLS13}
+)
+
+var (
+	groupsLegacy = []string{"secp256r1", "secp384r1", "secp521r1"}
+	groupsModern = []string{"x25519", "secp256r1", "secp384r1"}
+)
+
+var (
+	sigAlgsLegacy = []string{
+		"rsa_pkcs1_sha512", "ecdsa_secp521r1_sha512", "rsa_pkcs1_sha384",
+		"ecdsa_secp384r1_sha384", "rsa_pkcs1_sha256", "ecdsa_secp256r1_sha256",
+		"rsa_pkcs1_sha1", "ecdsa_sha1",
+	}
+
+	sigAlgsModern = []string{
+		"ecdsa_secp256r1_sha256", "rsa_pss_rsae_sha256", "rsa_pkcs1_sha256",
+		"ecdsa_secp384r1_sha384", "rsa_pss_rsae_sha384", "rsa_pkcs1_sha384",
+		"rsa_pss_rsae_sha512", "rsa_pkcs1_sha512", "rsa_pkcs1_sha1",
+	}
+)
+
+var (
+	suitesTLS13 = []string{
+		"TLS_AES_128_GCM_SHA256", "TLS_AES_256_GCM_SHA384", "TLS_CHACHA20_POLY1305_SHA256",
+	}
+
+	suitesModern = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_CBC_SHA",
+	}
+
+	suitesAndroid44 = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_DHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256",
+		"TLS_DHE_RSA_WITH_AES_256_CBC_SHA", "TLS_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_RSA_WITH_AES_256_CBC_SHA256", "TLS_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+		"TLS_DHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_DHE_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_RSA_WITH_AES_128_CBC_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA",
+		"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA", "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+		"TLS_RSA_WITH_RC4_128_SHA", "TLS_RSA_WITH_RC4_128_MD5",
+	}
+
+	suitesIE11 = []string{
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+		"TLS_DHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_RSA_WITH_AES_256_GCM_SHA384", "TLS_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_RSA_WITH_AES_256_CBC_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_RSA_WITH_AES_256_CBC_SHA", "TLS_RSA_WITH_AES_128_CBC_SHA",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384", "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+		"TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	}
+
+	suitesJava = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_DHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+		"TLS_RSA_WITH_AES_256_CBC_SHA256", "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_RSA_WITH_AES_256_CBC_SHA", "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_RSA_WITH_AES_128_CBC_SHA256", "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+		"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	}
+
+	suitesOpenSSL = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_DHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256", "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_DHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384",
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384", "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_DHE_RSA_WITH_AES_128_CBC_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA", "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+		"TLS_DHE_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_256_CBC_SHA256",
+		"TLS_RSA_WITH_AES_128_CBC_SHA256", "TLS_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_RSA_WITH_AES_128_CBC_SHA",
+	}
+
+	suitesATS = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384", "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	}
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DefaultClients is default catalogue of simulated clients (IDs are the same
+// as used by SSL Labs)
+var DefaultClients = []*ClientProfile{
+	{
+		ID: 62, Name: "Android", Version: "4.4.2",
+		Protocols: protocolsTLS10to12, Suites: suitesAndroid44,
+		Groups: []string{"secp521r1", "secp384r1", "secp256r1"}, SigAlgs: sigAlgsLegacy,
+	},
+	{
+		ID: 167, Name: "Android", Version: "7.0",
+		Protocols: protocolsTLS10to12, Suites: suitesModern,
+		Groups: groupsModern, SigAlgs: sigAlgsLegacy,
+	},
+	{
+		ID: 158, Name: "Android", Version: "9.0",
+		Protocols: protocolsTLS10to13, Suites: concat(suitesTLS13, suitesModern),
+		Groups: groupsModern, SigAlgs: sigAlgsModern,
+	},
+	{
+		ID: 170, Name: "Chrome", Platform: "Win 10", Version: "80", IsReference: true,
+		Protocols: protocolsTLS10to13, Suites: concat(suitesTLS13, suitesModern, []string{"TLS_RSA_WITH_3DES_EDE_CBC_SHA"}),
+		Groups: groupsModern, SigAlgs: sigAlgsModern,
+	},
+	{
+		ID: 171, Name: "Firefox", Platform: "Win 10", Version: "73", IsReference: true,
+		Protocols: protocolsTLS10to13, Suites: concat(suitesTLS13, suitesModern, []string{"TLS_RSA_WITH_3DES_EDE_CBC_SHA"}),
+		Groups: []string{"x25519", "secp256r1", "secp384r1", "secp521r1", "ffdhe2048", "ffdhe3072"}, SigAlgs: sigAlgsModern,
+	},
+	{
+		ID: 143, Name: "IE", Platform: "Win 7", Version: "11", IsReference: true,
+		Protocols: protocolsTLS10to12, Suites: suitesIE11,
+		Groups: []string{"secp256r1", "secp384r1"}, SigAlgs: sigAlgsLegacy,
+	},
+	{
+		ID: 131, Name: "IE", Platform: "Win 10", Version: "11", IsReference: true,
+		Protocols: protocolsTLS10to12, Suites: concat([]string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}, suitesIE11),
+		Groups: []string{"secp256r1", "secp384r1"}, SigAlgs: sigAlgsLegacy,
+	},
+	{
+		ID: 147, Name: "Java", Version: "8u161",
+		Protocols: protocolsTLS10to12, Suites: suitesJava,
+		Groups: groupsLegacy, SigAlgs: sigAlgsLegacy,
+	},
+	{
+		ID: 162, Name: "Java", Version: "11.0.3",
+		Protocols: protocolsTLS10to13, Suites: concat(suitesTLS13[:2], suitesJava),
+		Groups: concat(groupsLegacy, []string{"ffdhe2048", "ffdhe3072"}), SigAlgs: sigAlgsModern,
+	},
+	{
+		ID: 164, Name: "OpenSSL", Version: "1.0.2s", IsReference: true,
+		Protocols: protocolsTLS10to12, Suites: suitesOpenSSL,
+		Groups: []string{"secp256r1", "secp521r1", "secp384r1"}, SigAlgs: sigAlgsLegacy,
+	},
+	{
+		ID: 165, Name: "OpenSSL", Version: "1.1.1c", IsReference: true,
+		Protocols: protocolsTLS10to13, Suites: concat([]string{"TLS_AES_256_GCM_SHA384", "TLS_CHACHA20_POLY1305_SHA256", "TLS_AES_128_GCM_SHA256"}, suitesOpenSSL),
+		Groups: []string{"x25519", "secp256r1", "x448", "secp521r1", "secp384r1"}, SigAlgs: sigAlgsModern,
+	},
+	{
+		ID: 166, Name: "Safari", Platform: "iOS 12.3.1", Version: "12.1.1", IsReference: true,
+		Protocols: protocolsTLS10to13, Suites: concat(suitesTLS13, suitesATS),
+		Groups: []string{"x25519", "secp256r1", "secp384r1", "secp521r1"}, SigAlgs: sigAlgsModern,
+	},
+	{
+		ID: 112, Name: "Apple ATS", Platform: "iOS 9", Version: "9", IsReference: true,
+		Protocols: []int{sslscan.PROTOCOL_TLS12}, Suites: suitesATS,
+		Groups: []string{"secp256r1", "secp384r1", "secp521r1"}, SigAlgs: sigAlgsLegacy,
+	},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// concat joins given lists into new list
+func concat(lists ...[]string) []string {
+	var result []string
+
+	for _, list := range lists {
+		result = append(result, list...)
+	}
+
+	return result
+}
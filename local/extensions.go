@@ -0,0 +1,265 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// appProtocols is list of application protocols offered with ALPN
+var appProtocols = []string{
+	"h2", "http/1.1", "http/1.0", "spdy/3.1", "spdy/3", "spdy/2",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeSNI checks if server requires SNI for handshake
+func (s *Scanner) ProbeSNI(t *Target, d *sslscan.EndpointDetails) error {
+	d.SNIRequired = false
+
+	if isIP(t.Host) {
+		return nil
+	}
+
+	conn, err := s.tlsDial(t, &tls.Config{ServerName: t.Host})
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	conn.Close()
+
+	conn, err = s.tlsDial(t, &tls.Config{})
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		d.SNIRequired = true
+
+		return nil
+	}
+
+	conn.Close()
+
+	return nil
+}
+
+// ProbeALPN checks which application protocols server supports with ALPN.
+// Protocols are listed in server preference order.
+func (s *Scanner) ProbeALPN(t *Target, d *sslscan.EndpointDetails) error {
+	d.SupportsALPN, d.ALPNProtocols = false, ""
+
+	var supported []string
+
+	remaining := append([]string{}, appProtocols...)
+
+	for len(remaining) != 0 {
+		conn, err := s.tlsDial(t, &tls.Config{
+			ServerName: getServerName(t),
+			NextProtos: remaining,
+		})
+
+		if err != nil {
+			if isDialError(err) {
+				return err
+			}
+
+			break
+		}
+
+		proto := conn.ConnectionState().NegotiatedProtocol
+		conn.Close()
+
+		if !containsString(remaining, proto) {
+			break
+		}
+
+		supported = append(supported, proto)
+		remaining = removeString(remaining, proto)
+	}
+
+	if len(supported) != 0 {
+		d.SupportsALPN, d.ALPNProtocols = true, strings.Join(supported, " ")
+	}
+
+	return nil
+}
+
+// ProbeNPN checks if server supports NPN and which protocols it advertises
+func (s *Scanner) ProbeNPN(t *Target, d *sslscan.EndpointDetails) error {
+	d.SupportsNPN, d.NPNProtocols = false, ""
+
+	version := getMaxLegacyProtocol(d.Protocols)
+
+	if version == 0 {
+		return nil
+	}
+
+	hello := newClientHello(version, t.Host)
+	hello.Extensions = []extension{{Type: EXT_NPN}}
+
+	sh, err := s.handshake(t, hello)
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	data, ok := sh.Extensions[EXT_NPN]
+
+	if !ok {
+		return nil
+	}
+
+	d.SupportsNPN = true
+	d.NPNProtocols = strings.Join(parseProtocolList(data), " ")
+
+	return nil
+}
+
+// ProbeOCSPStapling checks if server staples OCSP response and validates it
+func (s *Scanner) ProbeOCSPStapling(t *Target, d *sslscan.EndpointDetails) error {
+	d.OCSPStapling = false
+	d.StaplingRevocationStatus = sslscan.REVOCATION_STATUS_NOT_CHECKED
+	d.StaplingRevocationErrorMessage = ""
+
+	conn, err := s.tlsDial(t, &tls.Config{ServerName: getServerName(t)})
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	state := conn.ConnectionState()
+	conn.Close()
+
+	if len(state.OCSPResponse) == 0 {
+		return nil
+	}
+
+	d.OCSPStapling = true
+	d.StaplingRevocationStatus, d.StaplingRevocationErrorMessage = checkOCSPResponse(
+		state.OCSPResponse, state.PeerCertificates, time.Now(),
+	)
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// checkOCSPResponse validates OCSP response for leaf certificate and returns
+// revocation status and error message
+func checkOCSPResponse(data []byte, chain []*x509.Certificate, now time.Time) (int, string) {
+	var issuer *x509.Certificate
+
+	switch {
+	case len(chain) == 0:
+		return sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR, "Server didn't send certificate"
+	case len(chain) > 1:
+		issuer = chain[1]
+	case isSelfSigned(chain[0]):
+		issuer = chain[0]
+	}
+
+	resp, err := ocsp.ParseResponseForCert(data, chain[0], issuer)
+
+	if err != nil {
+		return sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR, "Invalid OCSP response: " + err.Error()
+	}
+
+	if !resp.NextUpdate.IsZero() && now.After(resp.NextUpdate) {
+		return sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR, "OCSP response is expired"
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		return sslscan.REVOCATION_STATUS_NOT_REVOKED, ""
+	case ocsp.Revoked:
+		return sslscan.REVOCATION_STATUS_REVOKED, ""
+	}
+
+	return sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR, "OCSP responder doesn't know about certificate"
+}
+
+// parseProtocolList parses list of length-prefixed protocol names
+func parseProtocolList(data []byte) []string {
+	var result []string
+
+	p := &parser{data: data}
+
+	for p.Len() != 0 {
+		proto := p.Bytes(int(p.Uint8()))
+
+		if p.failed {
+			break
+		}
+
+		result = append(result, string(proto))
+	}
+
+	return result
+}
+
+// getMaxLegacyProtocol returns max supported protocol version which supports
+// extensions and isn't TLS 1.3
+func getMaxLegacyProtocol(protocols []*sslscan.Protocol) int {
+	var result int
+
+	for _, p := range protocols {
+		if p.ID >= sslscan.PROTOCOL_TLS10 && p.ID <= sslscan.PROTOCOL_TLS12 && p.ID > result {
+			result = p.ID
+		}
+	}
+
+	return result
+}
+
+// containsString returns true if slice contains given string
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeString returns copy of slice without given string
+func removeString(list []string, value string) []string {
+	var result []string
+
+	for _, v := range list {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,187 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestALPN(c *check.C) {
+	cases := []struct {
+		protos   []string
+		expected string
+	}{
+		{[]string{"h2", "http/1.1"}, "h2 http/1.1"},
+		{[]string{"http/1.1", "spdy/3"}, "http/1.1 spdy/3"},
+		{nil, ""},
+	}
+
+	for _, tc := range cases {
+		srv := startServer(c, &tls.Config{
+			Certificates: []tls.Certificate{s.cert},
+			NextProtos:   tc.protos,
+		})
+
+		details := &sslscan.EndpointDetails{}
+
+		c.Assert(newTestScanner(srv).ProbeALPN(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.SupportsALPN, check.Equals, tc.expected != "")
+		c.Assert(details.ALPNProtocols, check.Equals, tc.expected)
+	}
+}
+
+func (s *LocalSuite) TestNPN(c *check.C) {
+	for _, protos := range [][]string{{"h2", "http/1.1"}, nil} {
+		srv := startFakeServer(c, &fakeConfig{
+			Versions: []uint16{sslscan.PROTOCOL_TLS12},
+			Suites:   []uint16{0xc02f},
+			Group:    23,
+			Cert:     s.cert.Certificate[0],
+			NPN:      protos,
+		})
+
+		details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS12)}}
+
+		c.Assert(newTestScanner(srv).ProbeNPN(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.SupportsNPN, check.Equals, protos != nil)
+		c.Assert(details.NPNProtocols, check.Equals, map[bool]string{true: "h2 http/1.1", false: ""}[protos != nil])
+	}
+
+	details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS13)}}
+
+	c.Assert(New().ProbeNPN(&Target{}, details), check.IsNil)
+	c.Assert(details.SupportsNPN, check.Equals, false)
+
+	c.Assert(parseProtocolList([]byte{2, 'h', '2', 5, 'h'}), check.DeepEquals, []string{"h2"})
+}
+
+func (s *LocalSuite) TestSNIRequired(c *check.C) {
+	srv := startServer(c, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == "" {
+				return nil, errors.New("SNI is required")
+			}
+
+			return &s.cert, nil
+		},
+	})
+
+	defer srv.Close()
+
+	target := srv.Target()
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+
+	c.Assert(scanner.ProbeSNI(target, details), check.IsNil)
+	c.Assert(details.SNIRequired, check.Equals, true)
+
+	target.Host = "127.0.0.1"
+
+	c.Assert(scanner.ProbeSNI(target, details), check.IsNil)
+	c.Assert(details.SNIRequired, check.Equals, false)
+
+	srv2 := startServer(c, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+	defer srv2.Close()
+
+	c.Assert(newTestScanner(srv2).ProbeSNI(srv2.Target(), details), check.IsNil)
+	c.Assert(details.SNIRequired, check.Equals, false)
+}
+
+func (s *LocalSuite) TestOCSPStapling(c *check.C) {
+	pki := newTestPKI(c)
+
+	cases := []struct {
+		status   int
+		expected int
+	}{
+		{ocsp.Good, sslscan.REVOCATION_STATUS_NOT_REVOKED},
+		{ocsp.Revoked, sslscan.REVOCATION_STATUS_REVOKED},
+		{ocsp.Unknown, sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR},
+	}
+
+	for _, tc := range cases {
+		cert := newTestChain([]*x509.Certificate{pki.Leaf, pki.Inter}, pki.LeafKey)
+		cert.OCSPStaple = newTestOCSPResponse(c, pki, tc.status, time.Now().Add(time.Hour))
+
+		srv := startServer(c, &tls.Config{Certificates: []tls.Certificate{cert}})
+		details := &sslscan.EndpointDetails{}
+
+		c.Assert(newTestScanner(srv).ProbeOCSPStapling(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.OCSPStapling, check.Equals, true)
+		c.Assert(details.StaplingRevocationStatus, check.Equals, tc.expected)
+	}
+
+	srv := startServer(c, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+	details := &sslscan.EndpointDetails{}
+
+	c.Assert(newTestScanner(srv).ProbeOCSPStapling(srv.Target(), details), check.IsNil)
+
+	srv.Close()
+
+	c.Assert(details.OCSPStapling, check.Equals, false)
+	c.Assert(details.StaplingRevocationStatus, check.Equals, sslscan.REVOCATION_STATUS_NOT_CHECKED)
+
+	chain := []*x509.Certificate{pki.Leaf, pki.Inter}
+	expired := newTestOCSPResponse(c, pki, ocsp.Good, time.Now().Add(-time.Minute))
+
+	status, msg := checkOCSPResponse(expired, chain, time.Now())
+	c.Assert(status, check.Equals, sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR)
+	c.Assert(msg, check.Equals, "OCSP response is expired")
+
+	status, msg = checkOCSPResponse([]byte{1, 2, 3}, chain, time.Now())
+	c.Assert(status, check.Equals, sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR)
+	c.Assert(msg, check.Matches, "Invalid OCSP response: .*")
+
+	status, _ = checkOCSPResponse(expired, nil, time.Now())
+	c.Assert(status, check.Equals, sslscan.REVOCATION_STATUS_REVOCATION_CHECK_ERROR)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newTestOCSPResponse creates OCSP response for leaf certificate signed by
+// intermediate CA
+func newTestOCSPResponse(c *check.C, pki *testPKI, status int, nextUpdate time.Time) []byte {
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: pki.Leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   nextUpdate,
+	}
+
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-time.Hour)
+	}
+
+	resp, err := ocsp.CreateResponse(pki.Inter, pki.Inter, template, pki.InterKey)
+
+	if err != nil {
+		c.Fatalf("Can't create OCSP response: %v", err)
+	}
+
+	return resp
+}
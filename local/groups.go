@@ -0,0 +1,192 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	GROUP_TYPE_EC = "EC"
+	GROUP_TYPE_DH = "DH"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// groupInfo contains info about named group
+type groupInfo struct {
+	ID       uint16
+	Name     string
+	Bits     int    // group size in bits
+	Type     string // EC or DH
+	Strength int    // strength in RSA-equivalent bits
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// groups is catalogue of known named groups
+var groups = []*groupInfo{
+	{29, "x25519", 256, GROUP_TYPE_EC, 3072},
+	{30, "x448", 448, GROUP_TYPE_EC, 7680},
+	{23, "secp256r1", 256, GROUP_TYPE_EC, 3072},
+	{24, "secp384r1", 384, GROUP_TYPE_EC, 7680},
+	{25, "secp521r1", 521, GROUP_TYPE_EC, 15360},
+	{256, "ffdhe2048", 2048, GROUP_TYPE_DH, 2048},
+	{257, "ffdhe3072", 3072, GROUP_TYPE_DH, 3072},
+	{258, "ffdhe4096", 4096, GROUP_TYPE_DH, 4096},
+	{259, "ffdhe6144", 6144, GROUP_TYPE_DH, 6144},
+	{260, "ffdhe8192", 8192, GROUP_TYPE_DH, 8192},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getGroupInfo returns info about named group with given ID
+func getGroupInfo(id uint16) *groupInfo {
+	for _, g := range groups {
+		if g.ID == id {
+			return g
+		}
+	}
+
+	return &groupInfo{ID: id, Name: fmt.Sprintf("0x%04x", id)}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeNamedGroups checks which named groups are supported by server and
+// if server has preferred groups
+func (s *Scanner) ProbeNamedGroups(t *Target, d *sslscan.EndpointDetails) error {
+	d.NamedGroups = nil
+
+	version := getMaxProtocol(d.Protocols)
+
+	if version < sslscan.PROTOCOL_TLS10 {
+		return nil
+	}
+
+	var supported []uint16
+
+	for _, g := range groups {
+		// DH groups can be negotiated only with TLS 1.3
+		if g.Type == GROUP_TYPE_DH && version != sslscan.PROTOCOL_TLS13 {
+			continue
+		}
+
+		group, err := s.negotiateGroup(t, version, []uint16{g.ID})
+
+		if err != nil {
+			return err
+		}
+
+		if group == g.ID {
+			supported = append(supported, g.ID)
+		}
+	}
+
+	if len(supported) == 0 {
+		return nil
+	}
+
+	ordered, preference, err := s.orderGroups(t, version, supported)
+
+	if err != nil {
+		return err
+	}
+
+	d.NamedGroups = &sslscan.NamedGroups{Preference: preference}
+
+	for _, id := range ordered {
+		g := getGroupInfo(id)
+		d.NamedGroups.List = append(d.NamedGroups.List, sslscan.NamedGroup{
+			ID:             int(g.ID),
+			Name:           g.Name,
+			Bits:           g.Bits,
+			NamedGroupType: g.Type,
+		})
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// orderGroups offers supported groups in reverse order and removes selected
+// group until all groups are selected. If server always selects first offered
+// group, it hasn't preference, and groups are returned in original order.
+func (s *Scanner) orderGroups(t *Target, version int, supported []uint16) ([]uint16, bool, error) {
+	var ordered []uint16
+	var preference bool
+
+	remaining := reverseUint16(supported)
+
+	for len(remaining) != 0 {
+		group, err := s.negotiateGroup(t, version, remaining)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !containsUint16(remaining, group) {
+			break
+		}
+
+		if group != remaining[0] {
+			preference = true
+		}
+
+		ordered = append(ordered, group)
+		remaining = removeUint16(remaining, group)
+	}
+
+	if !preference || len(remaining) != 0 {
+		return supported, preference, nil
+	}
+
+	return ordered, true, nil
+}
+
+// negotiateGroup offers given groups and returns group selected by server
+func (s *Scanner) negotiateGroup(t *Target, version int, offered []uint16) (uint16, error) {
+	hello := newClientHello(version, t.Host)
+	hello.Groups = offered
+
+	if version != sslscan.PROTOCOL_TLS13 {
+		hello.Suites = getKxSuiteIDs(KX_ECDH)
+	}
+
+	sh, err := s.handshake(t, hello)
+
+	if err != nil {
+		if isDialError(err) {
+			return 0, err
+		}
+
+		return 0, nil
+	}
+
+	group, _ := parseKeyExchange(KX_ECDH, sh)
+
+	return group, nil
+}
+
+// getMaxProtocol returns max supported protocol version
+func getMaxProtocol(protocols []*sslscan.Protocol) int {
+	var result int
+
+	for _, p := range protocols {
+		if p.ID > result {
+			result = p.ID
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,90 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestNamedGroups(c *check.C) {
+	cases := []struct {
+		max        uint16
+		curves     []tls.CurveID
+		expected   []string
+		preference bool
+	}{
+		{tls.VersionTLS13, []tls.CurveID{tls.CurveP384, tls.X25519}, []string{"x25519", "secp384r1"}, true},
+		{tls.VersionTLS12, []tls.CurveID{tls.CurveP521, tls.CurveP256}, []string{"secp256r1", "secp521r1"}, false},
+	}
+
+	for _, tc := range cases {
+		srv := startServer(c, &tls.Config{
+			Certificates:     []tls.Certificate{s.cert},
+			MaxVersion:       tc.max,
+			CurvePreferences: tc.curves,
+		})
+
+		details := &sslscan.EndpointDetails{}
+		scanner := newTestScanner(srv)
+
+		c.Assert(scanner.ProbeProtocols(srv.Target(), details), check.IsNil)
+		c.Assert(scanner.ProbeNamedGroups(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.NamedGroups, check.NotNil)
+		c.Assert(details.NamedGroups.Preference, check.Equals, tc.preference)
+		c.Assert(getGroupNames(details.NamedGroups.List), check.DeepEquals, tc.expected)
+	}
+
+	c.Assert(getGroupInfo(0xfefe).Name, check.Equals, "0xfefe")
+
+	details := &sslscan.EndpointDetails{}
+
+	c.Assert(New().ProbeNamedGroups(&Target{}, details), check.IsNil)
+	c.Assert(details.NamedGroups, check.IsNil)
+}
+
+func (s *LocalSuite) TestNamedGroupsClientOrder(c *check.C) {
+	srv := startFakeServer(c, &fakeConfig{
+		Versions: []uint16{sslscan.PROTOCOL_TLS12},
+		Suites:   []uint16{0xc02f},
+		Group:    24,
+		Cert:     s.cert.Certificate[0],
+	})
+
+	defer srv.Close()
+
+	details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS12)}}
+
+	c.Assert(newTestScanner(srv).ProbeNamedGroups(srv.Target(), details), check.IsNil)
+	c.Assert(details.NamedGroups, check.NotNil)
+	c.Assert(details.NamedGroups.Preference, check.Equals, false)
+	c.Assert(details.NamedGroups.List, check.DeepEquals, []sslscan.NamedGroup{
+		{ID: 24, Name: "secp384r1", Bits: 384, NamedGroupType: GROUP_TYPE_EC},
+	})
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getGroupNames returns names of given groups
+func getGroupNames(groups []sslscan.NamedGroup) []string {
+	var result []string
+
+	for _, g := range groups {
+		result = append(result, g.Name)
+	}
+
+	return result
+}
@@ -0,0 +1,564 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	RECORD_CHANGE_CIPHER_SPEC = 20
+	RECORD_ALERT              = 21
+	RECORD_HANDSHAKE          = 22
+	RECORD_APPLICATION_DATA   = 23
+	RECORD_HEARTBEAT          = 24
+)
+
+const (
+	HANDSHAKE_CLIENT_HELLO        = 1
+	HANDSHAKE_SERVER_HELLO        = 2
+	HANDSHAKE_CERTIFICATE         = 11
+	HANDSHAKE_SERVER_KEY_EXCHANGE = 12
+	HANDSHAKE_SERVER_HELLO_DONE   = 14
+	HANDSHAKE_CERTIFICATE_STATUS  = 22
+)
+
+const (
+	EXT_SERVER_NAME            = 0
+	EXT_STATUS_REQUEST         = 5
+	EXT_SUPPORTED_GROUPS       = 10
+	EXT_EC_POINT_FORMATS       = 11
+	EXT_SIGNATURE_ALGORITHMS   = 13
+	EXT_HEARTBEAT              = 15
+	EXT_ALPN                   = 16
+	EXT_EXTENDED_MASTER_SECRET = 23
+	EXT_SESSION_TICKET         = 35
+	EXT_SUPPORTED_VERSIONS     = 43
+	EXT_KEY_SHARE              = 51
+	EXT_NPN                    = 13172
+	EXT_RENEGOTIATION_INFO     = 0xff01
+)
+
+// MAX_RECORD_SIZE is maximum size of TLS record payload
+const MAX_RECORD_SIZE = 16384 + 2048
+
+// MAX_HANDSHAKE_MESSAGES is maximum number of handshake messages read from server
+const MAX_HANDSHAKE_MESSAGES = 16
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// clientHello contains ClientHello message parameters
+type clientHello struct {
+	Version    uint16   // protocol version
+	ServerName string   // server name for SNI extension
+	Suites     []uint16 // offered cipher suites
+	Groups     []uint16 // offered named groups
+	SigAlgs    []uint16 // offered signature algorithms
+	ALPN       []string // offered ALPN protocols
+	SessionID  []byte   // session ID for resumption (random if empty)
+	Versions   []uint16 // versions for supported_versions extension (Version if empty)
+
+	Extensions []extension // additional extensions
+}
+
+// extension is raw TLS extension
+type extension struct {
+	Type uint16
+	Data []byte
+}
+
+// serverHello contains info from server handshake messages
+type serverHello struct {
+	Version   uint16 // negotiated protocol version
+	Suite     uint16 // negotiated cipher suite
+	Group     uint16 // negotiated named group (if known)
+	SessionID []byte
+	IsHRR     bool // true if server sent HelloRetryRequest
+
+	Extensions        map[uint16][]byte
+	Certificates      [][]byte // DER-encoded certificates (TLS 1.2 and older)
+	ServerKeyExchange []byte   // raw ServerKeyExchange message (TLS 1.2 and older)
+}
+
+// alertError is error for TLS alert received from server
+type alertError struct {
+	Level       uint8
+	Description uint8
+}
+
+// recordReader reads handshake messages from TLS records
+type recordReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// hrrRandom is special ServerHello.random value used for HelloRetryRequest
+var hrrRandom = []byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11, 0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E, 0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+// defaultGroups is list of named groups offered by default
+var defaultGroups = []uint16{29, 23, 24, 25, 256, 257}
+
+// defaultSigAlgs is list of signature algorithms offered by default
+var defaultSigAlgs = []uint16{
+	0x0403, 0x0503, 0x0603, 0x0804, 0x0805, 0x0806,
+	0x0401, 0x0501, 0x0601, 0x0203, 0x0201,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// errUnexpectedMessage is returned if server sent unexpected message
+var errUnexpectedMessage = errors.New("Unexpected handshake message")
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newClientHello creates ClientHello with default parameters for given protocol
+func newClientHello(version int, serverName string) *clientHello {
+	return &clientHello{
+		Version:    uint16(version),
+		ServerName: serverName,
+		Suites:     getSuiteIDs(version),
+		Groups:     defaultGroups,
+		SigAlgs:    defaultSigAlgs,
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Marshal encodes ClientHello as TLS record
+func (h *clientHello) Marshal() []byte {
+	legacyVersion := h.Version
+
+	if legacyVersion > sslscan.PROTOCOL_TLS12 {
+		legacyVersion = sslscan.PROTOCOL_TLS12
+	}
+
+	body := &bytes.Buffer{}
+
+	writeUint16(body, legacyVersion)
+	body.Write(randomBytes(32))
+
+	// Session ID is required for TLS 1.3 middlebox compatibility mode
+	sessionID := h.SessionID
+
+	if len(sessionID) == 0 {
+		sessionID = randomBytes(32)
+	}
+
+	body.WriteByte(byte(len(sessionID)))
+	body.Write(sessionID)
+
+	writeUint16(body, uint16(len(h.Suites)*2))
+
+	for _, suite := range h.Suites {
+		writeUint16(body, suite)
+	}
+
+	// Only null compression
+	body.Write([]byte{1, 0})
+
+	if h.Version > sslscan.PROTOCOL_SSL3 {
+		exts := h.marshalExtensions()
+		writeUint16(body, uint16(len(exts)))
+		body.Write(exts)
+	}
+
+	recordVersion := uint16(sslscan.PROTOCOL_TLS10)
+
+	if h.Version == sslscan.PROTOCOL_SSL3 {
+		recordVersion = sslscan.PROTOCOL_SSL3
+	}
+
+	return marshalRecord(RECORD_HANDSHAKE, recordVersion, marshalHandshake(HANDSHAKE_CLIENT_HELLO, body.Bytes()))
+}
+
+// marshalExtensions encodes ClientHello extensions
+func (h *clientHello) marshalExtensions() []byte {
+	buf := &bytes.Buffer{}
+
+	if h.ServerName != "" && net.ParseIP(h.ServerName) == nil {
+		data := &bytes.Buffer{}
+		writeUint16(data, uint16(len(h.ServerName)+3))
+		data.WriteByte(0) // host_name
+		writeUint16(data, uint16(len(h.ServerName)))
+		data.WriteString(h.ServerName)
+		writeExtension(buf, EXT_SERVER_NAME, data.Bytes())
+	}
+
+	if len(h.Groups) != 0 {
+		writeExtension(buf, EXT_SUPPORTED_GROUPS, marshalUint16List(h.Groups))
+		writeExtension(buf, EXT_EC_POINT_FORMATS, []byte{1, 0})
+	}
+
+	if len(h.SigAlgs) != 0 && h.Version >= sslscan.PROTOCOL_TLS12 {
+		writeExtension(buf, EXT_SIGNATURE_ALGORITHMS, marshalUint16List(h.SigAlgs))
+	}
+
+	if len(h.ALPN) != 0 {
+		data := &bytes.Buffer{}
+
+		for _, proto := range h.ALPN {
+			data.WriteByte(byte(len(proto)))
+			data.WriteString(proto)
+		}
+
+		list := &bytes.Buffer{}
+		writeUint16(list, uint16(data.Len()))
+		list.Write(data.Bytes())
+		writeExtension(buf, EXT_ALPN, list.Bytes())
+	}
+
+	if h.Version == sslscan.PROTOCOL_TLS13 {
+		versions := h.Versions
+
+		if len(versions) == 0 {
+			versions = []uint16{h.Version}
+		}
+
+		data := &bytes.Buffer{}
+		data.WriteByte(byte(len(versions) * 2))
+
+		for _, v := range versions {
+			writeUint16(data, v)
+		}
+
+		writeExtension(buf, EXT_SUPPORTED_VERSIONS, data.Bytes())
+		// Empty key share forces server to send HelloRetryRequest with
+		// selected group, so we don't have to generate real key shares
+		writeExtension(buf, EXT_KEY_SHARE, []byte{0, 0})
+	} else {
+		writeExtension(buf, EXT_RENEGOTIATION_INFO, []byte{0})
+	}
+
+	for _, ext := range h.Extensions {
+		writeExtension(buf, ext.Type, ext.Data)
+	}
+
+	return buf.Bytes()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Error returns error message
+func (e *alertError) Error() string {
+	return fmt.Sprintf("Server returned alert %d", e.Description)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// readServerHello reads server handshake messages until the end of server flight
+func readServerHello(r io.Reader) (*serverHello, error) {
+	rr := &recordReader{r: r}
+	typ, body, err := rr.ReadMessage()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != HANDSHAKE_SERVER_HELLO {
+		return nil, errUnexpectedMessage
+	}
+
+	hello, err := parseServerHello(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Rest of TLS 1.3 handshake is encrypted
+	if hello.IsHRR || hello.Version == sslscan.PROTOCOL_TLS13 {
+		return hello, nil
+	}
+
+	for i := 0; i < MAX_HANDSHAKE_MESSAGES; i++ {
+		typ, body, err = rr.ReadMessage()
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case HANDSHAKE_CERTIFICATE:
+			hello.Certificates, err = parseCertificates(body)
+
+			if err != nil {
+				return nil, err
+			}
+
+		case HANDSHAKE_SERVER_KEY_EXCHANGE:
+			hello.ServerKeyExchange = body
+
+		case HANDSHAKE_SERVER_HELLO_DONE:
+			return hello, nil
+		}
+	}
+
+	return nil, errUnexpectedMessage
+}
+
+// parseServerHello parses ServerHello message body
+func parseServerHello(data []byte) (*serverHello, error) {
+	hello := &serverHello{Extensions: make(map[uint16][]byte)}
+	p := &parser{data: data}
+
+	hello.Version = p.Uint16()
+	random := p.Bytes(32)
+	hello.SessionID = p.Bytes(int(p.Uint8()))
+	hello.Suite = p.Uint16()
+	p.Uint8() // compression method
+
+	if p.Len() != 0 {
+		exts := &parser{data: p.Bytes(int(p.Uint16()))}
+
+		for exts.Len() != 0 && !exts.failed {
+			typ := exts.Uint16()
+			hello.Extensions[typ] = exts.Bytes(int(exts.Uint16()))
+		}
+
+		if exts.failed {
+			p.failed = true
+		}
+	}
+
+	if p.failed {
+		return nil, fmt.Errorf("Can't parse ServerHello message")
+	}
+
+	hello.IsHRR = bytes.Equal(random, hrrRandom)
+
+	if v := hello.Extensions[EXT_SUPPORTED_VERSIONS]; len(v) == 2 {
+		hello.Version = binary.BigEndian.Uint16(v)
+	}
+
+	if ks := hello.Extensions[EXT_KEY_SHARE]; len(ks) >= 2 {
+		hello.Group = binary.BigEndian.Uint16(ks)
+	}
+
+	return hello, nil
+}
+
+// parseCertificates parses Certificate message body
+func parseCertificates(data []byte) ([][]byte, error) {
+	var result [][]byte
+
+	p := &parser{data: data}
+	list := &parser{data: p.Bytes(p.Uint24())}
+
+	for list.Len() != 0 && !list.failed {
+		result = append(result, list.Bytes(list.Uint24()))
+	}
+
+	if p.failed || list.failed {
+		return nil, fmt.Errorf("Can't parse Certificate message")
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ReadMessage reads next handshake message
+func (rr *recordReader) ReadMessage() (uint8, []byte, error) {
+	for len(rr.buf) < 4 || len(rr.buf) < 4+int(getUint24(rr.buf[1:])) {
+		err := rr.readRecord()
+
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	size := 4 + getUint24(rr.buf[1:])
+	typ, body := rr.buf[0], rr.buf[4:size]
+
+	rr.buf = rr.buf[size:]
+
+	return typ, body, nil
+}
+
+// readRecord reads next TLS record and appends its handshake data to buffer
+func (rr *recordReader) readRecord() error {
+	typ, data, err := readRecord(rr.r)
+
+	if err != nil {
+		return err
+	}
+
+	switch typ {
+	case RECORD_HANDSHAKE:
+		rr.buf = append(rr.buf, data...)
+	case RECORD_ALERT:
+		if len(data) < 2 {
+			return fmt.Errorf("Malformed alert record")
+		}
+
+		return &alertError{Level: data[0], Description: data[1]}
+	case RECORD_CHANGE_CIPHER_SPEC:
+		// ignore
+	default:
+		return fmt.Errorf("Unexpected record type %d", typ)
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// readRecord reads TLS record
+func readRecord(r io.Reader) (uint8, []byte, error) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(r, header)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	size := int(binary.BigEndian.Uint16(header[3:]))
+
+	if size > MAX_RECORD_SIZE {
+		return 0, nil, fmt.Errorf("Record is too big (%d bytes)", size)
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], data, nil
+}
+
+// marshalRecord encodes TLS record
+func marshalRecord(typ uint8, version uint16, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(typ)
+	writeUint16(buf, version)
+	writeUint16(buf, uint16(len(data)))
+	buf.Write(data)
+
+	return buf.Bytes()
+}
+
+// marshalHandshake encodes handshake message
+func marshalHandshake(typ uint8, body []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(typ)
+	buf.Write([]byte{byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))})
+	buf.Write(body)
+
+	return buf.Bytes()
+}
+
+// marshalUint16List encodes list of uint16 values with length prefix
+func marshalUint16List(list []uint16) []byte {
+	buf := &bytes.Buffer{}
+	writeUint16(buf, uint16(len(list)*2))
+
+	for _, v := range list {
+		writeUint16(buf, v)
+	}
+
+	return buf.Bytes()
+}
+
+// writeExtension writes extension with given type and data
+func writeExtension(buf *bytes.Buffer, typ uint16, data []byte) {
+	writeUint16(buf, typ)
+	writeUint16(buf, uint16(len(data)))
+	buf.Write(data)
+}
+
+// writeUint16 writes big-endian uint16 value
+func writeUint16(buf *bytes.Buffer, v uint16) {
+	buf.Write([]byte{byte(v >> 8), byte(v)})
+}
+
+// getUint24 returns big-endian uint24 value
+func getUint24(data []byte) int {
+	return int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+}
+
+// randomBytes returns slice with random data
+func randomBytes(size int) []byte {
+	data := make([]byte, size)
+	rand.Read(data)
+	return data
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parser is simple parser for TLS structures
+type parser struct {
+	data   []byte
+	failed bool
+}
+
+// Len returns size of unread data
+func (p *parser) Len() int {
+	return len(p.data)
+}
+
+// Bytes reads given number of bytes
+func (p *parser) Bytes(size int) []byte {
+	if p.failed || size > len(p.data) {
+		p.failed = true
+		return nil
+	}
+
+	result := p.data[:size]
+	p.data = p.data[size:]
+
+	return result
+}
+
+// Uint8 reads uint8 value
+func (p *parser) Uint8() uint8 {
+	data := p.Bytes(1)
+
+	if data == nil {
+		return 0
+	}
+
+	return data[0]
+}
+
+// Uint16 reads big-endian uint16 value
+func (p *parser) Uint16() uint16 {
+	data := p.Bytes(2)
+
+	if data == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint16(data)
+}
+
+// Uint24 reads big-endian uint24 value
+func (p *parser) Uint24() int {
+	data := p.Bytes(3)
+
+	if data == nil {
+		return 0
+	}
+
+	return getUint24(data)
+}
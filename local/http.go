@@ -0,0 +1,432 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	// HSTS_LONG_MAX_AGE is max-age value which SSL Labs considers to be sufficiently large
+	HSTS_LONG_MAX_AGE = 15552000
+
+	// MAX_REDIRECTS is max number of followed redirects
+	MAX_REDIRECTS = 5
+
+	// MAX_HEADERS is max number of response headers
+	MAX_HEADERS = 128
+)
+
+// USER_AGENT is user agent used for HTTP requests
+const USER_AGENT = "SSLScan/" + sslscan.VERSION
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeHTTP sends HTTP requests over TLS connection, follows redirects on the
+// same host and parses HSTS and HPKP policies from final response
+func (s *Scanner) ProbeHTTP(t *Target, d *sslscan.EndpointDetails) error {
+	d.HTTPTransactions = nil
+	d.HTTPStatusCode = 0
+	d.HTTPForwarding = ""
+
+	// Endpoints with STARTTLS don't serve HTTP
+	if s.StartTLS != "" {
+		return nil
+	}
+
+	requestURL := getBaseURL(t)
+
+	var final *sslscan.HTTPTransaction
+
+	for i := 0; i <= MAX_REDIRECTS; i++ {
+		tx, err := s.httpRequest(t, requestURL)
+
+		if err != nil {
+			if isDialError(err) {
+				return err
+			}
+
+			d.HSTSPolicy = &sslscan.HSTSPolicy{
+				LongMaxAge: HSTS_LONG_MAX_AGE,
+				Status:     sslscan.HSTS_STATUS_ERROR,
+				Error:      err.Error(),
+			}
+			d.HPKPPolicy = &sslscan.HPKPPolicy{Status: sslscan.HPKP_STATUS_ERROR, Error: err.Error()}
+			d.HPKPRoPolicy = &sslscan.HPKPPolicy{Status: sslscan.HPKP_STATUS_ERROR, Error: err.Error()}
+
+			return nil
+		}
+
+		d.HTTPTransactions = append(d.HTTPTransactions, tx)
+		final = tx
+
+		location := getHeader(tx, "Location")
+
+		if tx.StatusCode < 300 || tx.StatusCode >= 400 || location == "" {
+			break
+		}
+
+		next, err := resolveLocation(requestURL, location)
+
+		if err != nil {
+			break
+		}
+
+		if !isSameOrigin(t, next) {
+			d.HTTPForwarding = next.String()
+			break
+		}
+
+		requestURL = next
+	}
+
+	d.HTTPStatusCode = final.StatusCode
+	d.ServerSignature = getHeader(final, "Server")
+	d.HSTSPolicy = parseHSTS(getHeader(final, "Strict-Transport-Security"))
+	d.HPKPPolicy = parseHPKP(getHeader(final, "Public-Key-Pins"), t.Certs, false)
+	d.HPKPRoPolicy = parseHPKP(getHeader(final, "Public-Key-Pins-Report-Only"), t.Certs, true)
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// httpRequest sends GET request to given URL and reads response line and headers
+func (s *Scanner) httpRequest(t *Target, requestURL *url.URL) (*sslscan.HTTPTransaction, error) {
+	conn, err := s.tlsDial(t, &tls.Config{
+		ServerName: getServerName(t),
+		NextProtos: []string{"http/1.1"},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	tx := &sslscan.HTTPTransaction{
+		RequestURL:  requestURL.String(),
+		RequestLine: "GET " + requestURL.RequestURI() + " HTTP/1.1",
+		RequestHeaders: []string{
+			"Host: " + requestURL.Host,
+			"User-Agent: " + USER_AGENT,
+			"Accept: */*",
+			"Connection: Close",
+		},
+	}
+
+	request := tx.RequestLine + "\r\n" + strings.Join(tx.RequestHeaders, "\r\n") + "\r\n\r\n"
+
+	_, err = conn.Write([]byte(request))
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	tx.ResponseLine, err = reader.ReadLine()
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't read HTTP response: %v", err)
+	}
+
+	tx.StatusCode, err = parseStatusCode(tx.ResponseLine)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < MAX_HEADERS; i++ {
+		line, err := reader.ReadLine()
+
+		if err != nil || line == "" {
+			break
+		}
+
+		tx.ResponseHeadersRaw = append(tx.ResponseHeadersRaw, line)
+
+		index := strings.Index(line, ":")
+
+		if index > 0 {
+			tx.ResponseHeaders = append(tx.ResponseHeaders, sslscan.HTTPHeader{
+				Name:  strings.TrimSpace(line[:index]),
+				Value: strings.TrimSpace(line[index+1:]),
+			})
+		}
+	}
+
+	return tx, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseHSTS parses Strict-Transport-Security header value
+func parseHSTS(header string) *sslscan.HSTSPolicy {
+	policy := &sslscan.HSTSPolicy{
+		LongMaxAge: HSTS_LONG_MAX_AGE,
+		Header:     header,
+		Status:     sslscan.HSTS_STATUS_ABSENT,
+	}
+
+	if header == "" {
+		return policy
+	}
+
+	directives, err := parseDirectives(header)
+
+	if err != nil {
+		policy.Status, policy.Error = sslscan.HSTS_STATUS_INVALID, err.Error()
+		return policy
+	}
+
+	policy.Directives = make(map[string]string)
+
+	for _, d := range directives {
+		if _, ok := policy.Directives[d.Name]; ok {
+			policy.Status = sslscan.HSTS_STATUS_INVALID
+			policy.Error = fmt.Sprintf("Duplicate directive %s", d.Name)
+			return policy
+		}
+
+		policy.Directives[d.Name] = d.Value
+
+		switch d.Name {
+		case "includesubdomains":
+			policy.IncludeSubDomains = true
+		case "preload":
+			policy.Preload = true
+		}
+	}
+
+	maxAge, err := parseMaxAge(policy.Directives)
+
+	switch {
+	case err != nil:
+		policy.Status, policy.Error = sslscan.HSTS_STATUS_INVALID, err.Error()
+	case maxAge == 0:
+		policy.Status = sslscan.HSTS_STATUS_DISABLED
+	default:
+		policy.Status, policy.MaxAge = sslscan.HSTS_STATUS_PRESENT, maxAge
+	}
+
+	return policy
+}
+
+// parseHPKP parses Public-Key-Pins or Public-Key-Pins-Report-Only header
+// value and matches pins with given certificates
+func parseHPKP(header string, certs []*sslscan.Cert, reportOnly bool) *sslscan.HPKPPolicy {
+	policy := &sslscan.HPKPPolicy{
+		Header:      header,
+		Status:      sslscan.HPKP_STATUS_ABSENT,
+		Pins:        []sslscan.Pin{},
+		MatchedPins: []sslscan.Pin{},
+		Directives:  []sslscan.Directive{},
+	}
+
+	if header == "" {
+		return policy
+	}
+
+	directives, err := parseDirectives(header)
+
+	if err != nil {
+		policy.Status, policy.Error = sslscan.HPKP_STATUS_INVALID, err.Error()
+		return policy
+	}
+
+	values := make(map[string]string)
+
+	for _, d := range directives {
+		policy.Directives = append(policy.Directives, d)
+
+		if !strings.HasPrefix(d.Name, "pin-") {
+			values[d.Name] = d.Value
+			continue
+		}
+
+		pin := sslscan.Pin{HashFunction: strings.TrimPrefix(d.Name, "pin-"), Value: d.Value}
+		policy.Pins = append(policy.Pins, pin)
+
+		if pin.HashFunction == "sha256" && isPinMatched(pin.Value, certs) {
+			policy.MatchedPins = append(policy.MatchedPins, pin)
+		}
+	}
+
+	_, policy.IncludeSubDomains = values["includesubdomains"]
+	policy.ReportURI = values["report-uri"]
+
+	maxAge, err := parseMaxAge(values)
+
+	switch {
+	case err != nil && !reportOnly:
+		policy.Status, policy.Error = sslscan.HPKP_STATUS_INVALID, err.Error()
+	case err == nil && maxAge == 0:
+		policy.Status = sslscan.HPKP_STATUS_DISABLED
+	case len(policy.Pins) == 0:
+		policy.Status, policy.Error = sslscan.HPKP_STATUS_INVALID, "No pins"
+	case len(policy.MatchedPins) == 0:
+		policy.Status, policy.Error = sslscan.HPKP_STATUS_INVALID, "No pins match current configuration"
+	case len(policy.MatchedPins) == len(policy.Pins):
+		policy.Status, policy.Error = sslscan.HPKP_STATUS_INCOMPLETE, "No backup pins"
+	default:
+		policy.Status = sslscan.HPKP_STATUS_VALID
+	}
+
+	policy.MaxAge = maxAge
+
+	return policy
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseDirectives parses policy directives separated by semicolon
+func parseDirectives(header string) ([]sslscan.Directive, error) {
+	var result []sslscan.Directive
+
+	for _, part := range strings.Split(header, ";") {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		var name, value string
+
+		if index := strings.Index(part, "="); index != -1 {
+			name, value = part[:index], strings.TrimSpace(part[index+1:])
+		} else {
+			name = part
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if name == "" {
+			return nil, fmt.Errorf("Directive without name")
+		}
+
+		if strings.HasPrefix(value, "\"") {
+			unquoted, err := strconv.Unquote(value)
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value of directive %s", name)
+			}
+
+			value = unquoted
+		}
+
+		result = append(result, sslscan.Directive{Name: name, Value: value})
+	}
+
+	return result, nil
+}
+
+// parseMaxAge returns value of max-age directive
+func parseMaxAge(directives map[string]string) (int64, error) {
+	value, ok := directives["max-age"]
+
+	if !ok {
+		return 0, fmt.Errorf("Directive max-age is missing")
+	}
+
+	maxAge, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil || maxAge < 0 {
+		return 0, fmt.Errorf("Invalid max-age value \"%s\"", value)
+	}
+
+	return maxAge, nil
+}
+
+// parseStatusCode parses status code from HTTP response line
+func parseStatusCode(line string) (int, error) {
+	fields := strings.Fields(line)
+
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return 0, fmt.Errorf("Invalid HTTP response line \"%s\"", line)
+	}
+
+	code, err := strconv.Atoi(fields[1])
+
+	if err != nil {
+		return 0, fmt.Errorf("Invalid HTTP status code \"%s\"", fields[1])
+	}
+
+	return code, nil
+}
+
+// isPinMatched returns true if any certificate has given SPKI pin
+func isPinMatched(pin string, certs []*sslscan.Cert) bool {
+	for _, cert := range certs {
+		if cert.PINSHA256 == pin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getHeader returns value of first response header with given name
+func getHeader(tx *sslscan.HTTPTransaction, name string) string {
+	for _, h := range tx.ResponseHeaders {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+
+	return ""
+}
+
+// getBaseURL returns URL of site root on target
+func getBaseURL(t *Target) *url.URL {
+	host := t.Host
+
+	if t.Port != DEFAULT_PORT {
+		host = net.JoinHostPort(host, strconv.Itoa(t.Port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	return &url.URL{Scheme: "https", Host: host, Path: "/"}
+}
+
+// resolveLocation resolves redirect location relative to request URL
+func resolveLocation(requestURL *url.URL, location string) (*url.URL, error) {
+	next, err := url.Parse(location)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return requestURL.ResolveReference(next), nil
+}
+
+// isSameOrigin returns true if given URL points to target over HTTPS
+func isSameOrigin(t *Target, u *url.URL) bool {
+	if u.Scheme != "https" || !strings.EqualFold(u.Hostname(), t.Host) {
+		return false
+	}
+
+	port := u.Port()
+
+	if port == "" {
+		return t.Port == DEFAULT_PORT
+	}
+
+	return port == strconv.Itoa(t.Port)
+}
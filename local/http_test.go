@@ -0,0 +1,197 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const TEST_BACKUP_PIN = "E9CZ9INDbd+2eRQozYqqbQ2yXLVKB9+xcprMF+44U1g="
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestHTTP(c *check.C) {
+	cert, _ := x509.ParseCertificate(s.cert.Certificate[0])
+	pin := newCert(cert).PINSHA256
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/home?lang=en", http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "TestServer")
+		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
+		w.Header().Set("Public-Key-Pins", `pin-sha256="`+pin+`"; pin-sha256="`+TEST_BACKUP_PIN+`"; max-age=600; report-uri="https://example.com/hpkp"`)
+		w.Header().Set("Public-Key-Pins-Report-Only", `pin-sha256="`+pin+`"`)
+	})
+
+	srv := startHTTPServer(c, s.cert, mux)
+	defer srv.Close()
+
+	target := srv.Target()
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+
+	c.Assert(scanner.ProbeChain(target, details), check.IsNil)
+	c.Assert(scanner.ProbeHTTP(target, details), check.IsNil)
+
+	c.Assert(details.HTTPTransactions, check.HasLen, 2)
+
+	tx := details.HTTPTransactions[0]
+
+	c.Assert(tx.RequestURL, check.Equals, getBaseURL(target).String())
+	c.Assert(tx.RequestLine, check.Equals, "GET / HTTP/1.1")
+	c.Assert(tx.RequestHeaders[0], check.Equals, "Host: "+getBaseURL(target).Host)
+	c.Assert(tx.StatusCode, check.Equals, 302)
+	c.Assert(tx.ResponseLine, check.Equals, "HTTP/1.1 302 Found")
+	c.Assert(getHeader(tx, "location"), check.Equals, "/home?lang=en")
+
+	tx = details.HTTPTransactions[1]
+
+	c.Assert(tx.RequestLine, check.Equals, "GET /home?lang=en HTTP/1.1")
+	c.Assert(tx.StatusCode, check.Equals, 200)
+	c.Assert(tx.ResponseHeadersRaw, check.HasLen, len(tx.ResponseHeaders))
+
+	c.Assert(details.HTTPStatusCode, check.Equals, 200)
+	c.Assert(details.HTTPForwarding, check.Equals, "")
+	c.Assert(details.ServerSignature, check.Equals, "TestServer")
+
+	hsts := details.HSTSPolicy
+
+	c.Assert(hsts.Status, check.Equals, sslscan.HSTS_STATUS_PRESENT)
+	c.Assert(hsts.LongMaxAge, check.Equals, HSTS_LONG_MAX_AGE)
+	c.Assert(hsts.MaxAge, check.Equals, int64(31536000))
+	c.Assert(hsts.IncludeSubDomains, check.Equals, true)
+	c.Assert(hsts.Preload, check.Equals, true)
+	c.Assert(hsts.Directives, check.DeepEquals, map[string]string{"max-age": "31536000", "includesubdomains": "", "preload": ""})
+
+	hpkp := details.HPKPPolicy
+
+	c.Assert(hpkp.Status, check.Equals, sslscan.HPKP_STATUS_VALID)
+	c.Assert(hpkp.MaxAge, check.Equals, int64(600))
+	c.Assert(hpkp.ReportURI, check.Equals, "https://example.com/hpkp")
+	c.Assert(hpkp.Pins, check.HasLen, 2)
+	c.Assert(hpkp.MatchedPins, check.DeepEquals, []sslscan.Pin{{HashFunction: "sha256", Value: pin}})
+	c.Assert(hpkp.Directives, check.HasLen, 4)
+
+	c.Assert(details.HPKPRoPolicy.Status, check.Equals, sslscan.HPKP_STATUS_INCOMPLETE)
+}
+
+func (s *LocalSuite) TestHTTPForwarding(c *check.C) {
+	srv := startHTTPServer(c, s.cert, http.RedirectHandler("https://example.com/", http.StatusMovedPermanently))
+	defer srv.Close()
+
+	details := &sslscan.EndpointDetails{}
+
+	c.Assert(newTestScanner(srv).ProbeHTTP(srv.Target(), details), check.IsNil)
+	c.Assert(details.HTTPTransactions, check.HasLen, 1)
+	c.Assert(details.HTTPStatusCode, check.Equals, 301)
+	c.Assert(details.HTTPForwarding, check.Equals, "https://example.com/")
+	c.Assert(details.HSTSPolicy.Status, check.Equals, sslscan.HSTS_STATUS_ABSENT)
+	c.Assert(details.HPKPPolicy.Status, check.Equals, sslscan.HPKP_STATUS_ABSENT)
+}
+
+func (s *LocalSuite) TestHTTPErrors(c *check.C) {
+	srv := startServer(c, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+
+	c.Assert(scanner.ProbeHTTP(srv.Target(), details), check.IsNil)
+	c.Assert(details.HTTPTransactions, check.HasLen, 0)
+	c.Assert(details.HSTSPolicy.Status, check.Equals, sslscan.HSTS_STATUS_ERROR)
+	c.Assert(details.HSTSPolicy.Error, check.Not(check.Equals), "")
+	c.Assert(details.HPKPPolicy.Status, check.Equals, sslscan.HPKP_STATUS_ERROR)
+
+	srv.Close()
+
+	c.Assert(scanner.ProbeHTTP(srv.Target(), details), check.NotNil)
+}
+
+func (s *LocalSuite) TestHSTSParsing(c *check.C) {
+	cases := []struct {
+		header string
+		status string
+		maxAge int64
+	}{
+		{"", sslscan.HSTS_STATUS_ABSENT, 0},
+		{"max-age=\"300\"", sslscan.HSTS_STATUS_PRESENT, 300},
+		{"max-age=0; includeSubDomains", sslscan.HSTS_STATUS_DISABLED, 0},
+		{"includeSubDomains", sslscan.HSTS_STATUS_INVALID, 0},
+		{"max-age=abc", sslscan.HSTS_STATUS_INVALID, 0},
+		{"max-age=100; max-age=200", sslscan.HSTS_STATUS_INVALID, 0},
+		{"max-age=\"100", sslscan.HSTS_STATUS_INVALID, 0},
+		{"=100", sslscan.HSTS_STATUS_INVALID, 0},
+	}
+
+	for _, tc := range cases {
+		policy := parseHSTS(tc.header)
+
+		c.Assert(policy.Status, check.Equals, tc.status, check.Commentf("Header: %s", tc.header))
+		c.Assert(policy.MaxAge, check.Equals, tc.maxAge)
+	}
+}
+
+func (s *LocalSuite) TestHPKPParsing(c *check.C) {
+	certs := []*sslscan.Cert{{PINSHA256: "current"}}
+
+	cases := []struct {
+		header     string
+		reportOnly bool
+		status     string
+	}{
+		{"", false, sslscan.HPKP_STATUS_ABSENT},
+		{`pin-sha256="current"; pin-sha256="backup"; max-age=60`, false, sslscan.HPKP_STATUS_VALID},
+		{`pin-sha256="current"; pin-sha256="backup"`, false, sslscan.HPKP_STATUS_INVALID},
+		{`pin-sha256="current"; pin-sha256="backup"`, true, sslscan.HPKP_STATUS_VALID},
+		{`pin-sha256="current"; max-age=0`, false, sslscan.HPKP_STATUS_DISABLED},
+		{`max-age=60`, false, sslscan.HPKP_STATUS_INVALID},
+		{`pin-sha256="backup"; max-age=60`, false, sslscan.HPKP_STATUS_INVALID},
+		{`pin-sha256="current"; max-age=60`, false, sslscan.HPKP_STATUS_INCOMPLETE},
+		{`pin-sha256="current; max-age=60`, false, sslscan.HPKP_STATUS_INVALID},
+	}
+
+	for _, tc := range cases {
+		policy := parseHPKP(tc.header, certs, tc.reportOnly)
+
+		c.Assert(policy.Status, check.Equals, tc.status, check.Commentf("Header: %s", tc.header))
+	}
+
+	_, err := parseStatusCode("HTTP/1.1 ABC")
+	c.Assert(err, check.NotNil)
+
+	_, err = parseStatusCode("SSH-2.0-OpenSSH_8.0")
+	c.Assert(err, check.NotNil)
+
+	c.Assert(getBaseURL(&Target{Host: "::1", Port: 443}).String(), check.Equals, "https://[::1]/")
+	c.Assert(getBaseURL(&Target{Host: "example.com", Port: 8443}).String(), check.Equals, "https://example.com:8443/")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// startHTTPServer starts HTTPS server with given handler
+func startHTTPServer(c *check.C, cert tls.Certificate, handler http.Handler) *testServer {
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+
+	return &testServer{
+		Port:     srv.Listener.Addr().(*net.TCPAddr).Port,
+		listener: srv.Listener,
+	}
+}
@@ -0,0 +1,391 @@
+// Package local provides scanner which assesses TLS servers directly, without
+// SSL Labs API, so it can be used for internal hosts
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SCANNER_LOCAL is name of local backend
+const SCANNER_LOCAL = "local"
+
+// ENGINE_VERSION is version of local assessment engine
+const ENGINE_VERSION = "local-" + sslscan.VERSION
+
+const (
+	DEFAULT_PORT    = 443
+	DEFAULT_TIMEOUT = 10 * time.Second
+)
+
+// STATUS_PROBES_FAILED is endpoint status details code for partial assessment
+const STATUS_PROBES_FAILED = "PROBES_FAILED"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Scanner is scanner which connects to servers directly
+type Scanner struct {
+	Port       int              // server port (443 by default)
+	Timeout    time.Duration    // connection and handshake timeout
+	RootStores []*RootStore     // root stores for trust validation (system by default)
+	Clients    []*ClientProfile // simulated clients (DefaultClients by default)
+	StartTLS   string           // application protocol for STARTTLS (none by default)
+}
+
+// Target contains info about assessed endpoint
+type Target struct {
+	Host string // host name (used for SNI)
+	IP   string // endpoint IP address
+	Port int    // endpoint port
+
+	Certs []*sslscan.Cert // certificates collected during assessment
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new local scanner
+func New() *Scanner {
+	return &Scanner{
+		Port:    DEFAULT_PORT,
+		Timeout: DEFAULT_TIMEOUT,
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Name returns backend name
+func (s *Scanner) Name() string {
+	return SCANNER_LOCAL
+}
+
+// Scan runs assessment of given host. Params related to SSL Labs
+// cache and publishing are ignored.
+func (s *Scanner) Scan(host string, params sslscan.AnalyzeParams) (*sslscan.AnalyzeInfo, error) {
+	if s.StartTLS != "" && !IsStartTLSSupported(s.StartTLS) {
+		return nil, fmt.Errorf("Unsupported STARTTLS protocol %s", s.StartTLS)
+	}
+
+	start := time.Now()
+
+	info := &sslscan.AnalyzeInfo{
+		Host:          host,
+		Port:          s.getPort(),
+		Protocol:      s.getProtocol(),
+		Status:        sslscan.STATUS_READY,
+		StartTime:     toMs(start),
+		EngineVersion: ENGINE_VERSION,
+	}
+
+	ips, err := lookupHost(host)
+
+	if err != nil {
+		info.Status = sslscan.STATUS_ERROR
+		info.StatusMessage = "Unable to resolve domain name"
+		info.TestTime = toMs(time.Now())
+		return info, nil
+	}
+
+	for _, ip := range ips {
+		target := &Target{Host: host, IP: ip, Port: info.Port}
+		info.Endpoints = append(info.Endpoints, s.ScanEndpoint(target))
+
+		for _, cert := range target.Certs {
+			info.Certs = appendCert(info.Certs, cert)
+		}
+	}
+
+	info.TestTime = toMs(time.Now())
+
+	return info, nil
+}
+
+// ScanEndpoint runs all probes for given endpoint. If some probes failed, endpoint
+// contains partial details and list of failed probes in status details message.
+func (s *Scanner) ScanEndpoint(t *Target) *sslscan.EndpointInfo {
+	start := time.Now()
+	details := &sslscan.EndpointDetails{HostStartTime: toMs(start)}
+
+	endpoint := &sslscan.EndpointInfo{
+		IPAdress:      t.IP,
+		StatusMessage: "Ready",
+		Progress:      100,
+		Details:       details,
+	}
+
+	err := s.ProbeProtocols(t, details)
+
+	switch {
+	case err != nil:
+		endpoint.StatusMessage = "Unable to connect to the server"
+	case len(details.Protocols) == 0:
+		endpoint.StatusMessage = "No secure protocols supported"
+	default:
+		failed := s.runProbes(t, details)
+
+		if len(failed) != 0 {
+			endpoint.StatusMessage = "Assessment partially failed"
+			endpoint.StatusDetails = STATUS_PROBES_FAILED
+			endpoint.StatusDetailsMessage = strings.Join(failed, "; ")
+		}
+	}
+
+	endpoint.Duration = int(time.Since(start) / time.Millisecond)
+
+	return endpoint
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// runProbes runs all probes which require supported protocols list. Failed probe
+// doesn't stop assessment, so method returns list of errors of all failed probes.
+func (s *Scanner) runProbes(t *Target, d *sslscan.EndpointDetails) []string {
+	probes := []struct {
+		name  string
+		probe func(t *Target, d *sslscan.EndpointDetails) error
+	}{
+		{"suites", s.ProbeSuites},
+		{"named groups", s.ProbeNamedGroups},
+		{"chain", s.ProbeChain},
+		{"simulations", s.ProbeSimulations},
+		{"http", s.ProbeHTTP},
+		{"sni", s.ProbeSNI},
+		{"alpn", s.ProbeALPN},
+		{"npn", s.ProbeNPN},
+		{"session resumption", s.ProbeSessionResumption},
+		{"session tickets", s.ProbeSessionTickets},
+		{"ocsp stapling", s.ProbeOCSPStapling},
+		{"heartbleed", s.ProbeHeartbleed},
+		{"ccs injection", s.ProbeCCSInjection},
+	}
+
+	var failed []string
+
+	for _, p := range probes {
+		err := p.probe(t, d)
+
+		if err != nil {
+			failed = append(failed, p.name+": "+err.Error())
+		}
+	}
+
+	return failed
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Addr returns endpoint address
+func (t *Target) Addr() string {
+	return net.JoinHostPort(t.IP, strconv.Itoa(t.Port))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// dial opens connection to given target and negotiates TLS with STARTTLS
+// if required
+func (s *Scanner) dial(t *Target) (net.Conn, error) {
+	timeout := s.getTimeout()
+	conn, err := net.DialTimeout("tcp", t.Addr(), timeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if s.StartTLS != "" {
+		err = startTLS(conn, s.StartTLS, t.Host)
+
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// handshake sends ClientHello to target and reads server response
+func (s *Scanner) handshake(t *Target, hello *clientHello) (*serverHello, error) {
+	conn, sh, err := s.startHandshake(t, hello)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn.Close()
+
+	return sh, nil
+}
+
+// startHandshake sends ClientHello to target, reads server flight and returns
+// connection for further messages
+func (s *Scanner) startHandshake(t *Target, hello *clientHello) (net.Conn, *serverHello, error) {
+	conn, err := s.dial(t)
+
+	if err != nil {
+		return nil, nil, &dialError{err}
+	}
+
+	_, err = conn.Write(hello.Marshal())
+
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	sh, err := readServerHello(conn)
+
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, sh, nil
+}
+
+// tlsDial opens TLS connection to given target
+func (s *Scanner) tlsDial(t *Target, config *tls.Config) (*tls.Conn, error) {
+	conn, err := s.dial(t)
+
+	if err != nil {
+		return nil, &dialError{err}
+	}
+
+	return tlsHandshake(conn, config)
+}
+
+// tlsHandshake makes TLS handshake over given connection. Certificates are not
+// verified and all cipher suites supported by crypto/tls are offered.
+func tlsHandshake(conn net.Conn, config *tls.Config) (*tls.Conn, error) {
+	config.InsecureSkipVerify = true
+
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS10
+	}
+
+	if config.CipherSuites == nil {
+		config.CipherSuites = getTLSSuiteIDs()
+	}
+
+	tlsConn := tls.Client(conn, config)
+	err := tlsConn.Handshake()
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// getPort returns server port
+func (s *Scanner) getPort() int {
+	if s.Port <= 0 {
+		return DEFAULT_PORT
+	}
+
+	return s.Port
+}
+
+// getProtocol returns application protocol of assessed endpoints
+func (s *Scanner) getProtocol() string {
+	if s.StartTLS != "" {
+		return s.StartTLS
+	}
+
+	return PROTOCOL_HTTP
+}
+
+// getTimeout returns connection timeout
+func (s *Scanner) getTimeout() time.Duration {
+	if s.Timeout <= 0 {
+		return DEFAULT_TIMEOUT
+	}
+
+	return s.Timeout
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// dialError is error for failed connection to the server
+type dialError struct {
+	err error
+}
+
+// Error returns error message
+func (e *dialError) Error() string {
+	return "Can't connect to server: " + e.err.Error()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// lookupHost returns list of IP addresses for given host
+func lookupHost(host string) ([]string, error) {
+	if isIP(host) {
+		return []string{host}, nil
+	}
+
+	return net.LookupHost(host)
+}
+
+// isIP returns true if given host is IP address
+func isIP(host string) bool {
+	return net.ParseIP(host) != nil
+}
+
+// getServerName returns server name for SNI
+func getServerName(t *Target) string {
+	if isIP(t.Host) {
+		return ""
+	}
+
+	return t.Host
+}
+
+// appendCert appends certificate to list if it isn't already there
+func appendCert(certs []*sslscan.Cert, cert *sslscan.Cert) []*sslscan.Cert {
+	for _, c := range certs {
+		if c.ID == cert.ID {
+			return certs
+		}
+	}
+
+	return append(certs, cert)
+}
+
+// getTLSSuiteIDs returns IDs of all cipher suites supported by crypto/tls
+func getTLSSuiteIDs() []uint16 {
+	var result []uint16
+
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		result = append(result, suite.ID)
+	}
+
+	return result
+}
+
+// isDialError returns true if given error is connection error
+func isDialError(err error) bool {
+	_, ok := err.(*dialError)
+	return ok
+}
+
+// toMs converts time to milliseconds since 1970
+func toMs(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
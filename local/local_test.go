@@ -0,0 +1,501 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type LocalSuite struct {
+	cert tls.Certificate
+}
+
+// testServer is TLS server for tests
+type testServer struct {
+	Port int
+
+	listener net.Listener
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = check.Suite(&LocalSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) SetUpSuite(c *check.C) {
+	s.cert = newTestCert(c, "localhost")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestProtocols(c *check.C) {
+	cases := []struct {
+		min, max uint16
+		expected []int
+	}{
+		{tls.VersionTLS12, tls.VersionTLS13, []int{sslscan.PROTOCOL_TLS12, sslscan.PROTOCOL_TLS13}},
+		{tls.VersionTLS10, tls.VersionTLS11, []int{sslscan.PROTOCOL_TLS10, sslscan.PROTOCOL_TLS11}},
+		{tls.VersionTLS13, tls.VersionTLS13, []int{sslscan.PROTOCOL_TLS13}},
+		{tls.VersionTLS12, tls.VersionTLS12, []int{sslscan.PROTOCOL_TLS12}},
+	}
+
+	for _, tc := range cases {
+		srv := startServer(c, &tls.Config{
+			Certificates: []tls.Certificate{s.cert},
+			MinVersion:   tc.min,
+			MaxVersion:   tc.max,
+		})
+
+		details := &sslscan.EndpointDetails{}
+		err := newTestScanner(srv).ProbeProtocols(srv.Target(), details)
+
+		srv.Close()
+
+		c.Assert(err, check.IsNil)
+		c.Assert(getProtocolIDs(details.Protocols), check.DeepEquals, tc.expected)
+	}
+
+	p := newProtocol(sslscan.PROTOCOL_TLS12)
+
+	c.Assert(p.Name, check.Equals, "TLS")
+	c.Assert(p.Version, check.Equals, "1.2")
+	c.Assert(p.Q, check.IsNil)
+
+	p = newProtocol(sslscan.PROTOCOL_SSL3)
+
+	c.Assert(p.Name, check.Equals, "SSL")
+	c.Assert(p.Version, check.Equals, "3.0")
+	c.Assert(*p.Q, check.Equals, 0)
+}
+
+func (s *LocalSuite) TestScan(c *check.C) {
+	srv := startServer(c, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+	scanner := newTestScanner(srv)
+
+	var _ sslscan.Scanner = scanner
+
+	c.Assert(scanner.Name(), check.Equals, SCANNER_LOCAL)
+
+	info, err := scanner.Scan("127.0.0.1", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Status, check.Equals, sslscan.STATUS_READY)
+	c.Assert(info.Port, check.Equals, srv.Port)
+	c.Assert(info.EngineVersion, check.Equals, ENGINE_VERSION)
+	c.Assert(info.Endpoints, check.HasLen, 1)
+	c.Assert(info.Endpoints[0].IPAdress, check.Equals, "127.0.0.1")
+	c.Assert(info.Endpoints[0].StatusMessage, check.Equals, "Ready")
+	c.Assert(info.Endpoints[0].Details.Protocols, check.Not(check.HasLen), 0)
+	c.Assert(info.Endpoints[0].Details.CertChains, check.HasLen, 1)
+	c.Assert(info.Certs, check.HasLen, 1)
+
+	srv.Close()
+
+	info, err = scanner.Scan("127.0.0.1", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Endpoints[0].StatusMessage, check.Equals, "Unable to connect to the server")
+}
+
+func (s *LocalSuite) TestFailedProbes(c *check.C) {
+	srv := startServer(c, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+	scanner := newTestScanner(srv)
+	details := &sslscan.EndpointDetails{}
+
+	c.Assert(scanner.ProbeProtocols(srv.Target(), details), check.IsNil)
+
+	srv.Close()
+
+	failed := scanner.runProbes(srv.Target(), details)
+
+	c.Assert(failed, check.Not(check.HasLen), 0)
+	c.Assert(failed[0], check.Matches, "suites: Can't connect to server: .*")
+	c.Assert(failed[len(failed)-1], check.Matches, "ccs injection: Can't connect to server: .*")
+	c.Assert(details.Protocols, check.Not(check.HasLen), 0)
+}
+
+func (s *LocalSuite) TestHelloParsing(c *check.C) {
+	_, err := parseServerHello([]byte{3, 3})
+	c.Assert(err, check.ErrorMatches, "Can't parse ServerHello message")
+
+	_, err = parseCertificates([]byte{0, 0, 5, 0, 0, 9})
+	c.Assert(err, check.ErrorMatches, "Can't parse Certificate message")
+
+	hello, err := readServerHello(&byteReader{data: marshalRecord(RECORD_ALERT, 0x0303, []byte{2, 40})})
+	c.Assert(hello, check.IsNil)
+	c.Assert(err, check.ErrorMatches, "Server returned alert 40")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// fakeConfig contains configuration of fake TLS server
+type fakeConfig struct {
+	Versions    []uint16 // supported protocols
+	Suites      []uint16 // supported suites in server preference order
+	Group       uint16   // group used for ECDHE key exchange
+	ClientOrder bool     // select suite using client preference
+	ChaCha20    bool     // select ChaCha20 suite if client prefers it
+	Cert        []byte   // DER-encoded certificate
+	NPN         []string // protocols advertised with NPN
+	Resume      bool     // resume any session offered by client
+	Heartbeat   bool     // negotiate heartbeat extension
+	Heartbleed  bool     // reply to malformed heartbeat requests
+	AcceptCCS   bool     // accept early ChangeCipherSpec (CVE-2014-0224)
+}
+
+// Respond returns server response for given ClientHello
+func (f *fakeConfig) Respond(hello *clientHello) []byte {
+	var version, suite uint16
+
+	for _, v := range f.Versions {
+		if v <= hello.Version && v > version {
+			version = v
+		}
+	}
+
+	switch {
+	case f.ClientOrder:
+		suite = findCommonSuite(hello.Suites, f.Suites)
+	case f.ChaCha20 && len(hello.Suites) != 0 && isChaCha20Suite(getSuiteInfo(hello.Suites[0]).Name):
+		suite = findCommonSuite(hello.Suites[:1], f.Suites)
+	}
+
+	if suite == 0 {
+		suite = findCommonSuite(f.Suites, hello.Suites)
+	}
+
+	if version == 0 || suite == 0 {
+		return marshalRecord(RECORD_ALERT, sslscan.PROTOCOL_TLS10, []byte{2, 40})
+	}
+
+	sh := &bytes.Buffer{}
+	writeUint16(sh, version)
+	sh.Write(randomBytes(32))
+
+	if f.Resume && len(hello.SessionID) != 0 {
+		sh.WriteByte(byte(len(hello.SessionID)))
+		sh.Write(hello.SessionID)
+		writeUint16(sh, suite)
+		sh.WriteByte(0)
+
+		data := marshalRecord(RECORD_HANDSHAKE, version, marshalHandshake(HANDSHAKE_SERVER_HELLO, sh.Bytes()))
+
+		return append(data, marshalRecord(RECORD_CHANGE_CIPHER_SPEC, version, []byte{1})...)
+	}
+
+	sh.WriteByte(0)
+	writeUint16(sh, suite)
+	sh.WriteByte(0)
+
+	exts := &bytes.Buffer{}
+
+	if len(f.NPN) != 0 && hasExtension(hello, EXT_NPN) {
+		npn := &bytes.Buffer{}
+
+		for _, proto := range f.NPN {
+			npn.WriteByte(byte(len(proto)))
+			npn.WriteString(proto)
+		}
+
+		writeExtension(exts, EXT_NPN, npn.Bytes())
+	}
+
+	if f.Heartbeat && hasExtension(hello, EXT_HEARTBEAT) {
+		writeExtension(exts, EXT_HEARTBEAT, []byte{HEARTBEAT_MODE_PEER_ALLOWED})
+	}
+
+	if exts.Len() != 0 {
+		writeUint16(sh, uint16(exts.Len()))
+		sh.Write(exts.Bytes())
+	}
+
+	certs := &bytes.Buffer{}
+	certs.Write([]byte{0, byte((len(f.Cert) + 3) >> 8), byte(len(f.Cert) + 3)})
+	certs.Write([]byte{0, byte(len(f.Cert) >> 8), byte(len(f.Cert))})
+	certs.Write(f.Cert)
+
+	data := marshalHandshake(HANDSHAKE_SERVER_HELLO, sh.Bytes())
+	data = append(data, marshalHandshake(HANDSHAKE_CERTIFICATE, certs.Bytes())...)
+
+	if getKxType(getSuiteInfo(suite).Name) == KX_ECDH {
+		ske := []byte{3, byte(f.Group >> 8), byte(f.Group), 1, 0}
+		data = append(data, marshalHandshake(HANDSHAKE_SERVER_KEY_EXCHANGE, ske)...)
+	}
+
+	data = append(data, marshalHandshake(HANDSHAKE_SERVER_HELLO_DONE, nil)...)
+
+	return marshalRecord(RECORD_HANDSHAKE, version, data)
+}
+
+// RespondRecord returns server response for record sent after ClientHello.
+// Nil response means that server closes connection.
+func (f *fakeConfig) RespondRecord(typ uint8, data []byte) []byte {
+	switch typ {
+	case RECORD_HEARTBEAT:
+		if !f.Heartbleed || len(data) < 3 {
+			return nil
+		}
+
+		// Reply with declared payload size, not with actual one
+		size := int(binary.BigEndian.Uint16(data[1:3]))
+		payload := append([]byte{HEARTBEAT_RESPONSE, data[1], data[2]}, make([]byte, size+16)...)
+
+		return marshalRecord(RECORD_HEARTBEAT, sslscan.PROTOCOL_TLS12, payload)
+
+	case RECORD_CHANGE_CIPHER_SPEC:
+		if f.AcceptCCS {
+			return []byte{}
+		}
+
+		return marshalRecord(RECORD_ALERT, sslscan.PROTOCOL_TLS12, []byte{2, ALERT_UNEXPECTED_MESSAGE})
+
+	case RECORD_HANDSHAKE:
+		return marshalRecord(RECORD_ALERT, sslscan.PROTOCOL_TLS12, []byte{2, ALERT_BAD_RECORD_MAC})
+	}
+
+	return nil
+}
+
+// findCommonSuite returns first suite from preferred list which is present in
+// other list
+func findCommonSuite(preferred, other []uint16) uint16 {
+	for _, suite := range preferred {
+		if containsUint16(other, suite) {
+			return suite
+		}
+	}
+
+	return 0
+}
+
+// parseClientHello parses ClientHello message body
+func parseClientHello(data []byte) (*clientHello, error) {
+	hello := &clientHello{}
+	p := &parser{data: data}
+
+	hello.Version = p.Uint16()
+	p.Bytes(32)
+	hello.SessionID = p.Bytes(int(p.Uint8()))
+
+	suites := &parser{data: p.Bytes(int(p.Uint16()))}
+
+	for suites.Len() > 1 {
+		hello.Suites = append(hello.Suites, suites.Uint16())
+	}
+
+	p.Bytes(int(p.Uint8()))
+
+	if p.Len() != 0 {
+		exts := &parser{data: p.Bytes(int(p.Uint16()))}
+
+		for exts.Len() != 0 && !exts.failed {
+			ext := extension{Type: exts.Uint16()}
+			ext.Data = exts.Bytes(int(exts.Uint16()))
+			hello.Extensions = append(hello.Extensions, ext)
+
+			if ext.Type == EXT_SUPPORTED_VERSIONS && len(ext.Data) >= 3 {
+				hello.Version = binary.BigEndian.Uint16(ext.Data[1:])
+			}
+		}
+	}
+
+	if p.failed {
+		return nil, fmt.Errorf("Can't parse ClientHello")
+	}
+
+	return hello, nil
+}
+
+// hasExtension returns true if ClientHello contains extension with given type
+func hasExtension(hello *clientHello, typ uint16) bool {
+	for _, ext := range hello.Extensions {
+		if ext.Type == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// byteReader is reader for static data
+type byteReader struct {
+	data []byte
+}
+
+// Read reads data
+func (r *byteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+
+	return n, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// startServer starts TLS server with given config on random port
+func startServer(c *check.C, config *tls.Config) *testServer {
+	return startListener(c, func(conn net.Conn) {
+		tlsConn := tls.Server(conn, config)
+		tlsConn.Handshake()
+		tlsConn.Close()
+	})
+}
+
+// startFakeServer starts raw TLS server which sends first server flight and
+// replies to records sent by client after ClientHello
+func startFakeServer(c *check.C, config *fakeConfig) *testServer {
+	return startListener(c, func(conn net.Conn) {
+		defer conn.Close()
+
+		_, data, err := readRecord(conn)
+
+		if err != nil || len(data) < 4 {
+			return
+		}
+
+		hello, err := parseClientHello(data[4:])
+
+		if err != nil {
+			return
+		}
+
+		conn.Write(config.Respond(hello))
+
+		for {
+			typ, data, err := readRecord(conn)
+
+			if err != nil {
+				return
+			}
+
+			resp := config.RespondRecord(typ, data)
+
+			if resp == nil {
+				return
+			}
+
+			conn.Write(resp)
+
+			// Server closes connection after fatal alert
+			if len(resp) != 0 && resp[0] == RECORD_ALERT {
+				return
+			}
+		}
+	})
+}
+
+// startListener starts TCP server with given connection handler on random port
+func startListener(c *check.C, handler func(conn net.Conn)) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		c.Fatalf("Can't start server: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			go handler(conn)
+		}
+	}()
+
+	return &testServer{
+		Port:     listener.Addr().(*net.TCPAddr).Port,
+		listener: listener,
+	}
+}
+
+// Target returns target for test server
+func (s *testServer) Target() *Target {
+	return &Target{Host: "localhost", IP: "127.0.0.1", Port: s.Port}
+}
+
+// Close stops server
+func (s *testServer) Close() {
+	s.listener.Close()
+}
+
+// newTestScanner creates scanner for test server
+func newTestScanner(srv *testServer) *Scanner {
+	scanner := New()
+	scanner.Port = srv.Port
+	scanner.Timeout = 3 * time.Second
+
+	return scanner
+}
+
+// newTestCert generates self-signed certificate for given host
+func newTestCert(c *check.C, host string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		c.Fatalf("Can't generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	if err != nil {
+		c.Fatalf("Can't create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// getProtocolIDs returns IDs of given protocols
+func getProtocolIDs(protocols []*sslscan.Protocol) []int {
+	var result []int
+
+	for _, p := range protocols {
+		result = append(result, p.ID)
+	}
+
+	return result
+}
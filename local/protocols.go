@@ -0,0 +1,94 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strconv"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// protocols is list of probed protocols
+var protocols = []int{
+	sslscan.PROTOCOL_SSL3,
+	sslscan.PROTOCOL_TLS10,
+	sslscan.PROTOCOL_TLS11,
+	sslscan.PROTOCOL_TLS12,
+	sslscan.PROTOCOL_TLS13,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeProtocols checks which protocol versions are supported by server and
+// fills protocols list
+func (s *Scanner) ProbeProtocols(t *Target, d *sslscan.EndpointDetails) error {
+	d.Protocols = nil
+
+	for _, version := range protocols {
+		ok, err := s.isProtocolSupported(t, version)
+
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			d.Protocols = append(d.Protocols, newProtocol(version))
+		}
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// isProtocolSupported returns true if server accepts handshake with given version.
+// If server doesn't accept any suite from catalogue, full IANA range is offered.
+func (s *Scanner) isProtocolSupported(t *Target, version int) (bool, error) {
+	for _, suites := range [][]uint16{getSuiteIDs(version), getRangeSuiteIDs(version)} {
+		hello := newClientHello(version, t.Host)
+		hello.Suites = suites
+
+		sh, err := s.handshake(t, hello)
+
+		if err != nil {
+			if isDialError(err) {
+				return false, err
+			}
+
+			continue
+		}
+
+		return int(sh.Version) == version, nil
+	}
+
+	return false, nil
+}
+
+// newProtocol creates protocol info for given version
+func newProtocol(version int) *sslscan.Protocol {
+	protocol := &sslscan.Protocol{ID: version}
+
+	switch version {
+	case sslscan.PROTOCOL_SSL2:
+		protocol.Name, protocol.Version = "SSL", "2.0"
+	case sslscan.PROTOCOL_SSL3:
+		protocol.Name, protocol.Version = "SSL", "3.0"
+	default:
+		protocol.Name = "TLS"
+		protocol.Version = "1." + strconv.Itoa(version-sslscan.PROTOCOL_TLS10)
+	}
+
+	// SSL Labs marks SSL 2.0 and SSL 3.0 as insecure
+	if version < sslscan.PROTOCOL_TLS10 {
+		protocol.Q = new(int)
+	}
+
+	return protocol
+}
@@ -0,0 +1,257 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// sessionCache is client session cache which keeps only last session
+type sessionCache struct {
+	session *tls.ClientSessionState
+}
+
+// recordingConn is connection which keeps copy of all received data
+type recordingConn struct {
+	net.Conn
+
+	received bytes.Buffer
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeSessionResumption checks if server supports session resumption with
+// session IDs
+func (s *Scanner) ProbeSessionResumption(t *Target, d *sslscan.EndpointDetails) error {
+	d.SessionResumption = sslscan.SESSION_RESUMPTION_DISABLED
+
+	version := getMaxLegacyProtocol(d.Protocols)
+
+	if version == 0 {
+		return nil
+	}
+
+	sh, err := s.getSessionHello(t, version)
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	if len(sh.SessionID) == 0 {
+		return nil
+	}
+
+	resumed, err := s.resumeSession(t, sh)
+
+	if err != nil && isDialError(err) {
+		return err
+	}
+
+	if resumed {
+		d.SessionResumption = sslscan.SESSION_RESUMPTION_ENABLED
+	} else {
+		d.SessionResumption = sslscan.SESSION_RESUMPTION_NOT_RESUMED
+	}
+
+	return nil
+}
+
+// ProbeSessionTickets checks if server supports session tickets
+func (s *Scanner) ProbeSessionTickets(t *Target, d *sslscan.EndpointDetails) error {
+	d.SessionTickets = 0
+
+	version := getMaxLegacyProtocol(d.Protocols)
+
+	if version == 0 {
+		return nil
+	}
+
+	cache := &sessionCache{}
+	conn, err := s.tlsDial(t, &tls.Config{
+		ServerName:         getServerName(t),
+		MaxVersion:         uint16(version),
+		ClientSessionCache: cache,
+	})
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		// Check if server fails handshake only due to session ticket extension
+		conn, err = s.tlsDial(t, &tls.Config{
+			ServerName:             getServerName(t),
+			MaxVersion:             uint16(version),
+			SessionTicketsDisabled: true,
+		})
+
+		if err != nil {
+			if isDialError(err) {
+				return err
+			}
+
+			return nil
+		}
+
+		conn.Close()
+		d.SessionTickets = sslscan.SESSION_TICKETS_INTOLERANT
+
+		return nil
+	}
+
+	conn.Close()
+
+	if cache.session == nil {
+		return nil
+	}
+
+	d.SessionTickets = sslscan.SESSION_TICKETS_SUPPORTED
+
+	conn, err = s.tlsDial(t, &tls.Config{
+		ServerName:         getServerName(t),
+		MaxVersion:         uint16(version),
+		ClientSessionCache: cache,
+	})
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		d.SessionTickets |= sslscan.SESSION_TICKETS_FAULTY
+
+		return nil
+	}
+
+	// Server issued ticket, but doesn't accept it
+	if !conn.ConnectionState().DidResume {
+		d.SessionTickets |= sslscan.SESSION_TICKETS_FAULTY
+	}
+
+	conn.Close()
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getSessionHello makes full handshake with disabled session tickets and returns
+// ServerHello sent by server
+func (s *Scanner) getSessionHello(t *Target, version int) (*serverHello, error) {
+	conn, err := s.dial(t)
+
+	if err != nil {
+		return nil, &dialError{err}
+	}
+
+	rc := &recordingConn{Conn: conn}
+	tlsConn, err := tlsHandshake(rc, &tls.Config{
+		ServerName:             getServerName(t),
+		MaxVersion:             uint16(version),
+		SessionTicketsDisabled: true,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Server adds session to cache only after full handshake, so we have
+	// to finish it before parsing ServerHello
+	tlsConn.Close()
+
+	rr := &recordReader{r: &rc.received}
+	typ, body, err := rr.ReadMessage()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != HANDSHAKE_SERVER_HELLO {
+		return nil, errUnexpectedMessage
+	}
+
+	return parseServerHello(body)
+}
+
+// resumeSession tries to resume session from given ServerHello and returns
+// true if server echoed session ID
+func (s *Scanner) resumeSession(t *Target, sh *serverHello) (bool, error) {
+	hello := newClientHello(int(sh.Version), t.Host)
+	hello.Suites = []uint16{sh.Suite}
+	hello.SessionID = sh.SessionID
+
+	// Server must not resume session with extended master secret if
+	// client doesn't send extension (RFC 7627)
+	if _, ok := sh.Extensions[EXT_EXTENDED_MASTER_SECRET]; ok {
+		hello.Extensions = []extension{{Type: EXT_EXTENDED_MASTER_SECRET}}
+	}
+
+	conn, err := s.dial(t)
+
+	if err != nil {
+		return false, &dialError{err}
+	}
+
+	defer conn.Close()
+
+	_, err = conn.Write(hello.Marshal())
+
+	if err != nil {
+		return false, err
+	}
+
+	// Resumed handshake continues with encrypted Finished message, so we
+	// read only ServerHello
+	rr := &recordReader{r: conn}
+	typ, body, err := rr.ReadMessage()
+
+	if err != nil {
+		return false, err
+	}
+
+	if typ != HANDSHAKE_SERVER_HELLO {
+		return false, errUnexpectedMessage
+	}
+
+	resumed, err := parseServerHello(body)
+
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(resumed.SessionID, sh.SessionID), nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns cached session
+func (c *sessionCache) Get(key string) (*tls.ClientSessionState, bool) {
+	return c.session, c.session != nil
+}
+
+// Put adds session to cache
+func (c *sessionCache) Put(key string, session *tls.ClientSessionState) {
+	c.session = session
+}
+
+// Read reads data from connection and keeps copy of it
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.Write(p[:n])
+	return n, err
+}
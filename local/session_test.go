@@ -0,0 +1,96 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestSessionTickets(c *check.C) {
+	cases := []struct {
+		max      uint16
+		disabled bool
+		expected int
+	}{
+		{tls.VersionTLS12, false, sslscan.SESSION_TICKETS_SUPPORTED},
+		{tls.VersionTLS12, true, 0},
+		{tls.VersionTLS13, false, 0},
+	}
+
+	for _, tc := range cases {
+		srv := startServer(c, &tls.Config{
+			Certificates:           []tls.Certificate{s.cert},
+			MinVersion:             tc.max,
+			MaxVersion:             tc.max,
+			SessionTicketsDisabled: tc.disabled,
+		})
+
+		details := &sslscan.EndpointDetails{}
+		scanner := newTestScanner(srv)
+
+		c.Assert(scanner.ProbeProtocols(srv.Target(), details), check.IsNil)
+		c.Assert(scanner.ProbeSessionTickets(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.SessionTickets, check.Equals, tc.expected)
+	}
+}
+
+func (s *LocalSuite) TestSessionResumption(c *check.C) {
+	srv := startServer(c, &tls.Config{
+		Certificates: []tls.Certificate{s.cert},
+		MaxVersion:   tls.VersionTLS12,
+	})
+
+	details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS12)}}
+	scanner := newTestScanner(srv)
+
+	sh, err := scanner.getSessionHello(srv.Target(), sslscan.PROTOCOL_TLS12)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(sh.Version, check.Equals, uint16(sslscan.PROTOCOL_TLS12))
+
+	// crypto/tls doesn't support resumption with session IDs
+	c.Assert(scanner.ProbeSessionResumption(srv.Target(), details), check.IsNil)
+	c.Assert(details.SessionResumption, check.Equals, sslscan.SESSION_RESUMPTION_DISABLED)
+
+	srv.Close()
+
+	c.Assert(scanner.ProbeSessionResumption(srv.Target(), details), check.NotNil)
+
+	sh = &serverHello{
+		Version:    sslscan.PROTOCOL_TLS12,
+		Suite:      0xc02f,
+		SessionID:  []byte{1, 2, 3, 4},
+		Extensions: map[uint16][]byte{EXT_EXTENDED_MASTER_SECRET: nil},
+	}
+
+	for _, resume := range []bool{true, false} {
+		srv = startFakeServer(c, &fakeConfig{
+			Versions: []uint16{sslscan.PROTOCOL_TLS12},
+			Suites:   []uint16{0xc02f},
+			Group:    23,
+			Cert:     s.cert.Certificate[0],
+			Resume:   resume,
+		})
+
+		resumed, err := newTestScanner(srv).resumeSession(srv.Target(), sh)
+
+		srv.Close()
+
+		c.Assert(err, check.IsNil)
+		c.Assert(resumed, check.Equals, resume)
+	}
+}
@@ -0,0 +1,341 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ClientProfile contains parameters of simulated client
+type ClientProfile struct {
+	ID          int      `json:"id"`          // unique client ID
+	Name        string   `json:"name"`        // name of the client (e.g. Chrome)
+	Platform    string   `json:"platform"`    // name of the platform (e.g. Win 10)
+	Version     string   `json:"version"`     // version of the client (e.g. 80)
+	IsReference bool     `json:"isReference"` // true if client is representative of modern clients
+	Protocols   []int    `json:"protocols"`   // offered protocols (e.g. 771 for TLS 1.2)
+	Suites      []string `json:"suites"`      // offered cipher suites in client preference order
+	Groups      []string `json:"groups"`      // offered named groups
+	SigAlgs     []string `json:"sigAlgs"`     // offered signature algorithms
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// sigAlgs contains IDs of signature algorithms
+var sigAlgs = map[string]uint16{
+	"rsa_pkcs1_sha1":         0x0201,
+	"ecdsa_sha1":             0x0203,
+	"rsa_pkcs1_sha256":       0x0401,
+	"ecdsa_secp256r1_sha256": 0x0403,
+	"rsa_pkcs1_sha384":       0x0501,
+	"ecdsa_secp384r1_sha384": 0x0503,
+	"rsa_pkcs1_sha512":       0x0601,
+	"ecdsa_secp521r1_sha512": 0x0603,
+	"rsa_pss_rsae_sha256":    0x0804,
+	"rsa_pss_rsae_sha384":    0x0805,
+	"rsa_pss_rsae_sha512":    0x0806,
+	"ed25519":                0x0807,
+	"ed448":                  0x0808,
+	"rsa_pss_pss_sha256":     0x0809,
+	"rsa_pss_pss_sha384":     0x080a,
+	"rsa_pss_pss_sha512":     0x080b,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// LoadClients loads client profiles from JSON file
+func LoadClients(file string) ([]*ClientProfile, error) {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var clients []*ClientProfile
+
+	err = json.Unmarshal(data, &clients)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse client profiles: %v", err)
+	}
+
+	for _, client := range clients {
+		err = client.Validate()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return clients, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeSimulations runs handshakes with parameters of all client profiles
+func (s *Scanner) ProbeSimulations(t *Target, d *sslscan.EndpointDetails) error {
+	d.SIMS = &sslscan.SIMS{}
+
+	for _, client := range s.getClients() {
+		sim, err := s.simulate(t, d, client)
+
+		if err != nil {
+			return err
+		}
+
+		d.SIMS.Results = append(d.SIMS.Results, sim)
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Validate checks client profile for errors
+func (p *ClientProfile) Validate() error {
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("Client %d doesn't have name", p.ID)
+	case len(p.Protocols) == 0:
+		return fmt.Errorf("Client %s doesn't have protocols", p.Title())
+	case len(p.Suites) == 0:
+		return fmt.Errorf("Client %s doesn't have cipher suites", p.Title())
+	}
+
+	for _, protocol := range p.Protocols {
+		if protocol < sslscan.PROTOCOL_TLS10 || protocol > sslscan.PROTOCOL_TLS13 {
+			return fmt.Errorf("Client %s has unsupported protocol %d", p.Title(), protocol)
+		}
+	}
+
+	for _, name := range p.Suites {
+		if getSuiteInfoByName(name) == nil {
+			return fmt.Errorf("Client %s has unknown cipher suite %s", p.Title(), name)
+		}
+	}
+
+	for _, name := range p.Groups {
+		if getGroupInfoByName(name) == nil {
+			return fmt.Errorf("Client %s has unknown named group %s", p.Title(), name)
+		}
+	}
+
+	for _, name := range p.SigAlgs {
+		if _, ok := sigAlgs[name]; !ok {
+			return fmt.Errorf("Client %s has unknown signature algorithm %s", p.Title(), name)
+		}
+	}
+
+	return nil
+}
+
+// Title returns client name with platform and version
+func (p *ClientProfile) Title() string {
+	result := p.Name
+
+	if p.Version != "" {
+		result += " " + p.Version
+	}
+
+	if p.Platform != "" {
+		result += " / " + p.Platform
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// simulate runs handshake with parameters of given client
+func (s *Scanner) simulate(t *Target, d *sslscan.EndpointDetails, client *ClientProfile) (*sslscan.SIM, error) {
+	sim := &sslscan.SIM{
+		Client: &sslscan.SimClient{
+			ID:          client.ID,
+			Name:        client.Name,
+			Platform:    client.Platform,
+			Version:     client.Version,
+			IsReference: client.IsReference,
+		},
+	}
+
+	if !hasCommonProtocol(client.Protocols, d.Protocols) {
+		sim.ErrorCode, sim.ErrorMessage = 1, "Protocol mismatch (not simulated)"
+		return sim, nil
+	}
+
+	sim.Attempts = 1
+
+	hello := client.newClientHello(t.Host)
+	sh, err := s.handshake(t, hello)
+
+	if err != nil {
+		if isDialError(err) {
+			return nil, err
+		}
+
+		sim.ErrorCode = 1
+
+		if _, ok := err.(*alertError); ok {
+			sim.ErrorMessage = "Protocol or cipher suite mismatch"
+		} else {
+			sim.ErrorMessage = "Handshake failed: " + err.Error()
+		}
+
+		return sim, nil
+	}
+
+	if !containsInt(client.Protocols, int(sh.Version)) || !containsUint16(hello.Suites, sh.Suite) {
+		sim.ErrorCode, sim.ErrorMessage = 1, "Server negotiated parameters not offered by client"
+		return sim, nil
+	}
+
+	suite := newSuite(sh)
+
+	sim.ProtocolID = int(sh.Version)
+	sim.SuiteID, sim.SuiteName = suite.ID, suite.Name
+	sim.KxType, sim.KxStrength = suite.KxType, suite.KxStrength
+	sim.DHBits, sim.DHG, sim.DHP, sim.DHYs = suite.DHBits, suite.DHG, suite.DHP, suite.DHYs
+	sim.NamedGroupID, sim.NamedGroupName, sim.NamedGroupBits = suite.NamedGroupID, suite.NamedGroupName, suite.NamedGroupBits
+
+	updateSimCert(sim, sh, t, d)
+
+	return sim, nil
+}
+
+// getClients returns configured client profiles or default catalogue
+func (s *Scanner) getClients() []*ClientProfile {
+	if len(s.Clients) != 0 {
+		return s.Clients
+	}
+
+	return DefaultClients
+}
+
+// newClientHello creates ClientHello with client parameters
+func (p *ClientProfile) newClientHello(serverName string) *clientHello {
+	hello := &clientHello{ServerName: serverName}
+
+	protocols := append([]int{}, p.Protocols...)
+	sort.Sort(sort.Reverse(sort.IntSlice(protocols)))
+
+	hello.Version = uint16(protocols[0])
+
+	for _, protocol := range protocols {
+		hello.Versions = append(hello.Versions, uint16(protocol))
+	}
+
+	for _, name := range p.Suites {
+		if suite := getSuiteInfoByName(name); suite != nil {
+			hello.Suites = append(hello.Suites, suite.ID)
+		}
+	}
+
+	for _, name := range p.Groups {
+		if group := getGroupInfoByName(name); group != nil {
+			hello.Groups = append(hello.Groups, group.ID)
+		}
+	}
+
+	for _, name := range p.SigAlgs {
+		if id, ok := sigAlgs[name]; ok {
+			hello.SigAlgs = append(hello.SigAlgs, id)
+		}
+	}
+
+	return hello
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// updateSimCert adds info about certificate used for handshake. Certificates
+// are encrypted in TLS 1.3, so info from certificate chain probe is used.
+func updateSimCert(sim *sslscan.SIM, sh *serverHello, t *Target, d *sslscan.EndpointDetails) {
+	if len(sh.Certificates) != 0 {
+		var chain []*x509.Certificate
+
+		for _, der := range sh.Certificates {
+			cert, err := x509.ParseCertificate(der)
+
+			if err != nil {
+				return
+			}
+
+			chain = append(chain, cert)
+		}
+
+		sim.CertChainID = getChainID(chain)
+		sim.KeyAlg, sim.KeySize = getKeyAlg(chain[0])
+		sim.SigAlg = sigAlgNames[chain[0].SignatureAlgorithm]
+
+		return
+	}
+
+	if len(d.CertChains) == 0 || len(d.CertChains[0].CertIDs) == 0 {
+		return
+	}
+
+	sim.CertChainID = d.CertChains[0].ID
+
+	for _, cert := range t.Certs {
+		if cert.ID == d.CertChains[0].CertIDs[0] {
+			sim.KeyAlg, sim.KeySize, sim.SigAlg = cert.KeyAlg, cert.KeySize, cert.SigAlg
+		}
+	}
+}
+
+// hasCommonProtocol returns true if client supports any of server protocols
+func hasCommonProtocol(client []int, server []*sslscan.Protocol) bool {
+	for _, p := range server {
+		if containsInt(client, p.ID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getSuiteInfoByName returns info about suite with given name
+func getSuiteInfoByName(name string) *suiteInfo {
+	for _, suite := range suites {
+		if suite.Name == name {
+			return suite
+		}
+	}
+
+	return nil
+}
+
+// getGroupInfoByName returns info about named group with given name
+func getGroupInfoByName(name string) *groupInfo {
+	for _, g := range groups {
+		if g.Name == name {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// containsInt returns true if slice contains given value
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestSimulations(c *check.C) {
+	srv := startServer(c, &tls.Config{
+		Certificates: []tls.Certificate{s.cert},
+		MinVersion:   tls.VersionTLS12,
+		MaxVersion:   tls.VersionTLS12,
+	})
+
+	defer srv.Close()
+
+	scanner := newTestScanner(srv)
+	scanner.Clients = []*ClientProfile{
+		{
+			ID: 1, Name: "Modern", Version: "1.0",
+			Protocols: []int{sslscan.PROTOCOL_TLS12, sslscan.PROTOCOL_TLS13},
+			Suites:    []string{"TLS_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+			Groups:    []string{"x25519", "secp256r1"},
+			SigAlgs:   []string{"ecdsa_secp256r1_sha256"},
+		},
+		{
+			ID: 2, Name: "RSA", Version: "1.0",
+			Protocols: []int{sslscan.PROTOCOL_TLS12},
+			Suites:    []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"},
+		},
+		{
+			ID: 3, Name: "Legacy", Platform: "XP", Version: "1.0",
+			Protocols: []int{sslscan.PROTOCOL_TLS10},
+			Suites:    []string{"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA"},
+		},
+	}
+
+	details := &sslscan.EndpointDetails{}
+	target := srv.Target()
+
+	c.Assert(scanner.ProbeProtocols(target, details), check.IsNil)
+	c.Assert(scanner.ProbeChain(target, details), check.IsNil)
+	c.Assert(scanner.ProbeSimulations(target, details), check.IsNil)
+	c.Assert(details.SIMS.Results, check.HasLen, 3)
+
+	sim := details.SIMS.Results[0]
+
+	c.Assert(sim.ErrorCode, check.Equals, 0)
+	c.Assert(sim.Attempts, check.Equals, 1)
+	c.Assert(sim.Client.ID, check.Equals, 1)
+	c.Assert(sim.ProtocolID, check.Equals, sslscan.PROTOCOL_TLS12)
+	c.Assert(sim.SuiteName, check.Equals, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256")
+	c.Assert(sim.NamedGroupName, check.Equals, "x25519")
+	c.Assert(sim.KeyAlg, check.Equals, "EC")
+	c.Assert(sim.KeySize, check.Equals, 256)
+	c.Assert(sim.SigAlg, check.Equals, "SHA256withECDSA")
+	c.Assert(sim.CertChainID, check.Equals, details.CertChains[0].ID)
+
+	sim = details.SIMS.Results[1]
+
+	c.Assert(sim.ErrorCode, check.Equals, 1)
+	c.Assert(sim.ErrorMessage, check.Equals, "Protocol or cipher suite mismatch")
+	c.Assert(sim.Attempts, check.Equals, 1)
+
+	sim = details.SIMS.Results[2]
+
+	c.Assert(sim.ErrorCode, check.Equals, 1)
+	c.Assert(sim.ErrorMessage, check.Equals, "Protocol mismatch (not simulated)")
+	c.Assert(sim.Attempts, check.Equals, 0)
+	c.Assert(sim.Client.Platform, check.Equals, "XP")
+
+	srv.Close()
+
+	c.Assert(scanner.ProbeSimulations(target, details), check.NotNil)
+}
+
+func (s *LocalSuite) TestSimulationsTLS13(c *check.C) {
+	srv := startServer(c, &tls.Config{
+		Certificates: []tls.Certificate{s.cert},
+		MinVersion:   tls.VersionTLS13,
+	})
+
+	defer srv.Close()
+
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+	target := srv.Target()
+
+	c.Assert(scanner.ProbeProtocols(target, details), check.IsNil)
+	c.Assert(scanner.ProbeChain(target, details), check.IsNil)
+	c.Assert(scanner.ProbeSimulations(target, details), check.IsNil)
+	c.Assert(details.SIMS.Results, check.HasLen, len(DefaultClients))
+
+	var succeeded int
+
+	for _, sim := range details.SIMS.Results {
+		if sim.ErrorCode != 0 {
+			c.Assert(sim.ErrorMessage, check.Equals, "Protocol mismatch (not simulated)")
+			continue
+		}
+
+		c.Assert(sim.ProtocolID, check.Equals, sslscan.PROTOCOL_TLS13)
+		c.Assert(sim.KeyAlg, check.Equals, "EC")
+		c.Assert(sim.CertChainID, check.Equals, details.CertChains[0].ID)
+
+		succeeded++
+	}
+
+	c.Assert(succeeded, check.Not(check.Equals), 0)
+}
+
+func (s *LocalSuite) TestClientProfiles(c *check.C) {
+	ids := make(map[int]bool)
+
+	for _, client := range DefaultClients {
+		c.Assert(client.Validate(), check.IsNil)
+		c.Assert(ids[client.ID], check.Equals, false)
+		ids[client.ID] = true
+	}
+
+	client := &ClientProfile{Name: "Test", Version: "1.0", Platform: "Linux"}
+
+	c.Assert(client.Title(), check.Equals, "Test 1.0 / Linux")
+	c.Assert(client.Validate(), check.ErrorMatches, "Client Test 1.0 / Linux doesn't have protocols")
+
+	client.Protocols = []int{sslscan.PROTOCOL_SSL3}
+	c.Assert(client.Validate(), check.ErrorMatches, "Client .* doesn't have cipher suites")
+	client.Suites = []string{"TLS_RSA_WITH_AES_128_CBC_SHA"}
+	c.Assert(client.Validate(), check.ErrorMatches, "Client .* has unsupported protocol 768")
+	client.Protocols = []int{sslscan.PROTOCOL_TLS12}
+	client.Groups = []string{"unknown"}
+	c.Assert(client.Validate(), check.ErrorMatches, "Client .* has unknown named group unknown")
+	client.Groups, client.SigAlgs = nil, []string{"unknown"}
+	c.Assert(client.Validate(), check.ErrorMatches, "Client .* has unknown signature algorithm unknown")
+	client.SigAlgs = nil
+	c.Assert(client.Validate(), check.IsNil)
+
+	c.Assert((&ClientProfile{ID: 1}).Validate(), check.ErrorMatches, "Client 1 doesn't have name")
+}
+
+func (s *LocalSuite) TestLoadClients(c *check.C) {
+	dir := c.MkDir()
+
+	ioutil.WriteFile(dir+"/clients.json", []byte(`[{
+		"id": 1000, "name": "MyApp", "platform": "Android", "version": "2.1",
+		"protocols": [771], "suites": ["TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"],
+		"groups": ["x25519"], "sigAlgs": ["ecdsa_secp256r1_sha256"]
+	}]`), 0644)
+
+	ioutil.WriteFile(dir+"/unknown.json", []byte(`[{
+		"id": 1000, "name": "MyApp", "protocols": [771], "suites": ["TLS_UNKNOWN"]
+	}]`), 0644)
+
+	ioutil.WriteFile(dir+"/broken.json", []byte(`{`), 0644)
+
+	clients, err := LoadClients(dir + "/clients.json")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(clients, check.HasLen, 1)
+	c.Assert(clients[0].ID, check.Equals, 1000)
+	c.Assert(clients[0].Title(), check.Equals, "MyApp 2.1 / Android")
+	c.Assert(clients[0].Groups, check.DeepEquals, []string{"x25519"})
+
+	_, err = LoadClients(dir + "/unknown.json")
+	c.Assert(err, check.ErrorMatches, "Client MyApp has unknown cipher suite TLS_UNKNOWN")
+
+	_, err = LoadClients(dir + "/broken.json")
+	c.Assert(err, check.ErrorMatches, "Can't parse client profiles: .*")
+
+	_, err = LoadClients(dir + "/missing.json")
+	c.Assert(err, check.NotNil)
+}
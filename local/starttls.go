@@ -0,0 +1,433 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Application protocols with STARTTLS support
+const (
+	STARTTLS_SMTP     = "smtp"
+	STARTTLS_IMAP     = "imap"
+	STARTTLS_POP3     = "pop3"
+	STARTTLS_FTP      = "ftp"
+	STARTTLS_LDAP     = "ldap"
+	STARTTLS_XMPP     = "xmpp"
+	STARTTLS_POSTGRES = "postgres"
+)
+
+// PROTOCOL_HTTP is application protocol of endpoints without STARTTLS
+const PROTOCOL_HTTP = "http"
+
+// MAX_STARTTLS_DATA is max size of data read during STARTTLS negotiation
+const MAX_STARTTLS_DATA = 64 * 1024
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// startTLSPorts contains default ports of application protocols
+var startTLSPorts = map[string]int{
+	STARTTLS_SMTP:     25,
+	STARTTLS_IMAP:     143,
+	STARTTLS_POP3:     110,
+	STARTTLS_FTP:      21,
+	STARTTLS_LDAP:     389,
+	STARTTLS_XMPP:     5222,
+	STARTTLS_POSTGRES: 5432,
+}
+
+// ldapStartTLSRequest is LDAP extended request with StartTLS OID
+// (1.3.6.1.4.1.1466.20037) and message ID 1
+var ldapStartTLSRequest = []byte{
+	0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16,
+	'1', '.', '3', '.', '6', '.', '1', '.', '4', '.', '1', '.',
+	'1', '4', '6', '6', '.', '2', '0', '0', '3', '7',
+}
+
+// postgresSSLRequest is PostgreSQL SSLRequest message
+var postgresSSLRequest = []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsStartTLSSupported returns true if STARTTLS is supported for given
+// application protocol
+func IsStartTLSSupported(protocol string) bool {
+	_, ok := startTLSPorts[protocol]
+	return ok
+}
+
+// GetStartTLSPort returns default port for given application protocol
+func GetStartTLSPort(protocol string) int {
+	port, ok := startTLSPorts[protocol]
+
+	if !ok {
+		return DEFAULT_PORT
+	}
+
+	return port
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// startTLS negotiates TLS over plain connection with given application protocol
+func startTLS(conn net.Conn, protocol, host string) error {
+	var err error
+
+	switch protocol {
+	case STARTTLS_SMTP:
+		err = startTLSSMTP(conn)
+	case STARTTLS_IMAP:
+		err = startTLSIMAP(conn)
+	case STARTTLS_POP3:
+		err = startTLSPOP3(conn)
+	case STARTTLS_FTP:
+		err = startTLSFTP(conn)
+	case STARTTLS_LDAP:
+		err = startTLSLDAP(conn)
+	case STARTTLS_XMPP:
+		err = startTLSXMPP(conn, host)
+	case STARTTLS_POSTGRES:
+		err = startTLSPostgres(conn)
+	default:
+		return fmt.Errorf("Unsupported STARTTLS protocol %s", protocol)
+	}
+
+	if err != nil {
+		return fmt.Errorf("STARTTLS negotiation failed: %v", err)
+	}
+
+	return nil
+}
+
+// startTLSSMTP negotiates TLS with SMTP server (RFC 3207)
+func startTLSSMTP(conn net.Conn) error {
+	r := newLineReader(conn)
+
+	_, _, err := r.ReadResponse(220)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(conn, "EHLO sslscan\r\n")
+
+	if err != nil {
+		return err
+	}
+
+	_, msg, err := r.ReadResponse(250)
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(strings.ToUpper(msg), "STARTTLS") {
+		return fmt.Errorf("Server doesn't support STARTTLS")
+	}
+
+	_, err = io.WriteString(conn, "STARTTLS\r\n")
+
+	if err != nil {
+		return err
+	}
+
+	_, _, err = r.ReadResponse(220)
+
+	return err
+}
+
+// startTLSIMAP negotiates TLS with IMAP server (RFC 3501)
+func startTLSIMAP(conn net.Conn) error {
+	r := newLineReader(conn)
+	line, err := r.ReadLine()
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("Unexpected greeting \"%s\"", line)
+	}
+
+	_, err = io.WriteString(conn, "A1 STARTTLS\r\n")
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		line, err = r.ReadLine()
+
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(line, "A1 ") {
+			break
+		}
+	}
+
+	if !strings.HasPrefix(line, "A1 OK") {
+		return fmt.Errorf("Unexpected response \"%s\"", line)
+	}
+
+	return nil
+}
+
+// startTLSPOP3 negotiates TLS with POP3 server (RFC 2595)
+func startTLSPOP3(conn net.Conn) error {
+	r := newLineReader(conn)
+	line, err := r.ReadLine()
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("Unexpected greeting \"%s\"", line)
+	}
+
+	_, err = io.WriteString(conn, "STLS\r\n")
+
+	if err != nil {
+		return err
+	}
+
+	line, err = r.ReadLine()
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("Unexpected response \"%s\"", line)
+	}
+
+	return nil
+}
+
+// startTLSFTP negotiates TLS with FTP server (RFC 4217)
+func startTLSFTP(conn net.Conn) error {
+	r := newLineReader(conn)
+
+	_, _, err := r.ReadResponse(220)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(conn, "AUTH TLS\r\n")
+
+	if err != nil {
+		return err
+	}
+
+	_, _, err = r.ReadResponse(234)
+
+	return err
+}
+
+// startTLSLDAP negotiates TLS with LDAP server (RFC 4511)
+func startTLSLDAP(conn net.Conn) error {
+	_, err := conn.Write(ldapStartTLSRequest)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := readBERElement(conn)
+
+	if err != nil {
+		return err
+	}
+
+	// LDAPMessage contains message ID and ExtendedResponse which starts
+	// with result code
+	p := &parser{data: data}
+	idTag, _ := parseBERElement(p)
+	respTag, resp := parseBERElement(p)
+	codeTag, code := parseBERElement(&parser{data: resp})
+
+	switch {
+	case p.failed || idTag != 0x02 || respTag != 0x78 || codeTag != 0x0a || len(code) != 1:
+		return fmt.Errorf("Malformed LDAP response")
+	case code[0] != 0:
+		return fmt.Errorf("Server returned result code %d", code[0])
+	}
+
+	return nil
+}
+
+// startTLSXMPP negotiates TLS with XMPP server (RFC 6120)
+func startTLSXMPP(conn net.Conn, host string) error {
+	_, err := fmt.Fprintf(conn,
+		"<?xml version='1.0'?><stream:stream xmlns='jabber:client' "+
+			"xmlns:stream='http://etherx.jabber.org/streams' to='%s' version='1.0'>",
+		host,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	features, err := readUntil(conn, "</stream:features>", "<stream:features/>")
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(features, "urn:ietf:params:xml:ns:xmpp-tls") {
+		return fmt.Errorf("Server doesn't support STARTTLS")
+	}
+
+	_, err = io.WriteString(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>")
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := readUntil(conn, "<proceed", "<failure")
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(resp, "<proceed") {
+		return fmt.Errorf("Server refused STARTTLS")
+	}
+
+	return nil
+}
+
+// startTLSPostgres negotiates TLS with PostgreSQL server
+func startTLSPostgres(conn net.Conn) error {
+	_, err := conn.Write(postgresSSLRequest)
+
+	if err != nil {
+		return err
+	}
+
+	resp := make([]byte, 1)
+
+	_, err = io.ReadFull(conn, resp)
+
+	if err != nil {
+		return err
+	}
+
+	if resp[0] != 'S' {
+		return fmt.Errorf("Server doesn't support SSL")
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newLineReader creates reader for text protocols. Server doesn't send
+// anything after STARTTLS response until ClientHello, so buffering is safe.
+func newLineReader(conn net.Conn) *textproto.Reader {
+	return textproto.NewReader(bufio.NewReader(conn))
+}
+
+// readBERElement reads BER-encoded element and returns its content
+func readBERElement(r io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+
+	_, err := io.ReadFull(r, header)
+
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(header[1])
+
+	if size&0x80 != 0 {
+		n := size & 0x7f
+
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("Malformed LDAP response")
+		}
+
+		buf := make([]byte, 4)
+
+		_, err = io.ReadFull(r, buf[4-n:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		size = int(binary.BigEndian.Uint32(buf))
+	}
+
+	if size > MAX_STARTTLS_DATA {
+		return nil, fmt.Errorf("LDAP response is too big (%d bytes)", size)
+	}
+
+	data := make([]byte, size)
+
+	_, err = io.ReadFull(r, data)
+
+	return data, err
+}
+
+// parseBERElement parses BER-encoded element and returns its tag and content
+func parseBERElement(p *parser) (uint8, []byte) {
+	tag := p.Uint8()
+	size := int(p.Uint8())
+
+	if size&0x80 != 0 {
+		n := size & 0x7f
+
+		if n == 0 || n > 4 {
+			p.failed = true
+			return 0, nil
+		}
+
+		size = 0
+
+		for _, b := range p.Bytes(n) {
+			size = size<<8 | int(b)
+		}
+	}
+
+	return tag, p.Bytes(size)
+}
+
+// readUntil reads data from connection until it contains any of given markers
+func readUntil(r io.Reader, markers ...string) (string, error) {
+	var buf bytes.Buffer
+
+	chunk := make([]byte, 1024)
+
+	for buf.Len() < MAX_STARTTLS_DATA {
+		n, err := r.Read(chunk)
+		buf.Write(chunk[:n])
+
+		for _, marker := range markers {
+			if bytes.Contains(buf.Bytes(), []byte(marker)) {
+				return buf.String(), nil
+			}
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("Response is too big")
+}
@@ -0,0 +1,236 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/textproto"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var startTLSProtocols = []string{
+	STARTTLS_SMTP, STARTTLS_IMAP, STARTTLS_POP3, STARTTLS_FTP,
+	STARTTLS_LDAP, STARTTLS_XMPP, STARTTLS_POSTGRES,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestStartTLS(c *check.C) {
+	config := &tls.Config{Certificates: []tls.Certificate{s.cert}}
+
+	for _, protocol := range startTLSProtocols {
+		srv := startStartTLSServer(c, protocol, true, config)
+
+		scanner := newTestScanner(srv)
+		scanner.StartTLS = protocol
+
+		info, err := scanner.Scan("127.0.0.1", sslscan.AnalyzeParams{})
+
+		srv.Close()
+
+		c.Assert(err, check.IsNil)
+		c.Assert(info.Protocol, check.Equals, protocol)
+		c.Assert(info.Endpoints, check.HasLen, 1)
+
+		details := info.Endpoints[0].Details
+
+		c.Assert(info.Endpoints[0].StatusMessage, check.Equals, "Ready", check.Commentf("Protocol: %s", protocol))
+		c.Assert(details.Protocols, check.Not(check.HasLen), 0)
+		c.Assert(details.Suites, check.Not(check.HasLen), 0)
+		c.Assert(details.CertChains, check.HasLen, 1)
+		c.Assert(details.HTTPTransactions, check.HasLen, 0)
+		c.Assert(info.Certs, check.HasLen, 1)
+	}
+}
+
+func (s *LocalSuite) TestStartTLSErrors(c *check.C) {
+	config := &tls.Config{Certificates: []tls.Certificate{s.cert}}
+
+	for _, protocol := range startTLSProtocols {
+		srv := startStartTLSServer(c, protocol, false, config)
+
+		conn, err := net.Dial("tcp", srv.Target().Addr())
+
+		c.Assert(err, check.IsNil)
+		c.Assert(
+			startTLS(conn, protocol, "localhost"), check.ErrorMatches,
+			"STARTTLS negotiation failed: .*", check.Commentf("Protocol: %s", protocol),
+		)
+
+		conn.Close()
+		srv.Close()
+	}
+
+	srv := startStartTLSServer(c, STARTTLS_SMTP, false, config)
+	defer srv.Close()
+
+	scanner := newTestScanner(srv)
+	scanner.StartTLS = STARTTLS_SMTP
+
+	info, err := scanner.Scan("127.0.0.1", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Endpoints[0].StatusMessage, check.Equals, "Unable to connect to the server")
+
+	scanner.StartTLS = "gopher"
+
+	_, err = scanner.Scan("127.0.0.1", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.ErrorMatches, "Unsupported STARTTLS protocol gopher")
+	c.Assert(startTLS(nil, "gopher", ""), check.ErrorMatches, "Unsupported STARTTLS protocol gopher")
+
+	c.Assert(IsStartTLSSupported(STARTTLS_IMAP), check.Equals, true)
+	c.Assert(IsStartTLSSupported("gopher"), check.Equals, false)
+	c.Assert(GetStartTLSPort(STARTTLS_SMTP), check.Equals, 25)
+	c.Assert(GetStartTLSPort("gopher"), check.Equals, DEFAULT_PORT)
+}
+
+func (s *LocalSuite) TestBERParsing(c *check.C) {
+	p := &parser{data: []byte{0x04, 0x82, 0x00, 0x02, 'o', 'k'}}
+	tag, data := parseBERElement(p)
+
+	c.Assert(p.failed, check.Equals, false)
+	c.Assert(tag, check.Equals, uint8(0x04))
+	c.Assert(string(data), check.Equals, "ok")
+
+	p = &parser{data: []byte{0x04, 0x80}}
+	parseBERElement(p)
+
+	c.Assert(p.failed, check.Equals, true)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// startStartTLSServer starts server stub which negotiates TLS with given
+// application protocol. If accept is false, server refuses STARTTLS request.
+func startStartTLSServer(c *check.C, protocol string, accept bool, config *tls.Config) *testServer {
+	return startListener(c, func(conn net.Conn) {
+		defer conn.Close()
+
+		if !negotiateStartTLS(conn, protocol, accept) || !accept {
+			return
+		}
+
+		tlsConn := tls.Server(conn, config)
+		tlsConn.Handshake()
+		tlsConn.Close()
+	})
+}
+
+// negotiateStartTLS runs server side of STARTTLS negotiation
+func negotiateStartTLS(conn net.Conn, protocol string, accept bool) bool {
+	r := textproto.NewReader(bufio.NewReader(conn))
+
+	// reply sends one of given responses depending on accept flag
+	reply := func(ok, fail string) bool {
+		if accept {
+			_, err := io.WriteString(conn, ok)
+			return err == nil
+		}
+
+		io.WriteString(conn, fail)
+
+		return false
+	}
+
+	switch protocol {
+	case STARTTLS_SMTP:
+		io.WriteString(conn, "220 mail.test ESMTP\r\n")
+
+		if line, _ := r.ReadLine(); line != "EHLO sslscan" {
+			return false
+		}
+
+		io.WriteString(conn, "250-mail.test\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+
+		if line, _ := r.ReadLine(); line != "STARTTLS" {
+			return false
+		}
+
+		return reply("220 Ready to start TLS\r\n", "454 TLS not available\r\n")
+
+	case STARTTLS_IMAP:
+		io.WriteString(conn, "* OK IMAP4rev1 ready\r\n")
+
+		if line, _ := r.ReadLine(); line != "A1 STARTTLS" {
+			return false
+		}
+
+		return reply("A1 OK Begin TLS negotiation\r\n", "A1 BAD STARTTLS not available\r\n")
+
+	case STARTTLS_POP3:
+		io.WriteString(conn, "+OK POP3 ready\r\n")
+
+		if line, _ := r.ReadLine(); line != "STLS" {
+			return false
+		}
+
+		return reply("+OK Begin TLS negotiation\r\n", "-ERR STLS not available\r\n")
+
+	case STARTTLS_FTP:
+		io.WriteString(conn, "220-Welcome\r\n220 FTP ready\r\n")
+
+		if line, _ := r.ReadLine(); line != "AUTH TLS" {
+			return false
+		}
+
+		return reply("234 AUTH TLS successful\r\n", "502 Command not implemented\r\n")
+
+	case STARTTLS_LDAP:
+		request := make([]byte, len(ldapStartTLSRequest))
+
+		if _, err := io.ReadFull(conn, request); err != nil || string(request) != string(ldapStartTLSRequest) {
+			return false
+		}
+
+		return reply(
+			"\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x00\x04\x00\x04\x00",
+			"\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x02\x04\x00\x04\x00",
+		)
+
+	case STARTTLS_XMPP:
+		if _, err := readUntil(conn, "version='1.0'>"); err != nil {
+			return false
+		}
+
+		io.WriteString(conn,
+			"<?xml version='1.0'?><stream:stream xmlns='jabber:client' "+
+				"xmlns:stream='http://etherx.jabber.org/streams' id='1' version='1.0'>"+
+				"<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'>"+
+				"<required/></starttls></stream:features>",
+		)
+
+		if _, err := readUntil(conn, "<starttls"); err != nil {
+			return false
+		}
+
+		return reply(
+			"<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>",
+			"<failure xmlns='urn:ietf:params:xml:ns:xmpp-tls'/></stream:stream>",
+		)
+
+	case STARTTLS_POSTGRES:
+		request := make([]byte, len(postgresSSLRequest))
+
+		if _, err := io.ReadFull(conn, request); err != nil || string(request) != string(postgresSSLRequest) {
+			return false
+		}
+
+		return reply("S", "N")
+	}
+
+	return false
+}
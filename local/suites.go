@@ -0,0 +1,552 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	KX_ECDH = "ECDH"
+	KX_DH   = "DH"
+	KX_RSA  = "RSA"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// suiteInfo contains info about cipher suite
+type suiteInfo struct {
+	ID       uint16
+	Name     string
+	Strength int // cipher strength in bits
+}
+
+// dhParams contains DH key exchange params
+type dhParams struct {
+	P, G, Ys []byte
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// suites is catalogue of known cipher suites
+var suites = []*suiteInfo{
+	// TLS 1.3
+	{0x1301, "TLS_AES_128_GCM_SHA256", 128},
+	{0x1302, "TLS_AES_256_GCM_SHA384", 256},
+	{0x1303, "TLS_CHACHA20_POLY1305_SHA256", 256},
+	{0x1304, "TLS_AES_128_CCM_SHA256", 128},
+	{0x1305, "TLS_AES_128_CCM_8_SHA256", 128},
+
+	// ECDHE
+	{0xc02c, "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", 256},
+	{0xc02b, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", 128},
+	{0xc030, "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", 256},
+	{0xc02f, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", 128},
+	{0xcca9, "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256", 256},
+	{0xcca8, "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256", 256},
+	{0xc024, "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384", 256},
+	{0xc023, "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256", 128},
+	{0xc028, "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384", 256},
+	{0xc027, "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256", 128},
+	{0xc00a, "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", 256},
+	{0xc009, "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", 128},
+	{0xc014, "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA", 256},
+	{0xc013, "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", 128},
+	{0xc008, "TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA", 112},
+	{0xc012, "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA", 112},
+	{0xc007, "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA", 128},
+	{0xc011, "TLS_ECDHE_RSA_WITH_RC4_128_SHA", 128},
+	{0xc006, "TLS_ECDHE_ECDSA_WITH_NULL_SHA", 0},
+	{0xc010, "TLS_ECDHE_RSA_WITH_NULL_SHA", 0},
+
+	// DHE
+	{0x009f, "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384", 256},
+	{0x009e, "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256", 128},
+	{0xccaa, "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256", 256},
+	{0x006b, "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256", 256},
+	{0x0067, "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256", 128},
+	{0x0039, "TLS_DHE_RSA_WITH_AES_256_CBC_SHA", 256},
+	{0x0033, "TLS_DHE_RSA_WITH_AES_128_CBC_SHA", 128},
+	{0x0088, "TLS_DHE_RSA_WITH_CAMELLIA_256_CBC_SHA", 256},
+	{0x0045, "TLS_DHE_RSA_WITH_CAMELLIA_128_CBC_SHA", 128},
+	{0x0016, "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA", 112},
+	{0x0015, "TLS_DHE_RSA_WITH_DES_CBC_SHA", 56},
+	{0x0014, "TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA", 40},
+
+	// RSA
+	{0x009d, "TLS_RSA_WITH_AES_256_GCM_SHA384", 256},
+	{0x009c, "TLS_RSA_WITH_AES_128_GCM_SHA256", 128},
+	{0x003d, "TLS_RSA_WITH_AES_256_CBC_SHA256", 256},
+	{0x003c, "TLS_RSA_WITH_AES_128_CBC_SHA256", 128},
+	{0x0035, "TLS_RSA_WITH_AES_256_CBC_SHA", 256},
+	{0x002f, "TLS_RSA_WITH_AES_128_CBC_SHA", 128},
+	{0x0084, "TLS_RSA_WITH_CAMELLIA_256_CBC_SHA", 256},
+	{0x0041, "TLS_RSA_WITH_CAMELLIA_128_CBC_SHA", 128},
+	{0x0007, "TLS_RSA_WITH_IDEA_CBC_SHA", 128},
+	{0x000a, "TLS_RSA_WITH_3DES_EDE_CBC_SHA", 112},
+	{0x0005, "TLS_RSA_WITH_RC4_128_SHA", 128},
+	{0x0004, "TLS_RSA_WITH_RC4_128_MD5", 128},
+	{0x0009, "TLS_RSA_WITH_DES_CBC_SHA", 56},
+	{0x0008, "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA", 40},
+	{0x0003, "TLS_RSA_EXPORT_WITH_RC4_40_MD5", 40},
+	{0x003b, "TLS_RSA_WITH_NULL_SHA256", 0},
+	{0x0002, "TLS_RSA_WITH_NULL_SHA", 0},
+	{0x0001, "TLS_RSA_WITH_NULL_MD5", 0},
+
+	// Anonymous
+	{0xc019, "TLS_ECDH_anon_WITH_AES_256_CBC_SHA", 256},
+	{0xc018, "TLS_ECDH_anon_WITH_AES_128_CBC_SHA", 128},
+	{0x003a, "TLS_DH_anon_WITH_AES_256_CBC_SHA", 256},
+	{0x0034, "TLS_DH_anon_WITH_AES_128_CBC_SHA", 128},
+	{0x0018, "TLS_DH_anon_WITH_RC4_128_MD5", 128},
+}
+
+// insecureMarkers contains parts of names of insecure cipher suites
+var insecureMarkers = []string{"_anon_", "_NULL_", "EXPORT", "_DES_", "DES40", "RC4", "_MD5"}
+
+// suiteRanges contains ranges of IANA cipher suites registry which are offered
+// if server doesn't accept any suite from catalogue
+var suiteRanges = [][2]uint16{
+	{0x0001, 0x00fe}, // 0x00ff is renegotiation SCSV
+	{0x1301, 0x13ff},
+	{0xc001, 0xc0ff},
+	{0xcc01, 0xccff},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeSuites enumerates cipher suites accepted by server for every
+// supported protocol and checks if server enforces its own suites order
+func (s *Scanner) ProbeSuites(t *Target, d *sslscan.EndpointDetails) error {
+	d.Suites, d.ChaCha20Preference = nil, false
+
+	for _, protocol := range d.Protocols {
+		ps, err := s.probeProtocolSuites(t, protocol.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if len(ps.List) == 0 {
+			continue
+		}
+
+		d.Suites = append(d.Suites, ps)
+
+		if ps.Preference && !d.ChaCha20Preference {
+			d.ChaCha20Preference, err = s.hasChaCha20Preference(t, ps)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	updateSuitesSummary(d)
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// probeProtocolSuites enumerates cipher suites for given protocol. If server
+// doesn't accept any suite from catalogue, full IANA range is offered.
+func (s *Scanner) probeProtocolSuites(t *Target, version int) (*sslscan.ProtocolSuites, error) {
+	result := &sslscan.ProtocolSuites{Protocol: version}
+	accepted, err := s.enumerateSuites(t, version, getSuiteIDs(version), result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accepted) == 0 {
+		accepted, err = s.enumerateSuites(t, version, getRangeSuiteIDs(version), result)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(accepted) < 2 {
+		return result, nil
+	}
+
+	result.Preference, err = s.hasSuitePreference(t, version, accepted)
+
+	return result, err
+}
+
+// enumerateSuites removes accepted suite from offer until server refuses
+// handshake and returns accepted suites in server order
+func (s *Scanner) enumerateSuites(t *Target, version int, offered []uint16, ps *sslscan.ProtocolSuites) ([]uint16, error) {
+	var accepted []uint16
+
+	for len(offered) != 0 {
+		hello := newClientHello(version, t.Host)
+		hello.Suites = offered
+
+		sh, err := s.handshake(t, hello)
+
+		if err != nil {
+			if isDialError(err) {
+				return nil, err
+			}
+
+			break
+		}
+
+		if int(sh.Version) != version || !containsUint16(offered, sh.Suite) {
+			break
+		}
+
+		accepted = append(accepted, sh.Suite)
+		offered = removeUint16(offered, sh.Suite)
+		ps.List = append(ps.List, newSuite(sh))
+	}
+
+	return accepted, nil
+}
+
+// hasSuitePreference offers accepted suites in reverse order and returns
+// true if server ignores client preference
+func (s *Scanner) hasSuitePreference(t *Target, version int, accepted []uint16) (bool, error) {
+	hello := newClientHello(version, t.Host)
+	hello.Suites = reverseUint16(accepted)
+
+	sh, err := s.handshake(t, hello)
+
+	if err != nil {
+		if isDialError(err) {
+			return false, err
+		}
+
+		return false, nil
+	}
+
+	return sh.Suite != hello.Suites[0], nil
+}
+
+// hasChaCha20Preference returns true if server which enforces its own suites
+// order selects ChaCha20 suite when client prefers it
+func (s *Scanner) hasChaCha20Preference(t *Target, ps *sslscan.ProtocolSuites) (bool, error) {
+	var chacha, other []uint16
+
+	for _, suite := range ps.List {
+		if isChaCha20Suite(suite.Name) {
+			chacha = append(chacha, uint16(suite.ID))
+		} else {
+			other = append(other, uint16(suite.ID))
+		}
+	}
+
+	// Preference doesn't matter if server prefers ChaCha20 anyway
+	if len(chacha) == 0 || len(other) == 0 || isChaCha20Suite(ps.List[0].Name) {
+		return false, nil
+	}
+
+	hello := newClientHello(ps.Protocol, t.Host)
+	hello.Suites = append(chacha, other...)
+
+	sh, err := s.handshake(t, hello)
+
+	if err != nil {
+		if isDialError(err) {
+			return false, err
+		}
+
+		return false, nil
+	}
+
+	return containsUint16(chacha, sh.Suite), nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// newSuite creates suite info from server handshake
+func newSuite(sh *serverHello) *sslscan.Suite {
+	info := getSuiteInfo(sh.Suite)
+
+	suite := &sslscan.Suite{
+		ID:             int(info.ID),
+		Name:           info.Name,
+		CipherStrength: info.Strength,
+		KxType:         getKxType(info.Name),
+		Q:              getSuiteQ(info.Name),
+	}
+
+	group, dh := parseKeyExchange(suite.KxType, sh)
+
+	switch {
+	case group != 0:
+		g := getGroupInfo(group)
+		suite.NamedGroupID = int(g.ID)
+		suite.NamedGroupName = g.Name
+		suite.NamedGroupBits = g.Bits
+		suite.KxStrength = g.Strength
+
+		if suite.KxType == "" {
+			suite.KxType = KX_ECDH
+
+			if g.Type == GROUP_TYPE_DH {
+				suite.KxType = KX_DH
+			}
+		}
+
+	case dh != nil:
+		suite.DHP, suite.DHG, suite.DHYs = len(dh.P), len(dh.G), len(dh.Ys)
+		suite.DHBits = new(big.Int).SetBytes(dh.P).BitLen()
+		suite.KxStrength = suite.DHBits
+
+	case suite.KxType == KX_RSA:
+		suite.KxStrength = getCertKeySize(sh.Certificates)
+	}
+
+	return suite
+}
+
+// updateSuitesSummary updates summary info about supported suites
+func updateSuitesSummary(d *sslscan.EndpointDetails) {
+	var total, rc4 int
+
+	d.SupportsRC4, d.SupportAEAD, d.SupportsCBC = false, false, false
+
+	for _, ps := range d.Suites {
+		for _, suite := range ps.List {
+			total++
+
+			switch {
+			case strings.Contains(suite.Name, "RC4"):
+				rc4++
+				d.SupportsRC4 = true
+			case strings.Contains(suite.Name, "_CBC_"):
+				d.SupportsCBC = true
+			case strings.Contains(suite.Name, "GCM"),
+				isChaCha20Suite(suite.Name),
+				strings.Contains(suite.Name, "CCM"):
+				d.SupportAEAD = true
+			}
+		}
+	}
+
+	d.RC4Only = total != 0 && rc4 == total
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseKeyExchange returns named group or DH params used for key exchange
+func parseKeyExchange(kx string, sh *serverHello) (uint16, *dhParams) {
+	if sh.Group != 0 {
+		return sh.Group, nil
+	}
+
+	p := &parser{data: sh.ServerKeyExchange}
+
+	switch kx {
+	case KX_ECDH:
+		// Only named curves are supported
+		if p.Uint8() != 3 {
+			return 0, nil
+		}
+
+		return p.Uint16(), nil
+
+	case KX_DH:
+		dh := &dhParams{
+			P:  p.Bytes(int(p.Uint16())),
+			G:  p.Bytes(int(p.Uint16())),
+			Ys: p.Bytes(int(p.Uint16())),
+		}
+
+		if p.failed {
+			return 0, nil
+		}
+
+		return 0, dh
+	}
+
+	return 0, nil
+}
+
+// getSuiteInfo returns info about cipher suite with given ID
+func getSuiteInfo(id uint16) *suiteInfo {
+	for _, suite := range suites {
+		if suite.ID == id {
+			return suite
+		}
+	}
+
+	return &suiteInfo{ID: id, Name: fmt.Sprintf("0x%04x", id)}
+}
+
+// getSuiteIDs returns IDs of all known suites for given protocol
+func getSuiteIDs(version int) []uint16 {
+	var result []uint16
+
+	for _, suite := range suites {
+		if isTLS13Suite(suite.ID) == (version == sslscan.PROTOCOL_TLS13) {
+			result = append(result, suite.ID)
+		}
+	}
+
+	return result
+}
+
+// getRangeSuiteIDs returns IDs of all suites from IANA registry ranges which
+// are not in catalogue
+func getRangeSuiteIDs(version int) []uint16 {
+	var result []uint16
+
+	for _, r := range suiteRanges {
+		for id := int(r[0]); id <= int(r[1]); id++ {
+			if isTLS13Suite(uint16(id)) != (version == sslscan.PROTOCOL_TLS13) {
+				continue
+			}
+
+			if !isKnownSuite(uint16(id)) {
+				result = append(result, uint16(id))
+			}
+		}
+	}
+
+	return result
+}
+
+// getKxSuiteIDs returns IDs of all known TLS 1.2 and older suites with
+// given key exchange type
+func getKxSuiteIDs(kx string) []uint16 {
+	var result []uint16
+
+	for _, suite := range suites {
+		if getKxType(suite.Name) == kx {
+			result = append(result, suite.ID)
+		}
+	}
+
+	return result
+}
+
+// getKxType returns key exchange type for cipher suite with given name. For
+// TLS 1.3 suites key exchange type depends on negotiated group.
+func getKxType(name string) string {
+	switch {
+	case strings.HasPrefix(name, "TLS_ECDHE_"), strings.HasPrefix(name, "TLS_ECDH_anon_"):
+		return KX_ECDH
+	case strings.HasPrefix(name, "TLS_DHE_"), strings.HasPrefix(name, "TLS_DH_anon_"):
+		return KX_DH
+	case strings.HasPrefix(name, "TLS_RSA_"):
+		return KX_RSA
+	}
+
+	return ""
+}
+
+// getSuiteQ returns 0 for insecure suites, 1 for weak suites and nil otherwise
+func getSuiteQ(name string) *int {
+	for _, marker := range insecureMarkers {
+		if strings.Contains(name, marker) {
+			return new(int)
+		}
+	}
+
+	// CBC suites and suites without forward secrecy are considered weak
+	if strings.Contains(name, "_CBC_") || getKxType(name) == KX_RSA {
+		q := 1
+		return &q
+	}
+
+	return nil
+}
+
+// getCertKeySize returns size of leaf certificate key
+func getCertKeySize(certs [][]byte) int {
+	if len(certs) == 0 {
+		return 0
+	}
+
+	cert, err := x509.ParseCertificate(certs[0])
+
+	if err != nil {
+		return 0
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	}
+
+	return 0
+}
+
+// isTLS13Suite returns true if given suite can be used only with TLS 1.3
+func isTLS13Suite(id uint16) bool {
+	return id>>8 == 0x13
+}
+
+// isKnownSuite returns true if suite with given ID is in catalogue
+func isKnownSuite(id uint16) bool {
+	for _, suite := range suites {
+		if suite.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isChaCha20Suite returns true if suite with given name uses ChaCha20 cipher
+func isChaCha20Suite(name string) bool {
+	return strings.Contains(name, "CHACHA20")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// containsUint16 returns true if slice contains given value
+func containsUint16(list []uint16, v uint16) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeUint16 returns copy of slice without given value
+func removeUint16(list []uint16, v uint16) []uint16 {
+	var result []uint16
+
+	for _, item := range list {
+		if item != v {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// reverseUint16 returns reversed copy of slice
+func reverseUint16(list []uint16) []uint16 {
+	result := make([]uint16, len(list))
+
+	for i, item := range list {
+		result[len(list)-1-i] = item
+	}
+
+	return result
+}
@@ -0,0 +1,177 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestSuites(c *check.C) {
+	srv := startServer(c, &tls.Config{
+		Certificates: []tls.Certificate{s.cert},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		},
+		MinVersion: tls.VersionTLS12,
+	})
+
+	defer srv.Close()
+
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+
+	c.Assert(scanner.ProbeProtocols(srv.Target(), details), check.IsNil)
+	c.Assert(scanner.ProbeSuites(srv.Target(), details), check.IsNil)
+	c.Assert(details.Suites, check.HasLen, 2)
+
+	ps := details.Suites[0]
+
+	c.Assert(ps.Protocol, check.Equals, sslscan.PROTOCOL_TLS12)
+	c.Assert(ps.Preference, check.Equals, true)
+	c.Assert(ps.List, check.HasLen, 2)
+	c.Assert(ps.List[0].Name, check.Equals, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256")
+	c.Assert(ps.List[0].CipherStrength, check.Equals, 128)
+	c.Assert(ps.List[0].KxType, check.Equals, KX_ECDH)
+	c.Assert(ps.List[0].KxStrength, check.Equals, 3072)
+	c.Assert(ps.List[0].NamedGroupName, check.Equals, "x25519")
+	c.Assert(ps.List[0].Q, check.IsNil)
+	c.Assert(ps.List[1].Name, check.Equals, "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA")
+	c.Assert(*ps.List[1].Q, check.Equals, 1)
+
+	ps = details.Suites[1]
+
+	c.Assert(ps.Protocol, check.Equals, sslscan.PROTOCOL_TLS13)
+	c.Assert(ps.List, check.HasLen, 3)
+	c.Assert(ps.List[0].KxType, check.Equals, KX_ECDH)
+	c.Assert(ps.List[0].NamedGroupName, check.Equals, "x25519")
+
+	c.Assert(details.SupportAEAD, check.Equals, true)
+	c.Assert(details.SupportsCBC, check.Equals, true)
+	c.Assert(details.SupportsRC4, check.Equals, false)
+}
+
+func (s *LocalSuite) TestSuitesClientOrder(c *check.C) {
+	srv := startFakeServer(c, &fakeConfig{
+		Versions:    []uint16{sslscan.PROTOCOL_TLS12},
+		Suites:      []uint16{0x0005, 0xc030, 0x002f},
+		Group:       24,
+		ClientOrder: true,
+		Cert:        s.cert.Certificate[0],
+	})
+
+	defer srv.Close()
+
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+
+	c.Assert(scanner.ProbeProtocols(srv.Target(), details), check.IsNil)
+	c.Assert(getProtocolIDs(details.Protocols), check.DeepEquals, []int{sslscan.PROTOCOL_TLS12})
+	c.Assert(scanner.ProbeSuites(srv.Target(), details), check.IsNil)
+	c.Assert(details.Suites, check.HasLen, 1)
+
+	ps := details.Suites[0]
+
+	c.Assert(ps.Preference, check.Equals, false)
+	c.Assert(ps.List, check.HasLen, 3)
+	c.Assert(ps.List[0].Name, check.Equals, "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
+	c.Assert(ps.List[0].NamedGroupName, check.Equals, "secp384r1")
+	c.Assert(ps.List[0].NamedGroupBits, check.Equals, 384)
+	c.Assert(ps.List[0].KxStrength, check.Equals, 7680)
+	c.Assert(ps.List[1].Name, check.Equals, "TLS_RSA_WITH_AES_128_CBC_SHA")
+	c.Assert(ps.List[1].KxType, check.Equals, KX_RSA)
+	c.Assert(ps.List[1].KxStrength, check.Equals, 256)
+	c.Assert(*ps.List[1].Q, check.Equals, 1)
+	c.Assert(ps.List[2].Name, check.Equals, "TLS_RSA_WITH_RC4_128_SHA")
+	c.Assert(*ps.List[2].Q, check.Equals, 0)
+
+	c.Assert(details.SupportsRC4, check.Equals, true)
+	c.Assert(details.RC4Only, check.Equals, false)
+}
+
+func (s *LocalSuite) TestSuitesFallback(c *check.C) {
+	srv := startFakeServer(c, &fakeConfig{
+		Versions: []uint16{sslscan.PROTOCOL_TLS12},
+		Suites:   []uint16{0x00c4, 0xc0ad},
+		Group:    29,
+		Cert:     s.cert.Certificate[0],
+	})
+
+	defer srv.Close()
+
+	details := &sslscan.EndpointDetails{}
+	scanner := newTestScanner(srv)
+
+	c.Assert(scanner.ProbeProtocols(srv.Target(), details), check.IsNil)
+	c.Assert(scanner.ProbeSuites(srv.Target(), details), check.IsNil)
+	c.Assert(details.Suites, check.HasLen, 1)
+
+	ps := details.Suites[0]
+
+	c.Assert(ps.Preference, check.Equals, true)
+	c.Assert(ps.List, check.HasLen, 2)
+	c.Assert(ps.List[0].ID, check.Equals, 0x00c4)
+	c.Assert(ps.List[0].Name, check.Equals, "0x00c4")
+	c.Assert(ps.List[1].Name, check.Equals, "0xc0ad")
+}
+
+func (s *LocalSuite) TestSuitesChaCha20Preference(c *check.C) {
+	config := &fakeConfig{
+		Versions: []uint16{sslscan.PROTOCOL_TLS12},
+		Suites:   []uint16{0xc030, 0xcca8, 0xc02f},
+		Group:    29,
+		Cert:     s.cert.Certificate[0],
+	}
+
+	for _, chacha := range []bool{false, true} {
+		config.ChaCha20 = chacha
+		srv := startFakeServer(c, config)
+
+		details := &sslscan.EndpointDetails{}
+		scanner := newTestScanner(srv)
+
+		c.Assert(scanner.ProbeProtocols(srv.Target(), details), check.IsNil)
+		c.Assert(scanner.ProbeSuites(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.Suites, check.HasLen, 1)
+		c.Assert(details.Suites[0].Preference, check.Equals, true)
+		c.Assert(details.ChaCha20Preference, check.Equals, chacha)
+	}
+}
+
+func (s *LocalSuite) TestSuiteHelpers(c *check.C) {
+	c.Assert(getSuiteInfo(0xfefe).Name, check.Equals, "0xfefe")
+	c.Assert(getKxType("TLS_DH_anon_WITH_AES_128_CBC_SHA"), check.Equals, KX_DH)
+	c.Assert(getKxType("TLS_AES_128_GCM_SHA256"), check.Equals, "")
+	c.Assert(*getSuiteQ("TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA"), check.Equals, 0)
+	c.Assert(*getSuiteQ("TLS_RSA_WITH_DES_CBC_SHA"), check.Equals, 0)
+	c.Assert(*getSuiteQ("TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"), check.Equals, 1)
+	c.Assert(getSuiteQ("TLS_CHACHA20_POLY1305_SHA256"), check.IsNil)
+	c.Assert(getSuiteIDs(sslscan.PROTOCOL_TLS13), check.HasLen, 5)
+	c.Assert(getRangeSuiteIDs(sslscan.PROTOCOL_TLS13), check.HasLen, 250)
+	c.Assert(containsUint16(getRangeSuiteIDs(sslscan.PROTOCOL_TLS12), 0x00ff), check.Equals, false)
+	c.Assert(containsUint16(getRangeSuiteIDs(sslscan.PROTOCOL_TLS12), 0xc030), check.Equals, false)
+
+	group, dh := parseKeyExchange(KX_DH, &serverHello{ServerKeyExchange: []byte{0, 2, 0xff, 0xff, 0, 1, 2, 0, 1, 5}})
+
+	c.Assert(group, check.Equals, uint16(0))
+	c.Assert(dh, check.NotNil)
+	c.Assert(dh.P, check.DeepEquals, []byte{0xff, 0xff})
+
+	group, dh = parseKeyExchange(KX_DH, &serverHello{ServerKeyExchange: []byte{0, 9}})
+
+	c.Assert(dh, check.IsNil)
+}
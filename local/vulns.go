@@ -0,0 +1,164 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Heartbeat message types and modes
+const (
+	HEARTBEAT_REQUEST  = 1
+	HEARTBEAT_RESPONSE = 2
+
+	HEARTBEAT_MODE_PEER_ALLOWED = 1
+)
+
+// Alert descriptions
+const (
+	ALERT_UNEXPECTED_MESSAGE = 10
+	ALERT_BAD_RECORD_MAC     = 20
+	ALERT_DECRYPTION_FAILED  = 21
+)
+
+// HEARTBLEED_PAYLOAD_SIZE is payload size declared in malformed heartbeat
+// request. It's small, so vulnerable server leaks only few bytes of memory.
+const HEARTBLEED_PAYLOAD_SIZE = 64
+
+// HEARTBEAT_TIMEOUT is max time of waiting for heartbeat response
+const HEARTBEAT_TIMEOUT = 3 * time.Second
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ProbeHeartbleed checks if server supports heartbeat extension and is
+// vulnerable to Heartbleed (CVE-2014-0160)
+func (s *Scanner) ProbeHeartbleed(t *Target, d *sslscan.EndpointDetails) error {
+	d.Heartbeat, d.Heartbleed = false, false
+
+	version := getMaxLegacyProtocol(d.Protocols)
+
+	if version == 0 {
+		return nil
+	}
+
+	hello := newClientHello(version, t.Host)
+	hello.Extensions = []extension{{Type: EXT_HEARTBEAT, Data: []byte{HEARTBEAT_MODE_PEER_ALLOWED}}}
+
+	conn, sh, err := s.startHandshake(t, hello)
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	defer conn.Close()
+
+	_, d.Heartbeat = sh.Extensions[EXT_HEARTBEAT]
+
+	// Vulnerable servers always negotiate heartbeat extension
+	if !d.Heartbeat {
+		return nil
+	}
+
+	// Request declares payload which is longer than actually sent. Patched
+	// servers silently discard such requests, vulnerable servers reply with
+	// data from memory.
+	request := []byte{HEARTBEAT_REQUEST, 0, HEARTBLEED_PAYLOAD_SIZE}
+
+	_, err = conn.Write(marshalRecord(RECORD_HEARTBEAT, sh.Version, request))
+
+	if err != nil {
+		return nil
+	}
+
+	conn.SetReadDeadline(time.Now().Add(s.getHeartbeatTimeout()))
+
+	typ, data, err := readRecord(conn)
+
+	if err == nil && typ == RECORD_HEARTBEAT && len(data) != 0 && data[0] == HEARTBEAT_RESPONSE {
+		d.Heartbleed = true
+	}
+
+	return nil
+}
+
+// ProbeCCSInjection checks if server is vulnerable to OpenSSL CCS injection
+// (CVE-2014-0224)
+func (s *Scanner) ProbeCCSInjection(t *Target, d *sslscan.EndpointDetails) error {
+	d.OpenSSLCCS = sslscan.SSLCSC_STATUS_UNKNOWN
+
+	version := getMaxLegacyProtocol(d.Protocols)
+
+	if version == 0 {
+		return nil
+	}
+
+	conn, sh, err := s.startHandshake(t, newClientHello(version, t.Host))
+
+	if err != nil {
+		if isDialError(err) {
+			return err
+		}
+
+		d.OpenSSLCCS = sslscan.SSLCSC_STATUS_FAILED
+
+		return nil
+	}
+
+	defer conn.Close()
+
+	// Early ChangeCipherSpec is followed by record which server can't
+	// decrypt. Patched servers reject CCS with unexpected_message alert,
+	// vulnerable servers accept it and fail on decryption. Without the key
+	// exchange we can't prove that CCS was really used for record decryption,
+	// so decryption failure only marks server as possibly vulnerable.
+	data := marshalRecord(RECORD_CHANGE_CIPHER_SPEC, sh.Version, []byte{1})
+	data = append(data, marshalRecord(RECORD_HANDSHAKE, sh.Version, randomBytes(48))...)
+
+	_, err = conn.Write(data)
+
+	if err != nil {
+		d.OpenSSLCCS = sslscan.SSLCSC_STATUS_FAILED
+		return nil
+	}
+
+	typ, alert, err := readRecord(conn)
+
+	if err != nil || typ != RECORD_ALERT || len(alert) < 2 {
+		return nil
+	}
+
+	switch alert[1] {
+	case ALERT_BAD_RECORD_MAC, ALERT_DECRYPTION_FAILED:
+		d.OpenSSLCCS = sslscan.SSLCSC_STATUS_POSSIBLE_VULNERABLE
+	default:
+		d.OpenSSLCCS = sslscan.SSLCSC_STATUS_NOT_VULNERABLE
+	}
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getHeartbeatTimeout returns timeout for heartbeat response
+func (s *Scanner) getHeartbeatTimeout() time.Duration {
+	timeout := s.getTimeout()
+
+	if timeout > HEARTBEAT_TIMEOUT {
+		return HEARTBEAT_TIMEOUT
+	}
+
+	return timeout
+}
@@ -0,0 +1,123 @@
+package local
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *LocalSuite) TestHeartbleed(c *check.C) {
+	cases := []struct {
+		heartbeat, heartbleed bool
+	}{
+		{false, false},
+		{true, false},
+		{true, true},
+	}
+
+	for _, tc := range cases {
+		srv := startFakeServer(c, &fakeConfig{
+			Versions:   []uint16{sslscan.PROTOCOL_TLS12},
+			Suites:     []uint16{0xc02f},
+			Group:      23,
+			Cert:       s.cert.Certificate[0],
+			Heartbeat:  tc.heartbeat,
+			Heartbleed: tc.heartbleed,
+		})
+
+		details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS12)}}
+
+		c.Assert(newTestScanner(srv).ProbeHeartbleed(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.Heartbeat, check.Equals, tc.heartbeat)
+		c.Assert(details.Heartbleed, check.Equals, tc.heartbleed)
+	}
+
+	details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS13)}}
+
+	c.Assert(New().ProbeHeartbleed(&Target{}, details), check.IsNil)
+	c.Assert(details.Heartbeat, check.Equals, false)
+
+	details = &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS12)}}
+	srv := startServer(c, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+	target := srv.Target()
+
+	c.Assert(newTestScanner(srv).ProbeHeartbleed(target, details), check.IsNil)
+	c.Assert(details.Heartbeat, check.Equals, false)
+	c.Assert(details.Heartbleed, check.Equals, false)
+
+	srv.Close()
+
+	c.Assert(newTestScanner(srv).ProbeHeartbleed(target, details), check.NotNil)
+
+	scanner := &Scanner{Timeout: time.Second}
+	c.Assert(scanner.getHeartbeatTimeout(), check.Equals, time.Second)
+	scanner.Timeout = time.Minute
+	c.Assert(scanner.getHeartbeatTimeout(), check.Equals, HEARTBEAT_TIMEOUT)
+}
+
+func (s *LocalSuite) TestCCSInjection(c *check.C) {
+	cases := []struct {
+		accept   bool
+		expected int
+	}{
+		{false, sslscan.SSLCSC_STATUS_NOT_VULNERABLE},
+		{true, sslscan.SSLCSC_STATUS_POSSIBLE_VULNERABLE},
+	}
+
+	for _, tc := range cases {
+		srv := startFakeServer(c, &fakeConfig{
+			Versions:  []uint16{sslscan.PROTOCOL_TLS12},
+			Suites:    []uint16{0xc02f},
+			Group:     23,
+			Cert:      s.cert.Certificate[0],
+			AcceptCCS: tc.accept,
+		})
+
+		details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS12)}}
+
+		c.Assert(newTestScanner(srv).ProbeCCSInjection(srv.Target(), details), check.IsNil)
+
+		srv.Close()
+
+		c.Assert(details.OpenSSLCCS, check.Equals, tc.expected)
+	}
+
+	srv := startServer(c, &tls.Config{Certificates: []tls.Certificate{s.cert}})
+	target := srv.Target()
+
+	details := &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS12)}}
+
+	c.Assert(newTestScanner(srv).ProbeCCSInjection(target, details), check.IsNil)
+	c.Assert(details.OpenSSLCCS, check.Equals, sslscan.SSLCSC_STATUS_NOT_VULNERABLE)
+
+	srv.Close()
+
+	c.Assert(newTestScanner(srv).ProbeCCSInjection(target, details), check.NotNil)
+
+	// Server doesn't support any of offered suites
+	srv = startFakeServer(c, &fakeConfig{Versions: []uint16{sslscan.PROTOCOL_TLS12}, Suites: []uint16{0xffff}})
+	defer srv.Close()
+
+	c.Assert(newTestScanner(srv).ProbeCCSInjection(srv.Target(), details), check.IsNil)
+	c.Assert(details.OpenSSLCCS, check.Equals, sslscan.SSLCSC_STATUS_FAILED)
+
+	details = &sslscan.EndpointDetails{Protocols: []*sslscan.Protocol{newProtocol(sslscan.PROTOCOL_TLS13)}}
+
+	c.Assert(New().ProbeCCSInjection(&Target{}, details), check.IsNil)
+	c.Assert(details.OpenSSLCCS, check.Equals, sslscan.SSLCSC_STATUS_UNKNOWN)
+}
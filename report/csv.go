@@ -0,0 +1,354 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	PATH_ROOT_INFO     = "info"     // path into AnalyzeInfo
+	PATH_ROOT_ENDPOINT = "endpoint" // path into EndpointInfo
+	PATH_ROOT_DETAILS  = "details"  // path into EndpointDetails
+	PATH_ROOT_CERT     = "cert"     // path into leaf Cert
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Column is column of flat export
+type Column struct {
+	Name string // column header
+	Path string // dotted path to value (e.g. "details.hstsPolicy.maxAge")
+}
+
+// csvRow contains data for one row
+type csvRow struct {
+	info     *sslscan.AnalyzeInfo
+	endpoint *sslscan.EndpointInfo
+	cert     *sslscan.Cert
+	opts     *Options
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// csvRoots contains types of path roots
+var csvRoots = map[string]reflect.Type{
+	PATH_ROOT_INFO:     reflect.TypeOf(sslscan.AnalyzeInfo{}),
+	PATH_ROOT_ENDPOINT: reflect.TypeOf(sslscan.EndpointInfo{}),
+	PATH_ROOT_DETAILS:  reflect.TypeOf(sslscan.EndpointDetails{}),
+	PATH_ROOT_CERT:     reflect.TypeOf(sslscan.Cert{}),
+}
+
+// csvVirtualColumns contains computed columns
+var csvVirtualColumns = map[string]func(r *csvRow) string{
+	"protocols": func(r *csvRow) string {
+		if r.endpoint == nil || r.endpoint.Details == nil {
+			return ""
+		}
+
+		return joinCells(protocolsCells(r.endpoint.Details), " ")
+	},
+	"vulns": func(r *csvRow) string {
+		if r.endpoint == nil || r.endpoint.Details == nil {
+			return ""
+		}
+
+		var result []string
+
+		for _, vuln := range sslscan.FindVulnerabilities(r.endpoint.Details) {
+			result = append(result, vuln.Name)
+		}
+
+		return strings.Join(result, " ")
+	},
+	"cert.issuer": func(r *csvRow) string {
+		if r.cert == nil {
+			return ""
+		}
+
+		return getIssuerName(r.cert)
+	},
+	"cert.daysToExpiry": func(r *csvRow) string {
+		if r.cert == nil {
+			return ""
+		}
+
+		return strconv.Itoa(int(msToTime(r.cert.NotAfter).Sub(r.opts.Now).Hours() / 24))
+	},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DefaultColumns returns default set of columns for flat export
+func DefaultColumns() []*Column {
+	columns := []*Column{
+		{"host", "info.host"},
+		{"port", "info.port"},
+		{"status", "info.status"},
+		{"ip", "endpoint.ipAddress"},
+		{"grade", "endpoint.grade"},
+		{"protocols", "protocols"},
+		{"forwardSecrecy", "details.forwardSecrecy"},
+		{"hstsMaxAge", "details.hstsPolicy.maxAge"},
+		{"certIssuer", "cert.issuer"},
+		{"certDaysToExpiry", "cert.daysToExpiry"},
+	}
+
+	for _, vuln := range sslscan.CheckVulnerabilities(&sslscan.EndpointDetails{}) {
+		columns = append(columns, &Column{vuln.Name, "details." + vuln.Name})
+	}
+
+	return columns
+}
+
+// ParseColumns parses comma-separated list of columns. Every column is
+// dotted path (e.g. "details.hstsPolicy.maxAge") with optional header
+// name ("hsts=details.hstsPolicy.maxAge").
+func ParseColumns(spec string) ([]*Column, error) {
+	var result []*Column
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		column := &Column{Name: item, Path: item}
+
+		if strings.Contains(item, "=") {
+			column.Name = strings.TrimSpace(item[:strings.Index(item, "=")])
+			column.Path = strings.TrimSpace(item[strings.Index(item, "=")+1:])
+		}
+
+		err := checkColumnPath(column.Path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, column)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("List of columns is empty")
+	}
+
+	return result, nil
+}
+
+// WriteCSV writes one row per endpoint as CSV
+func WriteCSV(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options) error {
+	return writeFlat(w, infos, getOptions(opts), ',')
+}
+
+// WriteTSV writes one row per endpoint as TSV
+func WriteTSV(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options) error {
+	return writeFlat(w, infos, getOptions(opts), '\t')
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// writeFlat writes one row per endpoint with given separator
+func writeFlat(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options, sep rune) error {
+	columns := opts.Columns
+
+	if len(columns) == 0 {
+		columns = DefaultColumns()
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+
+	var header []string
+
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+
+	cw.Write(header)
+
+	for _, info := range infos {
+		if len(info.Endpoints) == 0 {
+			cw.Write(getFlatRow(&csvRow{info: info, opts: opts}, columns))
+			continue
+		}
+
+		for _, endpoint := range info.Endpoints {
+			row := &csvRow{info: info, endpoint: endpoint, opts: opts}
+
+			if certs := LeafCerts(info, endpoint); len(certs) != 0 {
+				row.cert = certs[0]
+			}
+
+			cw.Write(getFlatRow(row, columns))
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// getFlatRow returns values of all columns for row
+func getFlatRow(row *csvRow, columns []*Column) []string {
+	var result []string
+
+	for _, column := range columns {
+		result = append(result, getColumnValue(row, column.Path))
+	}
+
+	return result
+}
+
+// getColumnValue returns value of column for row
+func getColumnValue(row *csvRow, path string) string {
+	if fn := csvVirtualColumns[path]; fn != nil {
+		return fn(row)
+	}
+
+	parts := strings.Split(path, ".")
+
+	var root interface{}
+
+	switch parts[0] {
+	case PATH_ROOT_INFO:
+		root = row.info
+	case PATH_ROOT_ENDPOINT:
+		root = row.endpoint
+	case PATH_ROOT_DETAILS:
+		if row.endpoint != nil {
+			root = row.endpoint.Details
+		}
+	case PATH_ROOT_CERT:
+		root = row.cert
+	}
+
+	return strings.Join(resolvePath(reflect.ValueOf(root), parts[1:]), " ")
+}
+
+// resolvePath returns all values found by path
+func resolvePath(v reflect.Value, path []string) []string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var result []string
+
+		for i := 0; i < v.Len(); i++ {
+			result = append(result, resolvePath(v.Index(i), path)...)
+		}
+
+		return result
+
+	case reflect.Struct:
+		if len(path) == 0 {
+			return nil
+		}
+
+		index := findFieldIndex(v.Type(), path[0])
+
+		if index < 0 {
+			return nil
+		}
+
+		return resolvePath(v.Field(index), path[1:])
+
+	case reflect.Map:
+		if len(path) == 0 || v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+
+		return resolvePath(v.MapIndex(reflect.ValueOf(path[0]).Convert(v.Type().Key())), path[1:])
+	}
+
+	if len(path) != 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// checkColumnPath checks that path points to existing field
+func checkColumnPath(path string) error {
+	if csvVirtualColumns[path] != nil {
+		return nil
+	}
+
+	parts := strings.Split(path, ".")
+	t := csvRoots[parts[0]]
+
+	if t == nil {
+		return fmt.Errorf("Unknown column path \"%s\" (path must start with info, endpoint, details or cert)", path)
+	}
+
+	for _, part := range parts[1:] {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			index := findFieldIndex(t, part)
+
+			if index < 0 {
+				return fmt.Errorf("Unknown column path \"%s\" (unknown field \"%s\")", path, part)
+			}
+
+			t = t.Field(index).Type
+
+		case reflect.Map:
+			t = t.Elem()
+
+		default:
+			return fmt.Errorf("Unknown column path \"%s\" (\"%s\" is not an object)", path, part)
+		}
+	}
+
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Struct || t.Kind() == reflect.Map {
+		return fmt.Errorf("Column path \"%s\" points to object", path)
+	}
+
+	return nil
+}
+
+// findFieldIndex returns index of struct field with given JSON name
+func findFieldIndex(t reflect.Type, name string) int {
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+
+		if tag != "" && tag != "-" && strings.EqualFold(tag, name) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,77 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"encoding/csv"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ReportSuite) TestCSV(c *check.C) {
+	info := loadAssessment(c)
+	info.Endpoints[0].Details.Poodle = true
+
+	failed := &sslscan.AnalyzeInfo{Host: "unknown.com", Port: 443, Status: sslscan.STATUS_ERROR}
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(WriteCSV(buf, []*sslscan.AnalyzeInfo{info, failed}, testOptions), check.IsNil)
+
+	records, err := csv.NewReader(buf).ReadAll()
+
+	c.Assert(err, check.IsNil)
+	c.Assert(records, check.HasLen, 3)
+	c.Assert(records[0], check.HasLen, len(DefaultColumns()))
+	c.Assert(records[0][:10], check.DeepEquals, []string{
+		"host", "port", "status", "ip", "grade", "protocols",
+		"forwardSecrecy", "hstsMaxAge", "certIssuer", "certDaysToExpiry",
+	})
+	c.Assert(records[1][:11], check.DeepEquals, []string{
+		"essentialkaos.com", "443", "READY", "5.79.108.150", "A+", "TLS 1.2 TLS 1.3",
+		"4", "31536000", "Let's Encrypt Authority X3", "60", "false",
+	})
+	c.Assert(records[2][:6], check.DeepEquals, []string{"unknown.com", "443", "ERROR", "", "", ""})
+
+	poodle := -1
+
+	for i, name := range records[0] {
+		if name == "poodle" {
+			poodle = i
+		}
+	}
+
+	c.Assert(records[1][poodle], check.Equals, "true")
+
+	columns, err := ParseColumns("ip=endpoint.ipAddress, details.protocols.version, details.hstsPolicy.directives.max-age, cert.altNames, vulns, sims=details.sims.results.client.name")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(columns[0], check.DeepEquals, &Column{"ip", "endpoint.ipAddress"})
+	c.Assert(columns[1], check.DeepEquals, &Column{"details.protocols.version", "details.protocols.version"})
+
+	buf.Reset()
+
+	c.Assert(WriteTSV(buf, []*sslscan.AnalyzeInfo{info}, &Options{Columns: columns[:5]}), check.IsNil)
+	c.Assert(buf.String(), check.Equals, ""+
+		"ip\tdetails.protocols.version\tdetails.hstsPolicy.directives.max-age\tcert.altNames\tvulns\n"+
+		"5.79.108.150\t1.2 1.3\t31536000\tessentialkaos.com www.essentialkaos.com\tpoodle\n",
+	)
+
+	for _, spec := range []string{
+		"", "unknown.field", "details", "details.unknown",
+		"details.hstsPolicy", "details.grade.x", "cert.caaPolicy.caaRecords",
+	} {
+		_, err = ParseColumns(spec)
+		c.Assert(err, check.NotNil, check.Commentf("Spec %q must be invalid", spec))
+	}
+}
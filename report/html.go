@@ -0,0 +1,260 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// htmlReport contains data for HTML report template
+type htmlReport struct {
+	Title     string
+	Generated time.Time
+	Hosts     []*sslscan.AnalyzeInfo
+	Options   *Options
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// htmlFuncs contains functions for HTML report template
+var htmlFuncs = template.FuncMap{
+	"protocolName": sslscan.ProtocolName,
+	"certIssues":   CertIssues,
+	"chainIssues":  ChainIssues,
+	"findCert":     FindCert,
+	"certName":     CertName,
+	"join":         strings.Join,
+	"msTime": func(ms int64) string {
+		if ms <= 0 {
+			return "-"
+		}
+
+		return msToTime(ms).UTC().Format("2006-01-02 15:04:05 MST")
+	},
+	"gradeClass": func(grade string) string {
+		switch {
+		case grade == "":
+			return "grade-none"
+		case strings.HasPrefix(grade, "A"):
+			return "grade-a"
+		case grade == "B" || grade == "C":
+			return "grade-b"
+		}
+
+		return "grade-f"
+	},
+	"isInsecure": func(q *int) bool {
+		return q != nil && *q == 0
+	},
+	"isWeak": func(q *int) bool {
+		return q != nil && *q == 1
+	},
+	"failedChecks": func(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo, opts *Options) []*Check {
+		var result []*Check
+
+		for _, check := range getEndpointChecks(info, endpoint, opts) {
+			if !check.Passed && !check.Skipped {
+				result = append(result, check)
+			}
+		}
+
+		return result
+	},
+}
+
+// htmlTemplate is template for HTML report
+var htmlTemplate = template.Must(template.New("report").Funcs(htmlFuncs).Parse(HTML_TEMPLATE))
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// WriteHTML writes assessments results as self-contained HTML report
+func WriteHTML(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options) error {
+	opts = getOptions(opts)
+
+	var hosts []string
+
+	for _, info := range infos {
+		hosts = append(hosts, info.Host)
+	}
+
+	return htmlTemplate.Execute(w, &htmlReport{
+		Title:     "SSL Report: " + strings.Join(hosts, ", "),
+		Generated: opts.Now.UTC(),
+		Hosts:     infos,
+		Options:   opts,
+	})
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// HTML_TEMPLATE is template for HTML report
+const HTML_TEMPLATE = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="sslscan">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; font-size: 14px; color: #222; background: #f4f4f4; margin: 0; padding: 24px; }
+h1 { font-size: 24px; margin: 0 0 4px; }
+h2 { font-size: 20px; margin: 32px 0 8px; padding-bottom: 4px; border-bottom: 2px solid #ccc; }
+h3 { font-size: 16px; margin: 24px 0 8px; }
+h4 { font-size: 14px; margin: 16px 0 6px; }
+section { background: #fff; border: 1px solid #ddd; border-radius: 4px; padding: 16px 24px; margin-bottom: 24px; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 12px; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #eee; vertical-align: top; }
+th { background: #f0f0f0; font-weight: 600; }
+td.key { width: 240px; color: #555; }
+code, .mono { font-family: Menlo, Consolas, monospace; font-size: 12px; word-break: break-all; }
+.meta { color: #777; font-size: 12px; }
+.grade { display: inline-block; min-width: 48px; padding: 6px 8px; border-radius: 4px; color: #fff; font-size: 22px; font-weight: bold; text-align: center; }
+.grade-a { background: #7ed84c; }
+.grade-b { background: #ffa100; }
+.grade-f { background: #ec0000; }
+.grade-none { background: #999; }
+.ok { color: #2e7d32; }
+.bad { color: #c62828; font-weight: 600; }
+.weak { color: #ef6c00; }
+.problems { background: #fdecea; border-left: 4px solid #c62828; padding: 8px 12px; margin: 8px 0; }
+.problems li { margin: 2px 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">Generated at {{.Generated.Format "2006-01-02 15:04:05 MST"}} by sslscan</div>
+{{range $info := .Hosts}}
+<section id="host-{{$info.Host}}">
+<h2>{{$info.Host}}:{{$info.Port}}</h2>
+<div class="meta">Assessed at {{msTime $info.TestTime}} &middot; engine {{$info.EngineVersion}} &middot; criteria {{$info.CriteriaVersion}}</div>
+{{if eq $info.Status "ERROR"}}
+<div class="problems">Assessment failed: {{$info.StatusMessage}}</div>
+{{else}}
+<h3>Summary</h3>
+<table class="summary">
+<tr><th>Endpoint</th><th>Server name</th><th>Grade</th><th>Grade (trust ignored)</th><th>Status</th><th>Duration</th></tr>
+{{range $info.Endpoints}}
+<tr>
+<td class="mono">{{.IPAdress}}</td>
+<td>{{.ServerName}}</td>
+<td><span class="grade {{gradeClass .Grade}}">{{if .Grade}}{{.Grade}}{{else}}-{{end}}</span></td>
+<td>{{.GradeTrustIgnored}}</td>
+<td>{{.StatusMessage}}{{if .HasWarnings}} (has warnings){{end}}</td>
+<td>{{.Duration}} ms</td>
+</tr>
+{{end}}
+</table>
+{{range $endpoint := $info.Endpoints}}{{with $d := $endpoint.Details}}
+<h3 id="endpoint-{{$endpoint.IPAdress}}">Endpoint {{$endpoint.IPAdress}}</h3>
+{{with failedChecks $info $endpoint $.Options}}
+<ul class="problems">{{range .}}<li>{{.Name}}: {{.Message}}</li>{{end}}</ul>
+{{end}}
+
+<h4>Certificates</h4>
+{{range $i, $chain := $d.CertChains}}
+<table class="chain">
+<tr><th colspan="2">Chain #{{$i}}{{if $chain.NoSNI}} (No SNI){{end}} &middot; <span class="mono">{{$chain.ID}}</span></th></tr>
+<tr><td class="key">Chain issues</td><td>{{with chainIssues $chain.Issues}}<span class="bad">{{join . ", "}}</span>{{else}}<span class="ok">None</span>{{end}}</td></tr>
+{{range $j, $id := $chain.CertIDs}}{{with $cert := findCert $info $id}}
+<tr><td class="key">#{{$j}} {{certName $cert}}</td><td>
+Subject: {{$cert.Subject}}<br>
+Issuer: {{$cert.IssuerSubject}}<br>
+Valid: {{msTime $cert.NotBefore}} &mdash; {{msTime $cert.NotAfter}}<br>
+Key: {{$cert.KeyAlg}} {{$cert.KeySize}} bits &middot; Signature: {{$cert.SigAlg}}<br>
+{{with $cert.AltNames}}Alternative names: {{join . " "}}<br>{{end}}
+SHA256: <span class="mono">{{$cert.SHA256Hash}}</span><br>
+Issues: {{with certIssues $cert.Issues}}<span class="bad">{{join . ", "}}</span>{{else}}<span class="ok">None</span>{{end}}
+{{with $cert.CAAPolicy}}<br>CAA: {{range .CAARecords}}{{.Tag}}={{.Value}} {{end}}{{end}}
+</td></tr>
+{{else}}
+<tr><td class="key">#{{$j}}</td><td class="mono">{{$id}}</td></tr>
+{{end}}{{end}}
+{{range $k, $path := $chain.TrustPaths}}
+<tr><td class="key">Trust path #{{$k}}</td><td>
+{{range $n, $id := $path.CertIDs}}{{if $n}} &rarr; {{end}}{{with findCert $info $id}}{{certName .}}{{else}}<span class="mono">{{$id}}</span>{{end}}{{end}}<br>
+{{range $path.Trust}}{{.RootStore}}: {{if .IsTrusted}}<span class="ok">trusted</span>{{else}}<span class="bad">not trusted{{with .TrustErrorMessage}} ({{.}}){{end}}</span>{{end}} {{end}}
+</td></tr>
+{{end}}
+</table>
+{{end}}
+
+<h4>Protocols</h4>
+<table class="protocols">
+<tr><th>Protocol</th><th>Status</th></tr>
+{{range $d.Protocols}}
+<tr><td>{{protocolName .ID}}</td><td>{{if isInsecure .Q}}<span class="bad">Insecure</span>{{else}}<span class="ok">Yes</span>{{end}}</td></tr>
+{{end}}
+</table>
+
+<h4>Cipher Suites</h4>
+{{range $d.Suites}}
+<table class="suites">
+<tr><th colspan="3">{{protocolName .Protocol}}{{if .Preference}} (server has cipher suites preference){{end}}</th></tr>
+{{range .List}}
+<tr>
+<td class="mono{{if isInsecure .Q}} bad{{else if isWeak .Q}} weak{{end}}">{{.Name}} (0x{{printf "%x" .ID}}){{if isInsecure .Q}} INSECURE{{else if isWeak .Q}} WEAK{{end}}</td>
+<td>{{if .NamedGroupName}}ECDH {{.NamedGroupName}} ({{.NamedGroupBits}} bits){{else if .DHBits}}DH {{.DHBits}} bits{{else}}{{.KxType}}{{end}}</td>
+<td>{{.CipherStrength}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+
+{{with $d.SIMS}}
+<h4>Handshake Simulation</h4>
+<table class="sims">
+<tr><th>Client</th><th>Protocol</th><th>Suite</th><th>Key exchange</th></tr>
+{{range .Results}}
+<tr>
+<td>{{.Client.Name}} {{.Client.Version}}{{with .Client.Platform}} / {{.}}{{end}}{{if .Client.IsReference}} <b>R</b>{{end}}</td>
+{{if .ErrorCode}}
+<td colspan="3" class="bad">{{if .ErrorMessage}}{{.ErrorMessage}}{{else}}Handshake failed{{end}}</td>
+{{else}}
+<td>{{protocolName .ProtocolID}}</td>
+<td class="mono">{{.SuiteName}}</td>
+<td>{{if .NamedGroupName}}ECDH {{.NamedGroupName}}{{else}}{{.KxType}}{{end}}{{if .KeyAlg}} &middot; {{.KeyAlg}} {{.KeySize}}{{end}}</td>
+{{end}}
+</tr>
+{{end}}
+</table>
+{{end}}
+
+<h4>Protocol Details</h4>
+<table class="details">
+<tr><td class="key">Forward secrecy</td><td>{{$d.ForwardSecrecy}}</td></tr>
+<tr><td class="key">ALPN</td><td>{{if $d.SupportsALPN}}{{$d.ALPNProtocols}}{{else}}No{{end}}</td></tr>
+<tr><td class="key">OCSP stapling</td><td>{{$d.OCSPStapling}}</td></tr>
+<tr><td class="key">HSTS</td><td>{{with $d.HSTSPolicy}}{{.Status}}{{if .MaxAge}} (max-age={{.MaxAge}}){{end}}{{else}}-{{end}}</td></tr>
+<tr><td class="key">HPKP</td><td>{{with $d.HPKPPolicy}}{{.Status}}{{else}}-{{end}}</td></tr>
+<tr><td class="key">Server signature</td><td>{{$d.ServerSignature}}</td></tr>
+</table>
+
+{{range $d.HTTPTransactions}}
+<h4>HTTP Request: {{.RequestURL}}</h4>
+<table class="http">
+<tr><td class="key">Request</td><td class="mono">{{.RequestLine}}</td></tr>
+<tr><td class="key">Response</td><td class="mono">{{.ResponseLine}}</td></tr>
+{{range .ResponseHeaders}}
+<tr><td class="key">{{.Name}}</td><td class="mono">{{.Value}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{end}}{{end}}
+{{end}}
+</section>
+{{end}}
+</body>
+</html>
+`
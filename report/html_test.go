@@ -0,0 +1,60 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"strings"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ReportSuite) TestHTML(c *check.C) {
+	info := loadAssessment(c)
+	info.Endpoints[0].Details.Freak = true
+	info.Endpoints[0].Details.ServerSignature = "<script>alert(1)</script>"
+
+	failed := &sslscan.AnalyzeInfo{
+		Host:          "unknown.com",
+		Port:          443,
+		Status:        sslscan.STATUS_ERROR,
+		StatusMessage: "Unable to resolve domain name",
+	}
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(WriteHTML(buf, []*sslscan.AnalyzeInfo{info, failed}, testOptions), check.IsNil)
+
+	html := buf.String()
+
+	for _, expected := range []string{
+		"<title>SSL Report: essentialkaos.com, unknown.com</title>",
+		"Generated at 2020-10-01 12:00:00 UTC",
+		`<span class="grade grade-a">A&#43;</span>`,
+		"<li>Vulnerability: freak: Endpoint is vulnerable (freak = true)</li>",
+		"#0 essentialkaos.com",
+		"Valid: 2020-09-02 00:00:00 UTC &mdash; 2020-11-30 23:59:59 UTC",
+		"essentialkaos.com &rarr; Let&#39;s Encrypt Authority X3 &rarr; DST Root CA X3",
+		"Mozilla: <span class=\"ok\">trusted</span>",
+		"<th colspan=\"3\">TLS 1.3</th>",
+		"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA (0xc014) WEAK",
+		"Android 4.4.2",
+		"<td class=\"key\">Strict-Transport-Security</td><td class=\"mono\">max-age=31536000; preload</td>",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"Assessment failed: Unable to resolve domain name",
+	} {
+		c.Assert(strings.Contains(html, expected), check.Equals, true, check.Commentf("Report doesn't contain %q", expected))
+	}
+
+	// Report must be self-contained
+	c.Assert(html, check.Not(check.Matches), `(?s).*(<link|<script|<img|@import|url\().*`)
+}
@@ -0,0 +1,178 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// JUNIT_TIME_FORMAT is format of timestamps in JUnit reports
+const JUNIT_TIME_FORMAT = "2006-01-02T15:04:05"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// JUnitTestSuites is root element of JUnit report
+type JUnitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Suites   []*JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite contains results of checks for one host
+type JUnitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties []*JUnitProperty `xml:"properties>property,omitempty"`
+	Cases      []*JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitProperty is test suite property
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// JUnitTestCase contains result of one check for endpoint
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitMessage `xml:"failure,omitempty"`
+	Error     *JUnitMessage `xml:"error,omitempty"`
+	Skipped   *JUnitMessage `xml:"skipped,omitempty"`
+}
+
+// JUnitMessage is failure, error or skip message
+type JUnitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// JUnit converts assessments results to JUnit test suites (one test suite
+// per host and one test case per endpoint per check)
+func JUnit(infos []*sslscan.AnalyzeInfo, opts *Options) *JUnitTestSuites {
+	opts = getOptions(opts)
+	result := &JUnitTestSuites{Name: "sslscan"}
+
+	for _, info := range infos {
+		suite := junitTestSuite(info, opts)
+
+		result.Tests += suite.Tests
+		result.Failures += suite.Failures
+		result.Errors += suite.Errors
+		result.Skipped += suite.Skipped
+		result.Suites = append(result.Suites, suite)
+	}
+
+	return result
+}
+
+// WriteJUnit writes assessments results as JUnit XML report
+func WriteJUnit(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options) error {
+	data, err := xml.MarshalIndent(JUnit(infos, opts), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+
+	return err
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// junitTestSuite creates test suite for host
+func junitTestSuite(info *sslscan.AnalyzeInfo, opts *Options) *JUnitTestSuite {
+	suite := &JUnitTestSuite{
+		Name: info.Host + ":" + strconv.Itoa(info.Port),
+		Properties: []*JUnitProperty{
+			{"engineVersion", info.EngineVersion},
+			{"criteriaVersion", info.CriteriaVersion},
+		},
+	}
+
+	if info.TestTime > 0 {
+		suite.Timestamp = msToTime(info.TestTime).UTC().Format(JUNIT_TIME_FORMAT)
+	}
+
+	if info.Status == sslscan.STATUS_ERROR {
+		suite.Tests, suite.Errors = 1, 1
+		suite.Time = formatJUnitTime(0)
+		suite.Cases = append(suite.Cases, &JUnitTestCase{
+			Name:      "Assessment",
+			ClassName: info.Host,
+			Time:      formatJUnitTime(0),
+			Error: &JUnitMessage{
+				Message: info.StatusMessage,
+				Type:    sslscan.STATUS_ERROR,
+			},
+		})
+
+		return suite
+	}
+
+	var duration time.Duration
+
+	for _, endpoint := range info.Endpoints {
+		duration += time.Duration(endpoint.Duration) * time.Millisecond
+	}
+
+	suite.Time = formatJUnitTime(duration)
+
+	for _, check := range GetChecks(info, opts) {
+		tc := &JUnitTestCase{
+			Name:      check.Name,
+			ClassName: info.Host + "/" + check.Endpoint,
+			Time:      formatJUnitTime(0),
+		}
+
+		switch {
+		case check.Skipped:
+			suite.Skipped++
+			tc.Skipped = &JUnitMessage{Message: check.Message}
+		case !check.Passed:
+			suite.Failures++
+			tc.Failure = &JUnitMessage{
+				Message: check.Message,
+				Type:    check.ID,
+				Text:    check.Message,
+			}
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	return suite
+}
+
+// formatJUnitTime formats duration as seconds
+func formatJUnitTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,72 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"encoding/xml"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ReportSuite) TestJUnit(c *check.C) {
+	info := loadAssessment(c)
+	info.Endpoints[0].Details.Poodle = true
+
+	failed := &sslscan.AnalyzeInfo{
+		Host:          "unknown.com",
+		Port:          443,
+		Status:        sslscan.STATUS_ERROR,
+		StatusMessage: "Unable to resolve domain name",
+	}
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(WriteJUnit(buf, []*sslscan.AnalyzeInfo{info, failed}, testOptions), check.IsNil)
+	c.Assert(buf.String(), check.Matches, `(?s)<\?xml version="1.0" encoding="UTF-8"\?>.*`)
+
+	report := &JUnitTestSuites{}
+
+	c.Assert(xml.Unmarshal(buf.Bytes(), report), check.IsNil)
+	c.Assert(report.Tests, check.Equals, 21)
+	c.Assert(report.Failures, check.Equals, 1)
+	c.Assert(report.Errors, check.Equals, 1)
+	c.Assert(report.Suites, check.HasLen, 2)
+
+	suite := report.Suites[0]
+
+	c.Assert(suite.Name, check.Equals, "essentialkaos.com:443")
+	c.Assert(suite.Time, check.Equals, "77.571")
+	c.Assert(suite.Timestamp, check.Equals, "2020-10-01T12:01:17")
+	c.Assert(suite.Cases, check.HasLen, 20)
+	c.Assert(suite.Cases[0].Name, check.Equals, "Grade")
+	c.Assert(suite.Cases[0].ClassName, check.Equals, "essentialkaos.com/5.79.108.150")
+	c.Assert(suite.Cases[0].Failure, check.IsNil)
+
+	var poodle *JUnitTestCase
+
+	for _, tc := range suite.Cases {
+		if tc.Failure != nil {
+			poodle = tc
+		}
+	}
+
+	c.Assert(poodle, check.NotNil)
+	c.Assert(poodle.Name, check.Equals, "Vulnerability: poodle")
+	c.Assert(poodle.Failure.Type, check.Equals, "vuln.poodle")
+	c.Assert(poodle.Failure.Message, check.Equals, "Endpoint is vulnerable (poodle = true)")
+
+	suite = report.Suites[1]
+
+	c.Assert(suite.Cases, check.HasLen, 1)
+	c.Assert(suite.Cases[0].Error.Message, check.Equals, "Unable to resolve domain name")
+}
@@ -0,0 +1,399 @@
+// Package report provides renderers for assessments results
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	CHECK_GRADE         = "grade"
+	CHECK_VULNERABILITY = "vuln"
+	CHECK_CERT          = "cert"
+	CHECK_CHAIN         = "chain"
+	CHECK_HSTS          = "hsts"
+)
+
+const (
+	VERBOSITY_SHORT  = 1 // one table row per endpoint
+	VERBOSITY_NORMAL = 2 // summary for every endpoint
+	VERBOSITY_FULL   = 3 // summary with suites, chains and failed handshakes
+)
+
+// DEFAULT_MIN_GRADE is default minimal grade which passes grade check
+const DEFAULT_MIN_GRADE = "A-"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Options contains report options
+type Options struct {
+	MinGrade   string        // minimal grade which passes grade check (DEFAULT_MIN_GRADE if empty)
+	CertExpiry time.Duration // certificate check fails if certificate expires sooner
+	Now        time.Time     // time used for certificate checks (current time if empty)
+	Verbosity  int           // verbosity of text reports (VERBOSITY_NORMAL if empty)
+	NoColor    bool          // disable ANSI colors in text reports
+	Columns    []*Column     // columns of CSV/TSV export (DefaultColumns if empty)
+}
+
+// Check contains result of one check for endpoint
+type Check struct {
+	ID       string // stable check ID (e.g. "vuln.heartbleed")
+	Type     string // check type (CHECK_*)
+	Name     string // human-readable check name
+	Endpoint string // endpoint IP address
+	Passed   bool   // true if check is passed
+	Skipped  bool   // true if there is no data for check
+	Message  string // failure message with offending values
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// certIssues contains names of certificate issues
+var certIssues = []struct {
+	flag int
+	id   string
+	name string
+}{
+	{sslscan.CERT_ISSUE_NO_CHAIN_OF_TRUST, "no-chain-of-trust", "no chain of trust"},
+	{sslscan.CERT_ISSUE_NOT_BEFORE, "not-yet-valid", "not yet valid"},
+	{sslscan.CERT_ISSUE_NOT_AFTER, "expired", "expired"},
+	{sslscan.CERT_ISSUE_HOSTNAME_MISMATCH, "hostname-mismatch", "hostname mismatch"},
+	{sslscan.CERT_ISSUE_REVOKED, "revoked", "revoked"},
+	{sslscan.CERT_ISSUE_BAD_COMMON_NAME, "bad-common-name", "bad common name"},
+	{sslscan.CERT_ISSUE_SELF_SIGNED, "self-signed", "self-signed"},
+	{sslscan.CERT_ISSUE_BLACKLISTED, "blacklisted", "blacklisted"},
+	{sslscan.CERT_ISSUE_INSECURE_SIGNATURE, "insecure-signature", "insecure signature"},
+	{sslscan.CERT_ISSUE_INSECURE_KEY, "insecure-key", "insecure key"},
+}
+
+// chainIssues contains names of certificate chain issues
+var chainIssues = []struct {
+	flag int
+	id   string
+	name string
+}{
+	{sslscan.CERT_CHAIN_ISSUE_UNUSED, "unused", "unused"},
+	{sslscan.CERT_CHAIN_ISSUE_INCOMPLETE, "incomplete", "incomplete chain"},
+	{sslscan.CERT_CHAIN_ISSUE_DUPLICATE, "duplicate", "duplicate certificates"},
+	{sslscan.CERT_CHAIN_ISSUE_INCORRECT_ORDER, "incorrect-order", "incorrect order"},
+	{sslscan.CERT_CHAIN_ISSUE_SELF_SIGNED_ROOT, "self-signed-root", "contains self-signed root"},
+	{sslscan.CERT_CHAIN_ISSUE_CANT_VALIDATE, "cant-validate", "can't validate"},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetChecks returns results of all checks for every endpoint of assessment
+func GetChecks(info *sslscan.AnalyzeInfo, opts *Options) []*Check {
+	var result []*Check
+
+	if info == nil {
+		return result
+	}
+
+	opts = getOptions(opts)
+
+	for _, endpoint := range info.Endpoints {
+		result = append(result, getEndpointChecks(info, endpoint, opts)...)
+	}
+
+	return result
+}
+
+// CertIssues returns names of certificate issues
+func CertIssues(issues int) []string {
+	var result []string
+
+	for _, ci := range certIssues {
+		if issues&ci.flag != 0 {
+			result = append(result, ci.name)
+		}
+	}
+
+	return result
+}
+
+// ChainIssues returns names of certificate chain issues
+func ChainIssues(issues int) []string {
+	var result []string
+
+	for _, ci := range chainIssues {
+		if issues&ci.flag != 0 {
+			result = append(result, ci.name)
+		}
+	}
+
+	return result
+}
+
+// LeafCerts returns leaf certificates from all endpoint chains
+func LeafCerts(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo) []*sslscan.Cert {
+	var result []*sslscan.Cert
+
+	if endpoint.Details == nil {
+		return result
+	}
+
+	seen := make(map[string]bool)
+
+	for _, chain := range endpoint.Details.CertChains {
+		if len(chain.CertIDs) == 0 || seen[chain.CertIDs[0]] {
+			continue
+		}
+
+		cert := FindCert(info, chain.CertIDs[0])
+
+		if cert != nil {
+			seen[cert.ID] = true
+			result = append(result, cert)
+		}
+	}
+
+	return result
+}
+
+// FindCert returns certificate with given ID
+func FindCert(info *sslscan.AnalyzeInfo, id string) *sslscan.Cert {
+	for _, cert := range info.Certs {
+		if cert.ID == id {
+			return cert
+		}
+	}
+
+	return nil
+}
+
+// CertName returns certificate name (first common name or subject)
+func CertName(cert *sslscan.Cert) string {
+	if len(cert.CommonNames) != 0 {
+		return cert.CommonNames[0]
+	}
+
+	return cert.Subject
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getOptions returns options with default values
+func getOptions(opts *Options) *Options {
+	result := &Options{}
+
+	if opts != nil {
+		*result = *opts
+	}
+
+	if result.MinGrade == "" {
+		result.MinGrade = DEFAULT_MIN_GRADE
+	}
+
+	if result.Now.IsZero() {
+		result.Now = time.Now()
+	}
+
+	if result.Verbosity == 0 {
+		result.Verbosity = VERBOSITY_NORMAL
+	}
+
+	return result
+}
+
+// getEndpointChecks returns results of all checks for endpoint
+func getEndpointChecks(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo, opts *Options) []*Check {
+	ip := endpoint.IPAdress
+
+	result := []*Check{checkGrade(endpoint, opts)}
+
+	if endpoint.Details == nil {
+		return result
+	}
+
+	for _, vuln := range sslscan.CheckVulnerabilities(endpoint.Details) {
+		check := &Check{
+			ID:       CHECK_VULNERABILITY + "." + vuln.Name,
+			Type:     CHECK_VULNERABILITY,
+			Name:     "Vulnerability: " + vuln.Name,
+			Endpoint: ip,
+			Passed:   !vuln.Vulnerable,
+		}
+
+		if vuln.Vulnerable {
+			check.Message = fmt.Sprintf("Endpoint is vulnerable (%s = %v)", vuln.Name, vuln.Value)
+		}
+
+		result = append(result, check)
+	}
+
+	result = append(result,
+		checkCert(info, endpoint, opts),
+		checkChain(endpoint),
+		checkHSTS(endpoint),
+	)
+
+	return result
+}
+
+// checkGrade checks endpoint grade
+func checkGrade(endpoint *sslscan.EndpointInfo, opts *Options) *Check {
+	check := &Check{
+		ID:       CHECK_GRADE,
+		Type:     CHECK_GRADE,
+		Name:     "Grade",
+		Endpoint: endpoint.IPAdress,
+		Passed:   sslscan.CompareGrades(endpoint.Grade, opts.MinGrade) >= 0,
+	}
+
+	switch {
+	case check.Passed:
+		return check
+	case endpoint.Grade == "":
+		check.Message = fmt.Sprintf("Endpoint has no grade (%s)", endpoint.StatusMessage)
+	default:
+		check.Message = fmt.Sprintf("Grade %s is lower than %s", endpoint.Grade, opts.MinGrade)
+	}
+
+	return check
+}
+
+// checkCert checks validity of endpoint leaf certificates
+func checkCert(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo, opts *Options) *Check {
+	check := &Check{
+		ID:       CHECK_CERT + ".validity",
+		Type:     CHECK_CERT,
+		Name:     "Certificate validity",
+		Endpoint: endpoint.IPAdress,
+	}
+
+	certs := LeafCerts(info, endpoint)
+
+	if len(certs) == 0 {
+		check.Skipped = true
+		check.Message = "No certificates info"
+		return check
+	}
+
+	var problems []string
+
+	for _, cert := range certs {
+		var certProblems []string
+
+		notAfter := msToTime(cert.NotAfter)
+		notBefore := msToTime(cert.NotBefore)
+
+		switch {
+		case opts.Now.After(notAfter):
+			certProblems = append(certProblems, "expired at "+notAfter.Format(time.RFC3339))
+		case notAfter.Sub(opts.Now) < opts.CertExpiry:
+			certProblems = append(certProblems, "expires at "+notAfter.Format(time.RFC3339))
+		case opts.Now.Before(notBefore):
+			certProblems = append(certProblems, "not yet valid (from "+notBefore.Format(time.RFC3339)+")")
+		}
+
+		for _, issue := range CertIssues(cert.Issues) {
+			if !containsPrefix(certProblems, issue) {
+				certProblems = append(certProblems, issue)
+			}
+		}
+
+		if cert.RevocationStatus == sslscan.REVOCATION_STATUS_REVOKED &&
+			cert.Issues&sslscan.CERT_ISSUE_REVOKED == 0 {
+			certProblems = append(certProblems, "revoked")
+		}
+
+		if len(certProblems) != 0 {
+			problems = append(problems, fmt.Sprintf(
+				"%s: %s", CertName(cert), strings.Join(certProblems, ", "),
+			))
+		}
+	}
+
+	check.Passed = len(problems) == 0
+	check.Message = strings.Join(problems, "; ")
+
+	return check
+}
+
+// checkChain checks issues of endpoint certificate chains
+func checkChain(endpoint *sslscan.EndpointInfo) *Check {
+	check := &Check{
+		ID:       CHECK_CHAIN + ".issues",
+		Type:     CHECK_CHAIN,
+		Name:     "Certificate chain",
+		Endpoint: endpoint.IPAdress,
+	}
+
+	if len(endpoint.Details.CertChains) == 0 {
+		check.Skipped = true
+		check.Message = "No certificate chains info"
+		return check
+	}
+
+	var problems []string
+
+	for _, chain := range endpoint.Details.CertChains {
+		issues := ChainIssues(chain.Issues)
+
+		if len(issues) != 0 {
+			problems = append(problems, fmt.Sprintf(
+				"chain %s: %s (issues = %d)", chain.ID, strings.Join(issues, ", "), chain.Issues,
+			))
+		}
+	}
+
+	check.Passed = len(problems) == 0
+	check.Message = strings.Join(problems, "; ")
+
+	return check
+}
+
+// checkHSTS checks HSTS policy
+func checkHSTS(endpoint *sslscan.EndpointInfo) *Check {
+	check := &Check{
+		ID:       CHECK_HSTS,
+		Type:     CHECK_HSTS,
+		Name:     "HSTS",
+		Endpoint: endpoint.IPAdress,
+	}
+
+	policy := endpoint.Details.HSTSPolicy
+
+	switch {
+	case policy == nil:
+		check.Skipped = true
+		check.Message = "No HSTS policy info"
+	case policy.Status == sslscan.HSTS_STATUS_PRESENT:
+		check.Passed = true
+	case policy.Error != "":
+		check.Message = fmt.Sprintf("HSTS status is %s (%s)", policy.Status, policy.Error)
+	default:
+		check.Message = fmt.Sprintf("HSTS status is %s", policy.Status)
+	}
+
+	return check
+}
+
+// containsPrefix returns true if some item in slice starts with given prefix
+func containsPrefix(items []string, prefix string) bool {
+	for _, item := range items {
+		if strings.HasPrefix(item, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// msToTime converts timestamp in milliseconds to time
+func msToTime(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,126 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+	"pkg.re/essentialkaos/sslscan.v12/sslscantest"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type ReportSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = check.Suite(&ReportSuite{})
+
+// testOptions contains options with fixed time of assessment
+var testOptions = &Options{Now: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ReportSuite) TestChecks(c *check.C) {
+	c.Assert(GetChecks(nil, nil), check.HasLen, 0)
+
+	info := loadAssessment(c)
+	checks := GetChecks(info, testOptions)
+
+	c.Assert(checks, check.HasLen, 20)
+
+	for _, ch := range checks {
+		c.Assert(ch.Passed, check.Equals, true, check.Commentf("Check %s failed: %s", ch.ID, ch.Message))
+		c.Assert(ch.Endpoint, check.Equals, "5.79.108.150")
+	}
+
+	endpoint := info.Endpoints[0]
+	endpoint.Grade = "B"
+	endpoint.Details.Heartbleed = true
+	endpoint.Details.CertChains[0].Issues = sslscan.CERT_CHAIN_ISSUE_INCOMPLETE
+	endpoint.Details.HSTSPolicy.Status = sslscan.HSTS_STATUS_ABSENT
+	info.Certs[0].Issues = sslscan.CERT_ISSUE_NOT_AFTER | sslscan.CERT_ISSUE_HOSTNAME_MISMATCH
+
+	failed := getFailedChecks(GetChecks(info, &Options{MinGrade: "A", Now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}))
+
+	c.Assert(failed, check.HasLen, 5)
+	c.Assert(failed[CHECK_GRADE].Message, check.Equals, "Grade B is lower than A")
+	c.Assert(failed["vuln.heartbleed"].Message, check.Equals, "Endpoint is vulnerable (heartbleed = true)")
+	c.Assert(failed["cert.validity"].Message, check.Equals, "essentialkaos.com: expired at 2020-11-30T23:59:59Z, hostname mismatch")
+	c.Assert(failed["chain.issues"].Message, check.Equals, "chain ff8a173ccfbf4b02c2aa1db43a5ed08081129e11b30397b5bd584c96065529a6: incomplete chain (issues = 2)")
+	c.Assert(failed[CHECK_HSTS].Message, check.Equals, "HSTS status is absent")
+
+	info.Certs = nil
+	endpoint.Details.HSTSPolicy = nil
+	endpoint.Details.CertChains = nil
+	endpoint.Grade = ""
+	endpoint.StatusMessage = "Unable to connect to the server"
+
+	checks = GetChecks(info, nil)
+
+	c.Assert(checks[0].Message, check.Equals, "Endpoint has no grade (Unable to connect to the server)")
+	c.Assert(checks[len(checks)-1].Skipped, check.Equals, true)
+	c.Assert(checks[len(checks)-2].Skipped, check.Equals, true)
+	c.Assert(checks[len(checks)-3].Skipped, check.Equals, true)
+}
+
+func (s *ReportSuite) TestIssues(c *check.C) {
+	c.Assert(CertIssues(0), check.HasLen, 0)
+	c.Assert(CertIssues(sslscan.CERT_ISSUE_REVOKED|sslscan.CERT_ISSUE_INSECURE_KEY), check.DeepEquals, []string{"revoked", "insecure key"})
+	c.Assert(ChainIssues(sslscan.CERT_CHAIN_ISSUE_UNUSED|sslscan.CERT_CHAIN_ISSUE_CANT_VALIDATE), check.DeepEquals, []string{"unused", "can't validate"})
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// loadAssessment loads assessment result from fixtures
+func loadAssessment(c *check.C) *sslscan.AnalyzeInfo {
+	endpoint, err := sslscantest.LoadEndpoint("../responses/v3-2.1.3-2009q.json")
+
+	c.Assert(err, check.IsNil)
+
+	data, err := ioutil.ReadFile("testdata/certs.json")
+
+	c.Assert(err, check.IsNil)
+
+	info := &sslscan.AnalyzeInfo{
+		Host:            "essentialkaos.com",
+		Port:            443,
+		Protocol:        "http",
+		Status:          sslscan.STATUS_READY,
+		StartTime:       1601553600000,
+		TestTime:        1601553677571,
+		EngineVersion:   "2.1.3",
+		CriteriaVersion: "2009q",
+		Endpoints:       []*sslscan.EndpointInfo{endpoint},
+	}
+
+	c.Assert(json.Unmarshal(data, &info.Certs), check.IsNil)
+
+	return info
+}
+
+// getFailedChecks returns map with failed checks
+func getFailedChecks(checks []*Check) map[string]*Check {
+	result := make(map[string]*Check)
+
+	for _, ch := range checks {
+		if !ch.Passed {
+			result[ch.ID] = ch
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,427 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	SARIF_VERSION = "2.1.0"
+	SARIF_SCHEMA  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+const (
+	SARIF_LEVEL_ERROR   = "error"
+	SARIF_LEVEL_WARNING = "warning"
+	SARIF_LEVEL_NOTE    = "note"
+)
+
+const (
+	RULE_INSECURE_PROTOCOL = "insecure-protocol"
+	RULE_INSECURE_SUITE    = "insecure-suite"
+	RULE_HSTS_MISSING      = "hsts-missing"
+	RULE_CERT_EXPIRES_SOON = "cert-expires-soon"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SARIFLog is root object of SARIF report
+type SARIFLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*SARIFRun `json:"runs"`
+}
+
+// SARIFRun contains results of one tool run
+type SARIFRun struct {
+	Tool        *SARIFTool         `json:"tool"`
+	Invocations []*SARIFInvocation `json:"invocations,omitempty"`
+	Results     []*SARIFResult     `json:"results"`
+}
+
+// SARIFTool contains info about tool
+type SARIFTool struct {
+	Driver *SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver contains info about tool and rules
+type SARIFDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*SARIFRule `json:"rules"`
+}
+
+// SARIFRule contains rule description
+type SARIFRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name"`
+	ShortDescription     *SARIFMessage          `json:"shortDescription"`
+	DefaultConfiguration *SARIFConfiguration    `json:"defaultConfiguration"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+// SARIFConfiguration contains default rule configuration
+type SARIFConfiguration struct {
+	Level string `json:"level"`
+}
+
+// SARIFInvocation contains info about tool invocation
+type SARIFInvocation struct {
+	ExecutionSuccessful bool                 `json:"executionSuccessful"`
+	Notifications       []*SARIFNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+// SARIFNotification is tool execution notification
+type SARIFNotification struct {
+	Level   string        `json:"level"`
+	Message *SARIFMessage `json:"message"`
+}
+
+// SARIFResult contains info about one finding
+type SARIFResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             *SARIFMessage     `json:"message"`
+	Locations           []*SARIFLocation  `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+// SARIFMessage is text message
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFLocation contains finding location
+type SARIFLocation struct {
+	PhysicalLocation *SARIFPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []*SARIFLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+// SARIFPhysicalLocation contains artifact location
+type SARIFPhysicalLocation struct {
+	ArtifactLocation *SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+// SARIFArtifactLocation contains artifact URI
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFLogicalLocation contains logical location (endpoint)
+type SARIFLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// sarifRuleInfo contains rule info
+type sarifRuleInfo struct {
+	id       string
+	name     string
+	level    string
+	severity float64 // security severity (0-10)
+}
+
+// sarifFinding contains info about finding for endpoint
+type sarifFinding struct {
+	rule    string
+	subject string
+	message string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// sarifVulnRules contains rules for vulnerability fields
+var sarifVulnRules = map[string]*sarifRuleInfo{
+	"vulnBeast":               {name: "BEAST attack", level: SARIF_LEVEL_WARNING, severity: 3.7},
+	"heartbleed":              {name: "Heartbleed (CVE-2014-0160)", level: SARIF_LEVEL_ERROR, severity: 7.5},
+	"openSslCcs":              {name: "OpenSSL CCS injection (CVE-2014-0224)", level: SARIF_LEVEL_ERROR, severity: 7.4},
+	"openSSLLuckyMinus20":     {name: "OpenSSL padding oracle (CVE-2016-2107)", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"ticketbleed":             {name: "Ticketbleed (CVE-2016-9244)", level: SARIF_LEVEL_ERROR, severity: 7.5},
+	"bleichenbacher":          {name: "ROBOT attack", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"zombiePoodle":            {name: "Zombie POODLE", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"goldenDoodle":            {name: "GOLDENDOODLE", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"zeroLengthPaddingOracle": {name: "OpenSSL 0-length padding oracle (CVE-2019-1559)", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"sleepingPoodle":          {name: "Sleeping POODLE", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"poodle":                  {name: "POODLE (SSL 3.0)", level: SARIF_LEVEL_ERROR, severity: 3.4},
+	"poodleTls":               {name: "POODLE (TLS)", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"freak":                   {name: "FREAK attack", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"logjam":                  {name: "Logjam attack", level: SARIF_LEVEL_ERROR, severity: 3.7},
+	"drownVulnerable":         {name: "DROWN attack", level: SARIF_LEVEL_ERROR, severity: 5.9},
+	"supportsRc4":             {name: "RC4 cipher suites supported", level: SARIF_LEVEL_WARNING, severity: 5.9},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SARIF converts assessments results to SARIF 2.1.0 log
+func SARIF(infos []*sslscan.AnalyzeInfo, opts *Options) *SARIFLog {
+	opts = getOptions(opts)
+	rules, index := getSARIFRules()
+
+	run := &SARIFRun{
+		Tool: &SARIFTool{
+			Driver: &SARIFDriver{
+				Name:           "sslscan",
+				Version:        sslscan.VERSION,
+				InformationURI: "https://github.com/essentialkaos/sslscan",
+			},
+		},
+		Invocations: []*SARIFInvocation{{ExecutionSuccessful: true}},
+		Results:     []*SARIFResult{},
+	}
+
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule.toSARIF())
+	}
+
+	invocation := run.Invocations[0]
+
+	for _, info := range infos {
+		if info.Status == sslscan.STATUS_ERROR {
+			invocation.ExecutionSuccessful = false
+			invocation.Notifications = append(invocation.Notifications, &SARIFNotification{
+				Level:   SARIF_LEVEL_ERROR,
+				Message: &SARIFMessage{fmt.Sprintf("Assessment of %s failed: %s", info.Host, info.StatusMessage)},
+			})
+
+			continue
+		}
+
+		for _, endpoint := range info.Endpoints {
+			for _, f := range getSARIFFindings(info, endpoint, opts) {
+				rule := rules[index[f.rule]]
+				run.Results = append(run.Results, &SARIFResult{
+					RuleID:    rule.id,
+					RuleIndex: index[f.rule],
+					Level:     rule.level,
+					Message:   &SARIFMessage{f.message},
+					Locations: []*SARIFLocation{getSARIFLocation(info, endpoint)},
+					PartialFingerprints: map[string]string{
+						"sslscanFinding/v1": getSARIFFingerprint(info, endpoint, f),
+					},
+				})
+			}
+		}
+	}
+
+	return &SARIFLog{
+		Schema:  SARIF_SCHEMA,
+		Version: SARIF_VERSION,
+		Runs:    []*SARIFRun{run},
+	}
+}
+
+// WriteSARIF writes assessments results as SARIF 2.1.0 log
+func WriteSARIF(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(SARIF(infos, opts))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// toSARIF converts rule info to SARIF rule
+func (r *sarifRuleInfo) toSARIF() *SARIFRule {
+	return &SARIFRule{
+		ID:                   r.id,
+		Name:                 r.name,
+		ShortDescription:     &SARIFMessage{r.name},
+		DefaultConfiguration: &SARIFConfiguration{r.level},
+		Properties: map[string]interface{}{
+			"security-severity": strconv.FormatFloat(r.severity, 'f', 1, 64),
+			"tags":              []string{"security", "tls"},
+		},
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getSARIFRules returns list of all rules and map with rules indexes
+func getSARIFRules() ([]*sarifRuleInfo, map[string]int) {
+	rules := []*sarifRuleInfo{
+		{RULE_INSECURE_PROTOCOL, "Insecure protocol supported", SARIF_LEVEL_ERROR, 7.5},
+		{RULE_INSECURE_SUITE, "Insecure cipher suite supported", SARIF_LEVEL_WARNING, 5.9},
+		{RULE_HSTS_MISSING, "HSTS policy is missing", SARIF_LEVEL_WARNING, 4.3},
+		{RULE_CERT_EXPIRES_SOON, "Certificate expires soon", SARIF_LEVEL_WARNING, 4.0},
+	}
+
+	for _, vf := range sslscan.CheckVulnerabilities(&sslscan.EndpointDetails{}) {
+		rules = append(rules, getSARIFVulnRule(vf.Name))
+	}
+
+	for _, ci := range certIssues {
+		rules = append(rules, &sarifRuleInfo{
+			"cert-" + ci.id, "Certificate issue: " + ci.name, SARIF_LEVEL_ERROR, 7.4,
+		})
+	}
+
+	for _, ci := range chainIssues {
+		rules = append(rules, &sarifRuleInfo{
+			"chain-" + ci.id, "Certificate chain issue: " + ci.name, SARIF_LEVEL_WARNING, 3.1,
+		})
+	}
+
+	index := make(map[string]int)
+
+	for i, rule := range rules {
+		index[rule.id] = i
+	}
+
+	return rules, index
+}
+
+// getSARIFVulnRule returns rule for vulnerability field
+func getSARIFVulnRule(name string) *sarifRuleInfo {
+	rule := sarifRuleInfo{name: name, level: SARIF_LEVEL_ERROR, severity: 5.0}
+
+	if sarifVulnRules[name] != nil {
+		rule = *sarifVulnRules[name]
+	}
+
+	rule.id = "vuln-" + name
+
+	return &rule
+}
+
+// getSARIFFindings returns all findings for endpoint
+func getSARIFFindings(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo, opts *Options) []*sarifFinding {
+	var result []*sarifFinding
+
+	d := endpoint.Details
+
+	if d == nil {
+		return result
+	}
+
+	for _, p := range d.Protocols {
+		if p.Q != nil && *p.Q == 0 {
+			name := sslscan.ProtocolName(p.ID)
+			result = append(result, &sarifFinding{
+				RULE_INSECURE_PROTOCOL, name,
+				fmt.Sprintf("Insecure protocol %s is supported", name),
+			})
+		}
+	}
+
+	for _, ps := range d.Suites {
+		for _, suite := range ps.List {
+			if suite.Q != nil && *suite.Q == 0 {
+				name := sslscan.ProtocolName(ps.Protocol)
+				result = append(result, &sarifFinding{
+					RULE_INSECURE_SUITE, name + " " + suite.Name,
+					fmt.Sprintf("Insecure cipher suite %s is supported with %s", suite.Name, name),
+				})
+			}
+		}
+	}
+
+	for _, vuln := range sslscan.FindVulnerabilities(d) {
+		result = append(result, &sarifFinding{
+			"vuln-" + vuln.Name, vuln.Name,
+			fmt.Sprintf("Endpoint is vulnerable to %s (%s = %v)", getSARIFVulnRule(vuln.Name).name, vuln.Name, vuln.Value),
+		})
+	}
+
+	for _, cert := range LeafCerts(info, endpoint) {
+		for _, ci := range certIssues {
+			if cert.Issues&ci.flag != 0 {
+				result = append(result, &sarifFinding{
+					"cert-" + ci.id, cert.ID,
+					fmt.Sprintf("Certificate \"%s\" has issue: %s", CertName(cert), ci.name),
+				})
+			}
+		}
+
+		notAfter := msToTime(cert.NotAfter)
+
+		if cert.Issues&sslscan.CERT_ISSUE_NOT_AFTER == 0 && notAfter.Sub(opts.Now) < opts.CertExpiry {
+			result = append(result, &sarifFinding{
+				RULE_CERT_EXPIRES_SOON, cert.ID,
+				fmt.Sprintf("Certificate \"%s\" expires at %s", CertName(cert), notAfter.UTC().Format(time.RFC3339)),
+			})
+		}
+	}
+
+	for _, chain := range d.CertChains {
+		for _, ci := range chainIssues {
+			if chain.Issues&ci.flag != 0 {
+				result = append(result, &sarifFinding{
+					"chain-" + ci.id, chain.ID,
+					fmt.Sprintf("Certificate chain %s has issue: %s", chain.ID, ci.name),
+				})
+			}
+		}
+	}
+
+	if d.HSTSPolicy != nil && d.HSTSPolicy.Status != sslscan.HSTS_STATUS_PRESENT {
+		result = append(result, &sarifFinding{
+			RULE_HSTS_MISSING, "",
+			fmt.Sprintf("HSTS policy is not present (status: %s)", d.HSTSPolicy.Status),
+		})
+	}
+
+	return result
+}
+
+// getSARIFLocation returns location for endpoint
+func getSARIFLocation(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo) *SARIFLocation {
+	host := net.JoinHostPort(info.Host, strconv.Itoa(info.Port))
+	uri := &url.URL{Scheme: getSARIFScheme(info), Host: host, Path: "/"}
+
+	return &SARIFLocation{
+		PhysicalLocation: &SARIFPhysicalLocation{
+			ArtifactLocation: &SARIFArtifactLocation{uri.String()},
+		},
+		LogicalLocations: []*SARIFLogicalLocation{
+			{
+				Name:               endpoint.IPAdress,
+				FullyQualifiedName: host + "/" + endpoint.IPAdress,
+				Kind:               "endpoint",
+			},
+		},
+	}
+}
+
+// getSARIFScheme returns URI scheme for assessed service
+func getSARIFScheme(info *sslscan.AnalyzeInfo) string {
+	protocol := strings.ToLower(info.Protocol)
+
+	if protocol == "" || protocol == "http" {
+		return "https"
+	}
+
+	return protocol
+}
+
+// getSARIFFingerprint returns stable fingerprint for finding
+func getSARIFFingerprint(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo, f *sarifFinding) string {
+	hash := sha256.Sum256([]byte(
+		f.rule + "|" + info.Host + "|" + strconv.Itoa(info.Port) + "|" + endpoint.IPAdress + "|" + f.subject,
+	))
+
+	return hex.EncodeToString(hash[:16])
+}
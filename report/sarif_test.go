@@ -0,0 +1,97 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ReportSuite) TestSARIF(c *check.C) {
+	info := loadAssessment(c)
+
+	log := SARIF([]*sslscan.AnalyzeInfo{info}, testOptions)
+
+	c.Assert(log.Version, check.Equals, SARIF_VERSION)
+	c.Assert(log.Runs[0].Results, check.HasLen, 0)
+	c.Assert(log.Runs[0].Invocations[0].ExecutionSuccessful, check.Equals, true)
+
+	rules := log.Runs[0].Tool.Driver.Rules
+	ids := make(map[string]bool)
+
+	for _, rule := range rules {
+		c.Assert(ids[rule.ID], check.Equals, false, check.Commentf("Duplicate rule %s", rule.ID))
+		ids[rule.ID] = true
+	}
+
+	q := 0
+	d := info.Endpoints[0].Details
+	d.Protocols[0].Q = &q
+	d.Suites[0].List[0].Q = &q
+	d.Logjam = true
+	d.HSTSPolicy.Status = sslscan.HSTS_STATUS_ABSENT
+	info.Certs[0].Issues = sslscan.CERT_ISSUE_SELF_SIGNED
+
+	failed := &sslscan.AnalyzeInfo{Host: "unknown.com", Status: sslscan.STATUS_ERROR, StatusMessage: "Unable to resolve domain name"}
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(WriteSARIF(buf, []*sslscan.AnalyzeInfo{info, failed}, testOptions), check.IsNil)
+
+	log = &SARIFLog{}
+
+	c.Assert(json.Unmarshal(buf.Bytes(), log), check.IsNil)
+	c.Assert(log.Schema, check.Equals, SARIF_SCHEMA)
+
+	run := log.Runs[0]
+
+	c.Assert(run.Invocations[0].ExecutionSuccessful, check.Equals, false)
+	c.Assert(run.Invocations[0].Notifications[0].Message.Text, check.Equals, "Assessment of unknown.com failed: Unable to resolve domain name")
+	c.Assert(run.Results, check.HasLen, 5)
+
+	expected := []struct{ rule, level, message string }{
+		{RULE_INSECURE_PROTOCOL, SARIF_LEVEL_ERROR, "Insecure protocol TLS 1.2 is supported"},
+		{RULE_INSECURE_SUITE, SARIF_LEVEL_WARNING, "Insecure cipher suite TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 is supported with TLS 1.2"},
+		{"vuln-logjam", SARIF_LEVEL_ERROR, "Endpoint is vulnerable to Logjam attack (logjam = true)"},
+		{"cert-self-signed", SARIF_LEVEL_ERROR, "Certificate \"essentialkaos.com\" has issue: self-signed"},
+		{RULE_HSTS_MISSING, SARIF_LEVEL_WARNING, "HSTS policy is not present (status: absent)"},
+	}
+
+	for i, e := range expected {
+		result := run.Results[i]
+
+		c.Assert(result.RuleID, check.Equals, e.rule)
+		c.Assert(result.Level, check.Equals, e.level)
+		c.Assert(result.Message.Text, check.Equals, e.message)
+		c.Assert(run.Tool.Driver.Rules[result.RuleIndex].ID, check.Equals, e.rule)
+		c.Assert(result.Locations[0].PhysicalLocation.ArtifactLocation.URI, check.Equals, "https://essentialkaos.com:443/")
+		c.Assert(result.Locations[0].LogicalLocations[0].Name, check.Equals, "5.79.108.150")
+		c.Assert(result.PartialFingerprints["sslscanFinding/v1"], check.HasLen, 32)
+	}
+
+	// Fingerprints must be stable between runs
+	log2 := SARIF([]*sslscan.AnalyzeInfo{info}, testOptions)
+
+	c.Assert(log2.Runs[0].Results[2].PartialFingerprints, check.DeepEquals, run.Results[2].PartialFingerprints)
+
+	smtp := &sslscan.AnalyzeInfo{Host: "mail.essentialkaos.com", Port: 25, Protocol: "smtp"}
+	location := getSARIFLocation(smtp, info.Endpoints[0])
+
+	c.Assert(location.PhysicalLocation.ArtifactLocation.URI, check.Equals, "smtp://mail.essentialkaos.com:25/")
+
+	smtp.Protocol = "HTTP"
+	location = getSARIFLocation(smtp, info.Endpoints[0])
+
+	c.Assert(location.PhysicalLocation.ArtifactLocation.URI, check.Equals, "https://mail.essentialkaos.com:25/")
+}
@@ -0,0 +1,619 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+const (
+	STYLE_NONE = iota
+	STYLE_GOOD
+	STYLE_WARN
+	STYLE_BAD
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// textCell is text with style
+type textCell struct {
+	text  string
+	style int
+}
+
+// textFormatter formats text elements for specific markup
+type textFormatter interface {
+	heading(level int, text string) string
+	item(key string, value textCell) string
+	list(key string, values []textCell) string
+	table(header []string, rows [][]textCell) string
+}
+
+// markdownFormatter formats text as Markdown
+type markdownFormatter struct{}
+
+// ansiFormatter formats text for terminal with optional ANSI colors
+type ansiFormatter struct {
+	noColor bool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ansiColors contains ANSI escape sequences for styles
+var ansiColors = map[int]string{
+	STYLE_GOOD: "\033[32m",
+	STYLE_WARN: "\033[33m",
+	STYLE_BAD:  "\033[31m",
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// WriteMarkdown writes short summary of assessments results as Markdown
+func WriteMarkdown(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options) error {
+	return writeText(w, infos, getOptions(opts), markdownFormatter{})
+}
+
+// WriteText writes short summary of assessments results as text for terminal
+func WriteText(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options) error {
+	opts = getOptions(opts)
+	return writeText(w, infos, opts, ansiFormatter{opts.NoColor})
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// writeText writes assessments results using given formatter
+func writeText(w io.Writer, infos []*sslscan.AnalyzeInfo, opts *Options, f textFormatter) error {
+	bw := bufio.NewWriter(w)
+
+	if opts.Verbosity == VERBOSITY_SHORT || (opts.Verbosity == VERBOSITY_NORMAL && len(infos) > 1) {
+		bw.WriteString(renderBatchTable(infos, opts, f))
+		return bw.Flush()
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+
+		bw.WriteString(renderHost(info, opts, f))
+	}
+
+	return bw.Flush()
+}
+
+// renderBatchTable renders table with one row per endpoint
+func renderBatchTable(infos []*sslscan.AnalyzeInfo, opts *Options, f textFormatter) string {
+	var rows [][]textCell
+
+	for _, info := range infos {
+		if info.Status == sslscan.STATUS_ERROR || len(info.Endpoints) == 0 {
+			rows = append(rows, []textCell{
+				{text: info.Host}, {text: "-"},
+				{info.Status, STYLE_BAD}, {text: "-"}, {text: "-"}, {text: "-"}, {text: "-"},
+			})
+
+			continue
+		}
+
+		for _, endpoint := range info.Endpoints {
+			row := []textCell{
+				{text: info.Host},
+				{text: endpoint.IPAdress},
+				gradeCell(endpoint.Grade, opts),
+				{text: "-"}, {text: "-"}, {text: "-"}, {text: "-"},
+			}
+
+			if endpoint.Details != nil {
+				row[3] = textCell{text: joinCells(protocolsCells(endpoint.Details), ", ")}
+				row[4] = vulnsCountCell(endpoint.Details)
+				row[6] = hstsCell(endpoint.Details.HSTSPolicy)
+			}
+
+			if certs := LeafCerts(info, endpoint); len(certs) != 0 {
+				row[5] = expiryCell(certs[0], opts, true)
+			}
+
+			rows = append(rows, row)
+		}
+	}
+
+	return f.table(
+		[]string{"Host", "IP", "Grade", "Protocols", "Vulnerabilities", "Cert expiry", "HSTS"},
+		rows,
+	)
+}
+
+// renderHost renders summary for host
+func renderHost(info *sslscan.AnalyzeInfo, opts *Options, f textFormatter) string {
+	var buf strings.Builder
+
+	buf.WriteString(f.heading(1, info.Host+":"+strconv.Itoa(info.Port)))
+
+	if info.Status == sslscan.STATUS_ERROR {
+		buf.WriteString(f.item("Status", textCell{info.Status + ": " + info.StatusMessage, STYLE_BAD}))
+		return buf.String()
+	}
+
+	if info.TestTime > 0 {
+		buf.WriteString(f.item("Assessed", textCell{text: msToTime(info.TestTime).UTC().Format("2006-01-02 15:04 MST")}))
+	}
+
+	for _, endpoint := range info.Endpoints {
+		buf.WriteString("\n")
+		buf.WriteString(f.heading(2, endpoint.IPAdress+formatServerName(endpoint.ServerName)))
+		buf.WriteString(f.item("Grade", gradeCell(endpoint.Grade, opts)))
+
+		if endpoint.Details == nil {
+			buf.WriteString(f.item("Status", textCell{text: endpoint.StatusMessage}))
+			continue
+		}
+
+		d := endpoint.Details
+
+		buf.WriteString(f.list("Protocols", protocolsCells(d)))
+		buf.WriteString(f.list("Weak suites", weakSuitesCells(d)))
+		buf.WriteString(f.list("Vulnerabilities", vulnsCells(d)))
+
+		certs := LeafCerts(info, endpoint)
+
+		if len(certs) == 0 {
+			buf.WriteString(f.item("Certificate", textCell{text: "-"}))
+		}
+
+		for _, cert := range certs {
+			buf.WriteString(f.item("Certificate", textCell{
+				fmt.Sprintf("%s (issued by %s)", CertName(cert), getIssuerName(cert)), STYLE_NONE,
+			}))
+			buf.WriteString(f.item("Expiry", expiryCell(cert, opts, false)))
+			buf.WriteString(f.item("CAA", caaCell(cert)))
+		}
+
+		buf.WriteString(f.item("HSTS", hstsCell(d.HSTSPolicy)))
+		buf.WriteString(f.item("HPKP", hpkpCell(d.HPKPPolicy)))
+
+		if opts.Verbosity >= VERBOSITY_FULL {
+			buf.WriteString(renderEndpointDetails(info, endpoint, f))
+		}
+	}
+
+	return buf.String()
+}
+
+// renderEndpointDetails renders suites, chains and failed handshakes
+func renderEndpointDetails(info *sslscan.AnalyzeInfo, endpoint *sslscan.EndpointInfo, f textFormatter) string {
+	var buf strings.Builder
+
+	d := endpoint.Details
+
+	buf.WriteString(f.item("Forward secrecy", textCell{text: strconv.Itoa(d.ForwardSecrecy)}))
+	buf.WriteString(f.item("OCSP stapling", boolCell(d.OCSPStapling)))
+
+	for _, chain := range d.CertChains {
+		var certs []string
+
+		for _, id := range chain.CertIDs {
+			if cert := FindCert(info, id); cert != nil {
+				certs = append(certs, CertName(cert))
+			} else {
+				certs = append(certs, id)
+			}
+		}
+
+		cell := textCell{strings.Join(certs, " → "), STYLE_NONE}
+
+		if issues := ChainIssues(chain.Issues); len(issues) != 0 {
+			cell = textCell{cell.text + " (" + strings.Join(issues, ", ") + ")", STYLE_BAD}
+		}
+
+		buf.WriteString(f.item("Chain", cell))
+	}
+
+	for _, ps := range d.Suites {
+		var rows [][]textCell
+
+		for _, suite := range ps.List {
+			rows = append(rows, []textCell{
+				suiteCell(suite),
+				{text: strconv.Itoa(suite.CipherStrength)},
+				{text: formatKeyExchange(suite)},
+			})
+		}
+
+		buf.WriteString("\n")
+		buf.WriteString(f.heading(3, "Suites: "+sslscan.ProtocolName(ps.Protocol)))
+		buf.WriteString(f.table([]string{"Suite", "Bits", "Key exchange"}, rows))
+	}
+
+	if d.SIMS != nil {
+		var failed []textCell
+
+		for _, sim := range d.SIMS.Results {
+			if sim.ErrorCode != 0 && sim.Client != nil {
+				failed = append(failed, textCell{
+					strings.TrimSpace(sim.Client.Name + " " + sim.Client.Version + " " + sim.Client.Platform),
+					STYLE_WARN,
+				})
+			}
+		}
+
+		buf.WriteString("\n")
+		buf.WriteString(f.list("Failed handshakes", failed))
+	}
+
+	return buf.String()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// heading formats Markdown heading
+func (f markdownFormatter) heading(level int, text string) string {
+	return strings.Repeat("#", level) + " " + text + "\n\n"
+}
+
+// item formats Markdown list item
+func (f markdownFormatter) item(key string, value textCell) string {
+	return "- **" + key + ":** " + f.cell(value) + "\n"
+}
+
+// list formats Markdown list item with list of values
+func (f markdownFormatter) list(key string, values []textCell) string {
+	if len(values) == 0 {
+		return f.item(key, textCell{"none", STYLE_GOOD})
+	}
+
+	var result []string
+
+	for _, v := range values {
+		result = append(result, f.cell(v))
+	}
+
+	return "- **" + key + ":** " + strings.Join(result, ", ") + "\n"
+}
+
+// table formats Markdown table
+func (f markdownFormatter) table(header []string, rows [][]textCell) string {
+	var buf strings.Builder
+
+	buf.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	buf.WriteString("|" + strings.Repeat("---|", len(header)) + "\n")
+
+	for _, row := range rows {
+		var cells []string
+
+		for _, c := range row {
+			cells = append(cells, strings.Replace(f.cell(c), "|", "\\|", -1))
+		}
+
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	return buf.String()
+}
+
+// cell formats text with style
+func (f markdownFormatter) cell(c textCell) string {
+	if c.style == STYLE_BAD {
+		return "**" + c.text + "**"
+	}
+
+	return c.text
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// heading formats terminal heading
+func (f ansiFormatter) heading(level int, text string) string {
+	if level > 1 {
+		text = strings.Repeat("  ", level-1) + text
+	}
+
+	if f.noColor {
+		return text + "\n"
+	}
+
+	return "\033[1m" + text + "\033[0m\n"
+}
+
+// item formats key-value line
+func (f ansiFormatter) item(key string, value textCell) string {
+	return fmt.Sprintf("    %-18s %s\n", key+":", f.cell(value))
+}
+
+// list formats key-value line with list of values
+func (f ansiFormatter) list(key string, values []textCell) string {
+	if len(values) == 0 {
+		return f.item(key, textCell{"none", STYLE_GOOD})
+	}
+
+	var result []string
+
+	for _, v := range values {
+		result = append(result, f.cell(v))
+	}
+
+	return fmt.Sprintf("    %-18s %s\n", key+":", strings.Join(result, ", "))
+}
+
+// table formats table with aligned columns
+func (f ansiFormatter) table(header []string, rows [][]textCell) string {
+	var buf strings.Builder
+
+	widths := make([]int, len(header))
+
+	for i, h := range header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+
+	for _, row := range rows {
+		for i, c := range row {
+			if l := utf8.RuneCountInString(c.text); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+
+	for i, h := range header {
+		buf.WriteString(pad(strings.ToUpper(h), widths[i], i == len(header)-1))
+	}
+
+	buf.WriteString("\n")
+
+	for _, row := range rows {
+		for i, c := range row {
+			buf.WriteString(f.cell(textCell{pad(c.text, widths[i], i == len(row)-1), c.style}))
+		}
+
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
+
+// cell formats text with ANSI color
+func (f ansiFormatter) cell(c textCell) string {
+	if f.noColor || ansiColors[c.style] == "" {
+		return c.text
+	}
+
+	return ansiColors[c.style] + c.text + "\033[0m"
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// gradeCell returns cell with grade
+func gradeCell(grade string, opts *Options) textCell {
+	switch {
+	case grade == "":
+		return textCell{"-", STYLE_BAD}
+	case sslscan.CompareGrades(grade, opts.MinGrade) >= 0:
+		return textCell{grade, STYLE_GOOD}
+	case sslscan.CompareGrades(grade, "C") >= 0:
+		return textCell{grade, STYLE_WARN}
+	}
+
+	return textCell{grade, STYLE_BAD}
+}
+
+// protocolsCells returns cells with supported protocols
+func protocolsCells(d *sslscan.EndpointDetails) []textCell {
+	var result []textCell
+
+	for _, p := range d.Protocols {
+		style := STYLE_NONE
+
+		if (p.Q != nil && *p.Q == 0) || p.ID < sslscan.PROTOCOL_TLS10 {
+			style = STYLE_BAD
+		}
+
+		result = append(result, textCell{sslscan.ProtocolName(p.ID), style})
+	}
+
+	return result
+}
+
+// weakSuitesCells returns cells with insecure and weak suites
+func weakSuitesCells(d *sslscan.EndpointDetails) []textCell {
+	var result []textCell
+
+	seen := make(map[string]bool)
+
+	for _, ps := range d.Suites {
+		for _, suite := range ps.List {
+			if suite.Q == nil || seen[suite.Name] {
+				continue
+			}
+
+			seen[suite.Name] = true
+			result = append(result, suiteCell(suite))
+		}
+	}
+
+	return result
+}
+
+// suiteCell returns cell with suite name
+func suiteCell(suite *sslscan.Suite) textCell {
+	switch {
+	case suite.Q == nil:
+		return textCell{text: suite.Name}
+	case *suite.Q == 0:
+		return textCell{suite.Name + " (insecure)", STYLE_BAD}
+	}
+
+	return textCell{suite.Name + " (weak)", STYLE_WARN}
+}
+
+// vulnsCells returns cells with found vulnerabilities
+func vulnsCells(d *sslscan.EndpointDetails) []textCell {
+	var result []textCell
+
+	for _, vuln := range sslscan.FindVulnerabilities(d) {
+		result = append(result, textCell{vuln.Name, STYLE_BAD})
+	}
+
+	return result
+}
+
+// vulnsCountCell returns cell with number of found vulnerabilities
+func vulnsCountCell(d *sslscan.EndpointDetails) textCell {
+	vulns := vulnsCells(d)
+
+	if len(vulns) == 0 {
+		return textCell{"0", STYLE_GOOD}
+	}
+
+	return textCell{strconv.Itoa(len(vulns)) + " (" + joinCells(vulns, ", ") + ")", STYLE_BAD}
+}
+
+// expiryCell returns cell with certificate expiry info
+func expiryCell(cert *sslscan.Cert, opts *Options, short bool) textCell {
+	notAfter := msToTime(cert.NotAfter)
+	days := int(notAfter.Sub(opts.Now).Hours() / 24)
+
+	style := STYLE_GOOD
+
+	switch {
+	case days < 0:
+		style = STYLE_BAD
+	case notAfter.Sub(opts.Now) < opts.CertExpiry || days < 14:
+		style = STYLE_WARN
+	}
+
+	if short {
+		return textCell{strconv.Itoa(days) + "d", style}
+	}
+
+	if days < 0 {
+		return textCell{fmt.Sprintf("%s (expired %d days ago)", notAfter.UTC().Format("2006-01-02"), -days), style}
+	}
+
+	return textCell{fmt.Sprintf("%s (%d days left)", notAfter.UTC().Format("2006-01-02"), days), style}
+}
+
+// caaCell returns cell with CAA status
+func caaCell(cert *sslscan.Cert) textCell {
+	if !cert.DNSCAA || cert.CAAPolicy == nil {
+		return textCell{"no", STYLE_WARN}
+	}
+
+	var records []string
+
+	for _, r := range cert.CAAPolicy.CAARecords {
+		records = append(records, r.Tag+"="+r.Value)
+	}
+
+	return textCell{"yes (" + strings.Join(records, ", ") + ")", STYLE_GOOD}
+}
+
+// hstsCell returns cell with HSTS status
+func hstsCell(policy *sslscan.HSTSPolicy) textCell {
+	switch {
+	case policy == nil:
+		return textCell{text: "-"}
+	case policy.Status != sslscan.HSTS_STATUS_PRESENT:
+		return textCell{policy.Status, STYLE_BAD}
+	}
+
+	style := STYLE_GOOD
+
+	if policy.LongMaxAge > 0 && policy.MaxAge < int64(policy.LongMaxAge) {
+		style = STYLE_WARN
+	}
+
+	return textCell{fmt.Sprintf("%s (max-age=%d)", policy.Status, policy.MaxAge), style}
+}
+
+// hpkpCell returns cell with HPKP status
+func hpkpCell(policy *sslscan.HPKPPolicy) textCell {
+	switch {
+	case policy == nil:
+		return textCell{text: "-"}
+	case policy.Status == sslscan.HPKP_STATUS_VALID:
+		return textCell{policy.Status, STYLE_GOOD}
+	case policy.Status == sslscan.HPKP_STATUS_INVALID || policy.Status == sslscan.HPKP_STATUS_ERROR:
+		return textCell{policy.Status, STYLE_BAD}
+	}
+
+	return textCell{text: policy.Status}
+}
+
+// boolCell returns cell with boolean value
+func boolCell(value bool) textCell {
+	if value {
+		return textCell{"yes", STYLE_GOOD}
+	}
+
+	return textCell{"no", STYLE_WARN}
+}
+
+// joinCells joins text of cells
+func joinCells(cells []textCell, sep string) string {
+	var result []string
+
+	for _, c := range cells {
+		result = append(result, c.text)
+	}
+
+	if len(result) == 0 {
+		return "-"
+	}
+
+	return strings.Join(result, sep)
+}
+
+// formatServerName formats server name for heading
+func formatServerName(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return " (" + name + ")"
+}
+
+// formatKeyExchange formats suite key exchange info
+func formatKeyExchange(suite *sslscan.Suite) string {
+	switch {
+	case suite.NamedGroupName != "":
+		return fmt.Sprintf("ECDH %s (%d bits)", suite.NamedGroupName, suite.NamedGroupBits)
+	case suite.DHBits != 0:
+		return fmt.Sprintf("DH %d bits", suite.DHBits)
+	}
+
+	return suite.KxType
+}
+
+// getIssuerName returns issuer common name from issuer subject
+func getIssuerName(cert *sslscan.Cert) string {
+	for _, part := range strings.Split(cert.IssuerSubject, ",") {
+		part = strings.TrimSpace(part)
+
+		if strings.HasPrefix(part, "CN=") {
+			return strings.TrimPrefix(part, "CN=")
+		}
+	}
+
+	return cert.IssuerSubject
+}
+
+// pad pads text with spaces to given width
+func pad(text string, width int, last bool) string {
+	if last {
+		return text
+	}
+
+	return text + strings.Repeat(" ", width-utf8.RuneCountInString(text)+2)
+}
@@ -0,0 +1,104 @@
+package report
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"strings"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ReportSuite) TestMarkdown(c *check.C) {
+	info := loadAssessment(c)
+	info.Endpoints[0].Details.Heartbleed = true
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(WriteMarkdown(buf, []*sslscan.AnalyzeInfo{info}, testOptions), check.IsNil)
+
+	md := buf.String()
+
+	c.Assert(md, check.Matches, "(?s)# essentialkaos.com:443\n\n.*## 5.79.108.150 \\(curie.kaos.cc\\)\n\n.*")
+
+	for _, expected := range []string{
+		"- **Grade:** A+\n",
+		"- **Protocols:** TLS 1.2, TLS 1.3\n",
+		"- **Weak suites:** TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA (weak), TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA (weak)\n",
+		"- **Vulnerabilities:** **heartbleed**\n",
+		"- **Certificate:** essentialkaos.com (issued by Let's Encrypt Authority X3)\n",
+		"- **Expiry:** 2020-11-30 (60 days left)\n",
+		"- **CAA:** yes (issue=letsencrypt.org)\n",
+		"- **HSTS:** present (max-age=31536000)\n",
+		"- **HPKP:** absent\n",
+	} {
+		c.Assert(strings.Contains(md, expected), check.Equals, true, check.Commentf("Report doesn't contain %q", expected))
+	}
+
+	c.Assert(strings.Contains(md, "### Suites"), check.Equals, false)
+
+	opts := *testOptions
+	opts.Verbosity = VERBOSITY_FULL
+
+	buf.Reset()
+
+	c.Assert(WriteMarkdown(buf, []*sslscan.AnalyzeInfo{info}, &opts), check.IsNil)
+
+	md = buf.String()
+
+	c.Assert(strings.Contains(md, "- **Chain:** essentialkaos.com → Let's Encrypt Authority X3\n"), check.Equals, true)
+	c.Assert(strings.Contains(md, "### Suites: TLS 1.3\n\n| Suite | Bits | Key exchange |\n|---|---|---|\n| TLS_AES_128_GCM_SHA256 | 128 | ECDH x25519 (256 bits) |\n"), check.Equals, true)
+	c.Assert(strings.Contains(md, "- **Failed handshakes:** Android 2.3.7, "), check.Equals, true)
+
+	failed := &sslscan.AnalyzeInfo{Host: "unknown.com", Status: sslscan.STATUS_ERROR, StatusMessage: "Unable to resolve domain name"}
+
+	buf.Reset()
+
+	c.Assert(WriteMarkdown(buf, []*sslscan.AnalyzeInfo{info, failed}, testOptions), check.IsNil)
+	c.Assert(buf.String(), check.Equals, ""+
+		"| Host | IP | Grade | Protocols | Vulnerabilities | Cert expiry | HSTS |\n"+
+		"|---|---|---|---|---|---|---|\n"+
+		"| essentialkaos.com | 5.79.108.150 | A+ | TLS 1.2, TLS 1.3 | **1 (heartbleed)** | 60d | present (max-age=31536000) |\n"+
+		"| unknown.com | - | **ERROR** | - | - | - | - |\n",
+	)
+}
+
+func (s *ReportSuite) TestText(c *check.C) {
+	info := loadAssessment(c)
+	failed := &sslscan.AnalyzeInfo{Host: "unknown.com", Status: sslscan.STATUS_ERROR, StatusMessage: "Unable to resolve domain name"}
+
+	opts := *testOptions
+	opts.NoColor = true
+	opts.Verbosity = VERBOSITY_SHORT
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(WriteText(buf, []*sslscan.AnalyzeInfo{info}, &opts), check.IsNil)
+	c.Assert(buf.String(), check.Equals, ""+
+		"HOST               IP            GRADE  PROTOCOLS         VULNERABILITIES  CERT EXPIRY  HSTS\n"+
+		"essentialkaos.com  5.79.108.150  A+     TLS 1.2, TLS 1.3  0                60d          present (max-age=31536000)\n",
+	)
+
+	opts.Verbosity = VERBOSITY_NORMAL
+
+	buf.Reset()
+
+	c.Assert(WriteText(buf, []*sslscan.AnalyzeInfo{failed}, &opts), check.IsNil)
+	c.Assert(buf.String(), check.Equals, "unknown.com:0\n    Status:            ERROR: Unable to resolve domain name\n")
+
+	buf.Reset()
+
+	c.Assert(WriteText(buf, []*sslscan.AnalyzeInfo{info}, testOptions), check.IsNil)
+	c.Assert(strings.Contains(buf.String(), "\033[1messentialkaos.com:443\033[0m\n"), check.Equals, true)
+	c.Assert(strings.Contains(buf.String(), "    Grade:             \033[32mA+\033[0m\n"), check.Equals, true)
+	c.Assert(strings.Contains(buf.String(), "\033[33mTLS_ECDHE_RSA_WITH_AES_256_CBC_SHA (weak)\033[0m"), check.Equals, true)
+}
@@ -0,0 +1,102 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SCANNER_SSLLABS is name of SSL Labs API backend
+const SCANNER_SSLLABS = "ssllabs"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Scanner is backend-neutral interface for TLS assessments. Any backend (SSL Labs
+// API, local scanner, etc) must return results using AnalyzeInfo model, so results
+// can be used with reports, checks and diff regardless of their source.
+type Scanner interface {
+	// Name returns backend name
+	Name() string
+
+	// Scan runs assessment of given host and waits until it's completed
+	Scan(host string, params AnalyzeParams) (*AnalyzeInfo, error)
+}
+
+// ProgressHandler is function for handling assessment progress
+type ProgressHandler func(host string, info *AnalyzeInfo)
+
+// APIScanner is scanner which uses SSL Labs API
+type APIScanner struct {
+	API      *API
+	Interval time.Duration   // interval between assessment status checks
+	Timeout  time.Duration   // max duration of assessment (optional)
+	Detailed bool            // retrieve detailed endpoints info
+	Progress ProgressHandler // optional progress handler
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewScanner creates new scanner which uses given API client
+func NewScanner(api *API) *APIScanner {
+	return &APIScanner{
+		API:      api,
+		Interval: 10 * time.Second,
+		Detailed: true,
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Name returns backend name
+func (s *APIScanner) Name() string {
+	return SCANNER_SSLLABS
+}
+
+// Scan runs assessment of given host and waits until it's completed
+func (s *APIScanner) Scan(host string, params AnalyzeParams) (*AnalyzeInfo, error) {
+	start := time.Now()
+	progress, err := s.API.Analyze(host, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		info, err := progress.Info(false, params.FromCache)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Progress != nil {
+			s.Progress(host, info)
+		}
+
+		switch info.Status {
+		case STATUS_ERROR:
+			return info, nil
+
+		case STATUS_READY:
+			if !s.Detailed {
+				return info, nil
+			}
+
+			// Cached detailed results can't be used for new assessment
+			return progress.Info(true, !params.StartNew)
+		}
+
+		if s.Timeout > 0 && time.Since(start)+s.Interval > s.Timeout {
+			return nil, fmt.Errorf("Assessment of %s is not completed within %v", host, s.Timeout)
+		}
+
+		time.Sleep(s.Interval)
+	}
+}
@@ -0,0 +1,99 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// stubTransport returns IN_PROGRESS status for first analyze requests and READY after
+type stubTransport struct {
+	steps int
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *SSLLabsSuite) TestAPIScanner(c *check.C) {
+	transport := &stubTransport{steps: 3}
+	api, err := NewAPIWithOptions("SSLScanTester", _TESTER_VERSION, Options{Transport: transport})
+
+	c.Assert(err, check.IsNil)
+
+	scanner := NewScanner(api)
+	statuses := []string{}
+
+	scanner.Interval = time.Millisecond
+	scanner.Progress = func(host string, info *AnalyzeInfo) {
+		statuses = append(statuses, info.Status)
+	}
+
+	c.Assert(Scanner(scanner).Name(), check.Equals, SCANNER_SSLLABS)
+
+	info, err := scanner.Scan("essentialkaos.com", AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Status, check.Equals, STATUS_READY)
+	c.Assert(info.Endpoints, check.HasLen, 1)
+	c.Assert(info.Endpoints[0].Details, check.NotNil)
+	c.Assert(statuses, check.DeepEquals, []string{STATUS_IN_PROGRESS, STATUS_IN_PROGRESS, STATUS_READY})
+
+	transport.steps = 0
+	scanner.Detailed = false
+
+	info, err = scanner.Scan("essentialkaos.com", AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Endpoints[0].Details, check.IsNil)
+
+	info, err = scanner.Scan("unknown.com", AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Status, check.Equals, STATUS_ERROR)
+
+	_, err = scanner.Scan("error.com", AnalyzeParams{})
+
+	c.Assert(err, check.ErrorMatches, "API return HTTP code 503")
+
+	transport.steps = 100
+	scanner.Timeout = 5 * time.Millisecond
+
+	_, err = scanner.Scan("essentialkaos.com", AnalyzeParams{})
+
+	c.Assert(err, check.ErrorMatches, "Assessment of essentialkaos.com is not completed within 5ms")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (t *stubTransport) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	uri := string(req.RequestURI())
+
+	switch {
+	case strings.Contains(uri, "/info"):
+		resp.SetBodyString(`{"engineVersion":"2.1.5","criteriaVersion":"2009q"}`)
+	case strings.Contains(uri, "host=error.com"):
+		resp.SetStatusCode(503)
+	case strings.Contains(uri, "host=unknown.com"):
+		resp.SetBodyString(`{"host":"unknown.com","status":"ERROR","statusMessage":"Unable to resolve domain name"}`)
+	case strings.Contains(uri, "all=on"):
+		resp.SetBodyString(`{"host":"essentialkaos.com","status":"READY","endpoints":[{"ipAddress":"5.79.108.150","grade":"A+","details":{}}]}`)
+	case t.steps > 0:
+		t.steps--
+		resp.SetBodyString(`{"host":"essentialkaos.com","status":"IN_PROGRESS"}`)
+	default:
+		resp.SetBodyString(`{"host":"essentialkaos.com","status":"READY","endpoints":[{"ipAddress":"5.79.108.150","grade":"A+"}]}`)
+	}
+
+	return nil
+}
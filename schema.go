@@ -0,0 +1,237 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// SchemaReport contains info about differences between JSON data and structs
+type SchemaReport struct {
+	Unknown []string // paths of JSON fields which are not modelled by structs
+	Missing []string // paths of struct fields which are not present in JSON data
+}
+
+// SchemaError is error returned by API in strict mode if response contains
+// unknown fields
+type SchemaError struct {
+	Report *SchemaReport
+}
+
+// schemaWalker collects info about JSON fields
+type schemaWalker struct {
+	seen    map[string]bool
+	known   map[string]bool
+	unknown map[string]bool
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DecodeStrict decodes JSON data to given struct and returns report with all
+// unknown and missing fields
+func DecodeStrict(data []byte, v interface{}) (*SchemaReport, error) {
+	err := json.Unmarshal(data, v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+
+	err = json.Unmarshal(data, &raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w := &schemaWalker{
+		seen:    make(map[string]bool),
+		known:   make(map[string]bool),
+		unknown: make(map[string]bool),
+	}
+
+	w.walk("", raw, reflect.TypeOf(v))
+
+	report := &SchemaReport{
+		Unknown: mapToSortedSlice(w.unknown),
+	}
+
+	for path := range w.known {
+		if !w.seen[path] {
+			report.Missing = append(report.Missing, path)
+		}
+	}
+
+	sort.Strings(report.Missing)
+
+	return report, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsEmpty returns true if report doesn't contain any unknown or missing fields
+func (r *SchemaReport) IsEmpty() bool {
+	return len(r.Unknown) == 0 && len(r.Missing) == 0
+}
+
+// Error returns error message
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf(
+		"Response contains unknown fields: %s",
+		strings.Join(e.Report.Unknown, ", "),
+	)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// decodeStrict decodes JSON data and returns SchemaError if data contains
+// unknown fields
+func decodeStrict(data []byte, v interface{}) error {
+	report, err := DecodeStrict(data, v)
+
+	if err != nil {
+		return err
+	}
+
+	if len(report.Unknown) != 0 {
+		return &SchemaError{report}
+	}
+
+	return nil
+}
+
+// walk compares JSON value with given type
+func (w *schemaWalker) walk(path string, value interface{}, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			w.walkStruct(path, v, t)
+		case reflect.Map:
+			for _, item := range v {
+				w.walk(path+".*", item, t.Elem())
+			}
+		}
+
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+
+		for _, item := range v {
+			w.walk(path+"[]", item, t.Elem())
+		}
+	}
+}
+
+// walkStruct compares JSON object with struct
+func (w *schemaWalker) walkStruct(path string, obj map[string]interface{}, t reflect.Type) {
+	fields := getJSONFields(t)
+
+	for name := range fields {
+		w.known[joinPath(path, name)] = true
+	}
+
+	for key, value := range obj {
+		field, ok := fields[key]
+
+		if !ok {
+			field, key, ok = findFieldFold(fields, key)
+		}
+
+		if !ok {
+			w.unknown[joinPath(path, key)] = true
+			continue
+		}
+
+		fieldPath := joinPath(path, key)
+		w.seen[fieldPath] = true
+
+		if value != nil {
+			w.walk(fieldPath, value, field.Type)
+		}
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getJSONFields returns map JSON name → struct field
+func getJSONFields(t reflect.Type) map[string]reflect.StructField {
+	result := make(map[string]reflect.StructField)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		tag := field.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		if tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		result[name] = field
+	}
+
+	return result
+}
+
+// findFieldFold finds struct field using case-insensitive comparison like
+// encoding/json does
+func findFieldFold(fields map[string]reflect.StructField, key string) (reflect.StructField, string, bool) {
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, name, true
+		}
+	}
+
+	return reflect.StructField{}, key, false
+}
+
+// joinPath joins parent path and field name
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// mapToSortedSlice returns sorted slice with map keys
+func mapToSortedSlice(m map[string]bool) []string {
+	var result []string
+
+	for k := range m {
+		result = append(result, k)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
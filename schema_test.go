@@ -0,0 +1,84 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *SSLLabsSuite) TestDecodeStrict(c *check.C) {
+	info := &Info{}
+	report, err := DecodeStrict([]byte(`{"engineVersion":"2.1.5","newField":1,"messages":["a"]}`), info)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.EngineVersion, check.Equals, "2.1.5")
+	c.Assert(report.Unknown, check.DeepEquals, []string{"newField"})
+	c.Assert(report.Missing, check.DeepEquals, []string{
+		"criteriaVersion", "currentAssessments", "maxAssessments", "newAssessmentCoolOff",
+	})
+	c.Assert(report.IsEmpty(), check.Equals, false)
+
+	endpoint := &EndpointInfo{}
+	report, err = DecodeStrict([]byte(`{"details":{"protocols":[{"id":771,"x":1}],"hstsPolicy":{"directives":{"max-age":"1"}}}}`), endpoint)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(report.Unknown, check.DeepEquals, []string{"details.protocols[].x"})
+
+	_, err = DecodeStrict([]byte(`{`), info)
+	c.Assert(err, check.NotNil)
+
+	err = decodeStrict([]byte(`{"newField":1}`), info)
+	c.Assert(err, check.ErrorMatches, "Response contains unknown fields: newField")
+}
+
+// TestSchemaDrift decodes all captured responses and reports fields which
+// are not modelled (or not present anymore) for every engine version. The
+// newest fixture must be fully covered by structs.
+func (s *SSLLabsSuite) TestSchemaDrift(c *check.C) {
+	files, err := filepath.Glob("responses/v3-*.json")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.Not(check.HasLen), 0)
+
+	var lastReport *SchemaReport
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+
+		c.Assert(err, check.IsNil)
+
+		report, err := DecodeStrict(data, &EndpointInfo{})
+
+		c.Assert(err, check.IsNil)
+
+		version := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "v3-"), ".json")
+
+		c.Logf("%s: unmodelled: %s", version, formatSchemaFields(report.Unknown))
+		c.Logf("%s: missing: %s", version, formatSchemaFields(report.Missing))
+
+		lastReport = report
+	}
+
+	c.Assert(lastReport.Unknown, check.HasLen, 0)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func formatSchemaFields(fields []string) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	return strings.Join(fields, ", ")
+}
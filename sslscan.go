@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -20,9 +21,10 @@ import (
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 const (
-	API_URL_INFO     = "https://api.ssllabs.com/api/v3/info"
-	API_URL_ANALYZE  = "https://api.ssllabs.com/api/v3/analyze"
-	API_URL_DETAILED = "https://api.ssllabs.com/api/v3/getEndpointData"
+	API_URL          = "https://api.ssllabs.com/api/v3"
+	API_URL_INFO     = API_URL + "/info"
+	API_URL_ANALYZE  = API_URL + "/analyze"
+	API_URL_DETAILED = API_URL + "/getEndpointData"
 )
 
 const (
@@ -72,6 +74,20 @@ const (
 	POODLE_STATUS_VULNERABLE        = 2
 )
 
+const (
+	PADDING_ORACLE_STATUS_FAILED                      = -1
+	PADDING_ORACLE_STATUS_UNKNOWN                     = 0
+	PADDING_ORACLE_STATUS_NOT_VULNERABLE              = 1
+	PADDING_ORACLE_STATUS_ZOMBIE_POODLE_VULNERABLE    = 2
+	PADDING_ORACLE_STATUS_ZOMBIE_POODLE_EXPLOITABLE   = 3
+	PADDING_ORACLE_STATUS_GOLDEN_DOODLE_VULNERABLE    = 4
+	PADDING_ORACLE_STATUS_GOLDEN_DOODLE_EXPLOITABLE   = 5
+	PADDING_ORACLE_STATUS_ZERO_LENGTH_VULNERABLE      = 6
+	PADDING_ORACLE_STATUS_ZERO_LENGTH_EXPLOITABLE     = 7
+	PADDING_ORACLE_STATUS_SLEEPING_POODLE_VULNERABLE  = 10
+	PADDING_ORACLE_STATUS_SLEEPING_POODLE_EXPLOITABLE = 11
+)
+
 const (
 	REVOCATION_STATUS_NOT_CHECKED            = 0
 	REVOCATION_STATUS_REVOKED                = 1
@@ -81,6 +97,18 @@ const (
 	REVOCATION_STATUS_INTERNAL_INFO          = 5
 )
 
+const (
+	SESSION_RESUMPTION_DISABLED    = 0
+	SESSION_RESUMPTION_NOT_RESUMED = 1
+	SESSION_RESUMPTION_ENABLED     = 2
+)
+
+const (
+	SESSION_TICKETS_SUPPORTED = 1 << iota
+	SESSION_TICKETS_FAULTY
+	SESSION_TICKETS_INTOLERANT
+)
+
 const (
 	HSTS_STATUS_UNKNOWN  = "unknown"
 	HSTS_STATUS_ABSENT   = "absent"
@@ -139,6 +167,19 @@ const (
 	CERT_CHAIN_ISSUE_CANT_VALIDATE
 )
 
+const (
+	CERT_ISSUE_NO_CHAIN_OF_TRUST = 1 << iota
+	CERT_ISSUE_NOT_BEFORE
+	CERT_ISSUE_NOT_AFTER
+	CERT_ISSUE_HOSTNAME_MISMATCH
+	CERT_ISSUE_REVOKED
+	CERT_ISSUE_BAD_COMMON_NAME
+	CERT_ISSUE_SELF_SIGNED
+	CERT_ISSUE_BLACKLISTED
+	CERT_ISSUE_INSECURE_SIGNATURE
+	CERT_ISSUE_INSECURE_KEY
+)
+
 const (
 	PROTOCOL_SSL2  = 512
 	PROTOCOL_SSL3  = 768
@@ -154,8 +195,31 @@ const VERSION = "12.0.0"
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 type API struct {
-	Info   *Info
-	Client *fasthttp.Client
+	Info      *Info
+	Client    *fasthttp.Client
+	Transport Transport // optional custom transport, Client is used if not set
+	Cache     *Cache    // optional client-side cache for assessments results
+
+	url       string
+	userAgent string
+	strict    bool
+}
+
+// Transport is generic transport for API requests
+type Transport interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Options contains API client options
+type Options struct {
+	URL       string    // base API URL (API_URL by default)
+	Transport Transport // custom transport (e.g. for recording and replaying responses)
+	Strict    bool      // return SchemaError if response contains unknown fields
+}
+
+// APIError is error returned if API responded with non-200 status code
+type APIError struct {
+	StatusCode int // HTTP status code
 }
 
 type AnalyzeParams struct {
@@ -170,7 +234,9 @@ type AnalyzeProgress struct {
 	host       string
 	prevStatus string
 
-	maxAge int
+	maxAge      int
+	startNew    bool
+	cacheExpiry time.Time
 
 	api *API
 }
@@ -184,6 +250,8 @@ type Info struct {
 	CurrentAssessments   int      `json:"currentAssessments"`   // the number of ongoing assessments submitted by this client
 	NewAssessmentCoolOff int      `json:"newAssessmentCoolOff"` // he cool-off period after each new assessment; you're not allowed to submit a new assessment before the cool-off expires, otherwise you'll get a 429
 	Messages             []string `json:"messages"`             // a list of messages (strings). Messages can be public (sent to everyone) and private (sent only to the invoking client). Private messages are prefixed with "[Private]".
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type AnalyzeInfo struct {
@@ -201,6 +269,8 @@ type AnalyzeInfo struct {
 	CertHostnames   []string        `json:"certHostnames"`   // the list of certificate hostnames collected from the certificates seen during assessment
 	Endpoints       []*EndpointInfo `json:"endpoints"`       // list of Endpoint structs
 	Certs           []*Cert         `json:"certs"`           // a list of Cert structs, representing the chain certificates in the order in which they were retrieved from the server
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type EndpointInfo struct {
@@ -219,6 +289,8 @@ type EndpointInfo struct {
 	ETA                  int              `json:"eta"`                  // estimated time, in seconds, until the completion of the assessment
 	Delegation           int              `json:"delegation"`           // indicates domain name delegation with and without the www prefix
 	Details              *EndpointDetails `json:"details"`              // this field contains an EndpointDetails struct. It's not present by default, but can be enabled by using the "all" paramerer to the analyze API call
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type EndpointDetails struct {
@@ -287,6 +359,8 @@ type EndpointDetails struct {
 	DrownVulnerable                bool               `json:"drownVulnerable"`                // true if server vulnerable to drown attack
 	ImplementsTLS13MandatoryCS     bool               `json:"implementsTLS13MandatoryCS"`     // true if server supports mandatory TLS 1.3 cipher suite (TLS_AES_128_GCM_SHA256), null if TLS 1.3 not supported
 	ZeroRTTEnabled                 int                `json:"zeroRTTEnabled"`                 // results of the 0-RTT test
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type Cert struct {
@@ -320,6 +394,8 @@ type Cert struct {
 	KeyStrength            int        `json:"keyStrength"`            // key strength, in equivalent RSA bits
 	KeyKnownDebianInsecure bool       `json:"keyKnownDebianInsecure"` // true if debian flaw is found, else false
 	Raw                    string     `json:"raw"`                    // PEM-encoded certificate
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type ChainCert struct {
@@ -328,22 +404,30 @@ type ChainCert struct {
 	TrustPaths []*TrustPath `json:"trustPaths"` // trust path object
 	Issues     int          `json:"issues"`     // a number of flags that describe the chain and the problems it has
 	NoSNI      bool         `json:"noSni"`      // true for certificate obtained only with No Server Name Indication (SNI)
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type TrustPath struct {
 	CertIDs []string      `json:"certIds"` // list of certificate ID from leaf to root
 	Trust   []*TrustStore `json:"trust"`   // trust object. This object shows info about the trusted certificate by using Mozilla trust store
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type TrustStore struct {
 	RootStore         string `json:"rootStore"`         // this field shows the Trust store being used (eg. "Mozilla")
 	IsTrusted         bool   `json:"isTrusted"`         // true if trusted against above rootStore
 	TrustErrorMessage string `json:"trustErrorMessage"` // shows the error message if any
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type NamedGroups struct {
 	List       []NamedGroup `json:"list"`       // an slice of NamedGroup structs
 	Preference bool         `json:"preference"` // true if the server has preferred curves that it uses first
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type NamedGroup struct {
@@ -351,6 +435,8 @@ type NamedGroup struct {
 	Name           string `json:"name"`           // named curve name
 	Bits           int    `json:"bits"`           // named curve strength in EC bits
 	NamedGroupType string `json:"namedGroupType"` // -
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type Protocol struct {
@@ -359,12 +445,17 @@ type Protocol struct {
 	Version          string `json:"version"`          // protocol version, e.g. 1.2 (for TLS)
 	V2SuitesDisabled bool   `json:"v2SuitesDisabled"` // some servers have SSLv2 protocol enabled, but with all SSLv2 cipher suites disabled
 	Q                *int   `json:"q"`                // 0 if the protocol is insecure, null otherwise
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type ProtocolSuites struct {
-	Protocol   int      `json:"protocol"`   // protocol version
-	List       []*Suite `json:"list"`       // list of Suite structs
-	Preference bool     `json:"preference"` // true if the server actively selects cipher suites
+	Protocol           int      `json:"protocol"`           // protocol version
+	List               []*Suite `json:"list"`               // list of Suite structs
+	Preference         bool     `json:"preference"`         // true if the server actively selects cipher suites
+	ChaCha20Preference bool     `json:"chaCha20Preference"` // true if the server takes into account client preferences when deciding if to use ChaCha20 suites
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type Suite struct {
@@ -381,10 +472,14 @@ type Suite struct {
 	NamedGroupID   int    `json:"namedGroupId"`   // EC curve ID
 	NamedGroupName string `json:"namedGroupName"` // EC curve name
 	Q              *int   `json:"q"`              // 0 if the suite is insecure, null otherwise
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type SIMS struct {
 	Results []*SIM `json:"results"`
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type SIM struct {
@@ -408,6 +503,8 @@ type SIM struct {
 	KeyAlg         string     `json:"keyAlg"`         // connection certificate key algorithms (e.g., "RSA")
 	KeySize        int        `json:"keySize"`        // connection certificate key size (e.g., 2048)
 	SigAlg         string     `json:"sigAlg"`         // connection certificate signature algorithm (e.g, "SHA256withRSA")
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type SimClient struct {
@@ -416,6 +513,8 @@ type SimClient struct {
 	Platform    string `json:"platform"`    // name of the platform (e.g., XP SP3)
 	Version     string `json:"version"`     // version of the software being simulated (e.g., 49)
 	IsReference bool   `json:"isReference"` // true if the browser is considered representative of modern browsers, false otherwise
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type HSTSPolicy struct {
@@ -427,6 +526,8 @@ type HSTSPolicy struct {
 	IncludeSubDomains bool              `json:"includeSubDomains"` // true if the includeSubDomains directive is set; null otherwise
 	Preload           bool              `json:"preload"`           // true if the preload directive is set; null otherwise
 	Directives        map[string]string `json:"directives"`        // list of raw policy directives
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type HSTSPreload struct {
@@ -435,6 +536,8 @@ type HSTSPreload struct {
 	Status     string `json:"status"`     // preload status
 	Error      string `json:"error"`      // error message, when status is "error"
 	SourceTime int64  `json:"sourceTime"` // time, as a Unix timestamp, when the preload database was retrieved
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type HPKPPolicy struct {
@@ -447,6 +550,8 @@ type HPKPPolicy struct {
 	Pins              []Pin       `json:"pins"`              // list of all pins used by the policy
 	MatchedPins       []Pin       `json:"matchedPins"`       // list of pins that match the current configuration
 	Directives        []Directive `json:"directives"`        // list of raw policy directives
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type SPKPPolicy struct {
@@ -458,16 +563,22 @@ type SPKPPolicy struct {
 	MatchedPINs          []Pin  `json:"matchedPins"`          // list of pins that match the current configuration
 	ForbiddenPINs        []Pin  `json:"forbiddenPins"`        // list of all forbidden pins used by policy
 	MatchedForbiddenPINs []Pin  `json:"matchedForbiddenPins"` // list of forbidden pins that match the current configuration
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type Pin struct {
 	HashFunction string `json:"hashFunction"`
 	Value        string `json:"value"`
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type Directive struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type DrownHost struct {
@@ -477,17 +588,23 @@ type DrownHost struct {
 	Special bool   `json:"special"` // true if vulnerable OpenSSL version detected
 	SSLv2   bool   `json:"sslv2"`   // true if SSL v2 is supported
 	Status  string `json:"status"`  // drown host status
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type CAAPolicy struct {
 	PolicyHostname string      `json:"policyHostname"` // hostname where policy is located
 	CAARecords     []CAARecord `json:"caaRecords"`     // list of Supported CAARecords
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type CAARecord struct {
 	Tag   string `json:"tag"`   // a property of the CAA record
 	Value string `json:"value"` // corresponding value of a CAA property
 	Flags int    `json:"flags"` // corresponding flags of CAA property
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type HTTPTransaction struct {
@@ -499,11 +616,15 @@ type HTTPTransaction struct {
 	ResponseHeadersRaw []string     `json:"responseHeadersRaw"` // all response headers as a single field (useful if the headers are malformed)
 	ResponseHeaders    []HTTPHeader `json:"responseHeaders"`    // a slice of response HTTP headers
 	FragileServer      bool         `json:"fragileServer"`      // true if the server crashes when inspected by SSL Labs
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 type HTTPHeader struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
+
+	Extra map[string]json.RawMessage `json:"-"` // fields which are not modelled by struct
 }
 
 // RequestTimeout is request timeout in seconds
@@ -513,22 +634,34 @@ var RequestTimeout = 10.0
 
 // NewAPI create new api struct
 func NewAPI(app, version string) (*API, error) {
+	return NewAPIWithOptions(app, version, Options{})
+}
+
+// NewAPIWithOptions create new api struct with given options
+func NewAPIWithOptions(app, version string, options Options) (*API, error) {
 	if app == "" {
 		return nil, fmt.Errorf("App name can't be empty")
 	}
 
+	userAgent := getUserAgent(app, version)
+	url := strings.TrimRight(options.URL, "/")
+
 	api := &API{
 		Client: &fasthttp.Client{
-			Name:                getUserAgent(app, version),
+			Name:                userAgent,
 			MaxIdleConnDuration: 5 * time.Second,
 			ReadTimeout:         time.Duration(RequestTimeout) * time.Second,
 			WriteTimeout:        time.Duration(RequestTimeout) * time.Second,
 			MaxConnsPerHost:     100,
 		},
+		Transport: options.Transport,
+		url:       url,
+		userAgent: userAgent,
+		strict:    options.Strict,
 	}
 
 	info := &Info{}
-	err := api.doRequest(API_URL_INFO, info)
+	_, err := api.doRequest(api.getURL("/info"), info)
 
 	if err != nil {
 		return nil, err
@@ -543,11 +676,11 @@ func NewAPI(app, version string) (*API, error) {
 
 // Analyze start check for host
 func (api *API) Analyze(host string, params AnalyzeParams) (*AnalyzeProgress, error) {
-	progress := &AnalyzeProgress{host: host, api: api, maxAge: params.MaxAge}
+	progress := &AnalyzeProgress{host: host, api: api, maxAge: params.MaxAge, startNew: params.StartNew}
 	query := "host=" + host
 	query += "&" + paramsToQuery(params)
 
-	err := api.doRequest(API_URL_ANALYZE+"?"+query, nil)
+	_, err := api.doRequest(api.getURL("/analyze?"+query), nil)
 
 	if err != nil {
 		return nil, err
@@ -564,6 +697,8 @@ func (ap *AnalyzeProgress) Info(detailed, fromCache bool) (*AnalyzeInfo, error)
 		query += "&all=on"
 	}
 
+	cacheKey := ap.getCacheKey("/analyze?" + query)
+
 	if fromCache {
 		query += "&fromCache=on"
 
@@ -572,14 +707,27 @@ func (ap *AnalyzeProgress) Info(detailed, fromCache bool) (*AnalyzeInfo, error)
 		}
 	}
 
+	uri := ap.api.getURL("/analyze?" + query)
 	info := &AnalyzeInfo{}
-	err := ap.api.doRequest(API_URL_ANALYZE+"?"+query, info)
+
+	if fromCache && ap.getFromCache(cacheKey, info) {
+		ap.prevStatus = info.Status
+		ap.cacheExpiry = msToTime(info.CacheExpiryTime)
+		return info, nil
+	}
+
+	data, err := ap.api.doRequest(uri, info)
 
 	if err != nil {
 		return nil, err
 	}
 
 	ap.prevStatus = info.Status
+	ap.cacheExpiry = msToTime(info.CacheExpiryTime)
+
+	if info.Status == STATUS_READY {
+		ap.saveToCache(cacheKey, data, ap.cacheExpiry)
+	}
 
 	return info, nil
 }
@@ -588,6 +736,24 @@ func (ap *AnalyzeProgress) Info(detailed, fromCache bool) (*AnalyzeInfo, error)
 func (ap *AnalyzeProgress) GetEndpointInfo(ip string, fromCache bool) (*EndpointInfo, error) {
 	var err error
 
+	query := "host=" + ap.host + "&s=" + ip
+	cacheKey := ap.getCacheKey("/getEndpointData?" + query)
+
+	if fromCache {
+		query += "&fromCache=on"
+
+		if ap.maxAge > 0 {
+			query += "&maxAge=" + fmt.Sprintf("%d", ap.maxAge)
+		}
+	}
+
+	uri := ap.api.getURL("/getEndpointData?" + query)
+	info := &EndpointInfo{}
+
+	if fromCache && ap.getFromCache(cacheKey, info) {
+		return info, nil
+	}
+
 	if ap.prevStatus != STATUS_READY {
 		_, err = ap.Info(false, false)
 
@@ -600,57 +766,131 @@ func (ap *AnalyzeProgress) GetEndpointInfo(ip string, fromCache bool) (*Endpoint
 		}
 	}
 
-	query := "host=" + ap.host + "&s=" + ip
+	data, err := ap.api.doRequest(uri, info)
 
-	if fromCache {
-		query += "&fromCache=on"
+	if err != nil {
+		return nil, err
+	}
 
-		if ap.maxAge > 0 {
-			query += "&maxAge=" + fmt.Sprintf("%d", ap.maxAge)
-		}
+	ap.saveToCache(cacheKey, data, ap.cacheExpiry)
+
+	return info, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getCacheKey returns client-side cache key for given API method. Cache is used
+// only for requests with fromCache flag and max age is checked by cache itself,
+// so key contains only fromCache flag.
+func (ap *AnalyzeProgress) getCacheKey(method string) string {
+	return ap.api.getURL(method + "&fromCache=on")
+}
+
+// getFromCache tries to read response from client-side cache. Cache is
+// bypassed for new assessments, so older results never replace them.
+func (ap *AnalyzeProgress) getFromCache(key string, result interface{}) bool {
+	if ap.api.Cache == nil || ap.startNew {
+		return false
 	}
 
-	info := &EndpointInfo{}
-	err = ap.api.doRequest(API_URL_DETAILED+"?"+query, info)
+	return ap.api.Cache.get(key, time.Duration(ap.maxAge)*time.Hour, result)
+}
 
-	if err != nil {
-		return nil, err
+// saveToCache saves response to client-side cache
+func (ap *AnalyzeProgress) saveToCache(key string, data []byte, expiry time.Time) {
+	if ap.api.Cache == nil {
+		return
 	}
 
-	return info, nil
+	ap.api.Cache.Set(key, data, expiry)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Error returns error message
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API return HTTP code %d", e.StatusCode)
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
-// doRequest sends request through http client
-func (api *API) doRequest(uri string, result interface{}) error {
+// ProtocolName returns protocol name (e.g. "TLS 1.2") by ID
+func ProtocolName(id int) string {
+	switch id {
+	case PROTOCOL_SSL2:
+		return "SSL 2.0"
+	case PROTOCOL_SSL3:
+		return "SSL 3.0"
+	case PROTOCOL_TLS10:
+		return "TLS 1.0"
+	case PROTOCOL_TLS11:
+		return "TLS 1.1"
+	case PROTOCOL_TLS12:
+		return "TLS 1.2"
+	case PROTOCOL_TLS13:
+		return "TLS 1.3"
+	}
+
+	return fmt.Sprintf("0x%04x", id)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getURL returns full URL for given API method
+func (api *API) getURL(method string) string {
+	if api.url == "" {
+		return API_URL + method
+	}
+
+	return api.url + method
+}
+
+// doRequest sends request through http client and returns raw response data
+func (api *API) doRequest(uri string, result interface{}) ([]byte, error) {
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
 
 	req.SetRequestURI(uri)
+	req.Header.SetUserAgent(api.userAgent)
 
 	defer fasthttp.ReleaseRequest(req)
 	defer fasthttp.ReleaseResponse(resp)
 
-	err := api.Client.Do(req, resp)
+	var err error
+
+	if api.Transport != nil {
+		err = api.Transport.Do(req, resp)
+	} else {
+		err = api.Client.Do(req, resp)
+	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	statusCode := resp.StatusCode()
 
 	if statusCode != 200 {
-		return fmt.Errorf("API return HTTP code %d", statusCode)
+		return nil, &APIError{statusCode}
 	}
 
 	if result == nil {
-		return nil
+		return nil, nil
 	}
 
-	err = json.Unmarshal(resp.Body(), result)
+	data := append([]byte(nil), resp.Body()...)
 
-	return err
+	if api.strict {
+		return data, decodeStrict(data, result)
+	}
+
+	err = json.Unmarshal(data, result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -686,6 +926,15 @@ func paramsToQuery(params AnalyzeParams) string {
 	return ""
 }
 
+// msToTime converts timestamp in milliseconds to time
+func msToTime(ms int64) time.Time {
+	if ms <= 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
 // getUserAgent generate user-agent string for client
 func getUserAgent(app, version string) string {
 	if app != "" && version != "" {
@@ -9,9 +9,13 @@ package sslscan
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"pkg.re/essentialkaos/sslscan.v12/cassette"
+
 	check "pkg.re/check.v1"
 )
 
@@ -19,11 +23,16 @@ import (
 
 const _TESTER_VERSION = "9.0.0"
 
+// _CASSETTE_DIR is directory with recorded API responses
+const _CASSETTE_DIR = "testdata/cassettes"
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 func Test(t *testing.T) { check.TestingT(t) }
 
-type SSLLabsSuite struct{}
+type SSLLabsSuite struct {
+	recorder *cassette.Recorder
+}
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
@@ -31,8 +40,31 @@ var _ = check.Suite(&SSLLabsSuite{})
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// SetUpSuite configures cassette for replaying recorded API responses. Responses
+// can be recorded from real API by setting SSLSCAN_CASSETTE_MODE environment
+// variable to "record".
+func (s *SSLLabsSuite) SetUpSuite(c *check.C) {
+	mode := cassette.MODE_REPLAY
+
+	if os.Getenv("SSLSCAN_CASSETTE_MODE") == cassette.MODE_RECORD {
+		mode = cassette.MODE_RECORD
+	}
+
+	recorder, err := cassette.New(_CASSETTE_DIR, mode)
+
+	if err != nil {
+		c.Fatalf("Can't create cassette: %v", err)
+	}
+
+	s.recorder = recorder
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 func (s *SSLLabsSuite) TestInfo(c *check.C) {
-	api, err := NewAPI("SSLScanTester", _TESTER_VERSION)
+	s.skipIfNotRecorded(c)
+
+	api, err := NewAPIWithOptions("SSLScanTester", _TESTER_VERSION, Options{Transport: s.recorder})
 
 	RequestTimeout = 3.0
 
@@ -44,7 +76,9 @@ func (s *SSLLabsSuite) TestInfo(c *check.C) {
 }
 
 func (s *SSLLabsSuite) TestAnalyze(c *check.C) {
-	api, err := NewAPI("SSLScanTester", _TESTER_VERSION)
+	s.skipIfNotRecorded(c)
+
+	api, err := NewAPIWithOptions("SSLScanTester", _TESTER_VERSION, Options{Transport: s.recorder})
 
 	RequestTimeout = 3.0
 
@@ -76,7 +110,9 @@ func (s *SSLLabsSuite) TestAnalyze(c *check.C) {
 
 		fmt.Printf("∙")
 
-		time.Sleep(5 * time.Second)
+		if !s.recorder.IsReplaying() {
+			time.Sleep(5 * time.Second)
+		}
 	}
 
 	fmt.Println(" DONE")
@@ -303,3 +339,19 @@ func (s *SSLLabsSuite) TestAnalyze(c *check.C) {
 	c.Assert(certs[0].KeyKnownDebianInsecure, check.Equals, false)
 	c.Assert(certs[0].Raw, check.Not(check.Equals), "")
 }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// skipIfNotRecorded skips test if recorder is replaying responses, but nothing
+// was recorded yet
+func (s *SSLLabsSuite) skipIfNotRecorded(c *check.C) {
+	if !s.recorder.IsReplaying() {
+		return
+	}
+
+	files, _ := filepath.Glob(filepath.Join(_CASSETTE_DIR, "*.json"))
+
+	if len(files) == 0 {
+		c.Skip("API responses are not recorded (use SSLSCAN_CASSETTE_MODE=record)")
+	}
+}
@@ -0,0 +1,367 @@
+// Package sslscantest provides fake SSL Labs API server for tests
+package sslscantest
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Server is fake SSL Labs API server
+type Server struct {
+	URL string // base API URL for using with sslscan.Options
+
+	Info          *sslscan.Info // data returned by /info
+	DNSSteps      int           // number of polls with DNS status
+	ProgressSteps int           // number of polls with IN_PROGRESS status
+
+	srv         *httptest.Server
+	hosts       map[string]*sslscan.AnalyzeInfo
+	assessments map[string]*assessment
+	errors      []int
+	requests    int
+	mx          *sync.Mutex
+}
+
+// assessment contains state of running assessment
+type assessment struct {
+	polls     int
+	startTime int64
+}
+
+// apiError is API error response
+type apiError struct {
+	Errors []apiErrorMessage `json:"errors"`
+}
+
+// apiErrorMessage is API error message
+type apiErrorMessage struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewServer creates and starts new fake SSL Labs API server
+func NewServer() *Server {
+	s := &Server{
+		Info: &sslscan.Info{
+			EngineVersion:        "2.1.5",
+			CriteriaVersion:      "2009q",
+			MaxAssessments:       25,
+			NewAssessmentCoolOff: 1000,
+			Messages:             []string{},
+		},
+		DNSSteps:      1,
+		ProgressSteps: 3,
+
+		hosts:       make(map[string]*sslscan.AnalyzeInfo),
+		assessments: make(map[string]*assessment),
+		mx:          &sync.Mutex{},
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v3/info", s.handleInfo)
+	mux.HandleFunc("/api/v3/analyze", s.handleAnalyze)
+	mux.HandleFunc("/api/v3/getEndpointData", s.handleEndpointData)
+
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL + "/api/v3"
+
+	return s
+}
+
+// LoadEndpoint loads endpoint info from JSON file (e.g. getEndpointData response)
+func LoadEndpoint(file string) (*sslscan.EndpointInfo, error) {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &sslscan.EndpointInfo{}
+	err = json.Unmarshal(data, endpoint)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode endpoint data from %s: %v", file, err)
+	}
+
+	return endpoint, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// NewAPI creates new API client connected to fake server
+func (s *Server) NewAPI(app, version string) (*sslscan.API, error) {
+	return sslscan.NewAPIWithOptions(app, version, sslscan.Options{URL: s.URL})
+}
+
+// AddHost adds host with given final assessment result. Intermediate
+// assessment states are generated from it.
+func (s *Server) AddHost(info *sslscan.AnalyzeInfo) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.hosts[info.Host] = info
+	delete(s.assessments, info.Host)
+}
+
+// AddHostFromFiles adds host with endpoints loaded from given JSON files
+func (s *Server) AddHostFromFiles(host string, files ...string) error {
+	info := &sslscan.AnalyzeInfo{
+		Host:     host,
+		Port:     443,
+		Protocol: "http",
+		Status:   sslscan.STATUS_READY,
+	}
+
+	for _, file := range files {
+		endpoint, err := LoadEndpoint(file)
+
+		if err != nil {
+			return err
+		}
+
+		info.Endpoints = append(info.Endpoints, endpoint)
+	}
+
+	s.AddHost(info)
+
+	return nil
+}
+
+// InjectError makes server respond with given HTTP status code to next
+// N requests (e.g. 429, 503 or 529)
+func (s *Server) InjectError(statusCode, count int) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for i := 0; i < count; i++ {
+		s.errors = append(s.errors, statusCode)
+	}
+}
+
+// Requests returns number of requests handled by server
+func (s *Server) Requests() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.requests
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// handleInfo is handler for /info requests
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.processRequest(w) {
+		return
+	}
+
+	info := *s.Info
+	info.CurrentAssessments = len(s.assessments)
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleAnalyze is handler for /analyze requests
+func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.processRequest(w) {
+		return
+	}
+
+	query := r.URL.Query()
+	host := query.Get("host")
+
+	if host == "" {
+		writeError(w, http.StatusBadRequest, "host", "Parameter is required")
+		return
+	}
+
+	a := s.assessments[host]
+
+	if a == nil || query.Get("startNew") == "on" {
+		a = &assessment{startTime: now()}
+		s.assessments[host] = a
+	} else {
+		a.polls++
+	}
+
+	writeJSON(w, http.StatusOK, s.getAssessmentState(host, a, query.Get("all") == "on"))
+}
+
+// handleEndpointData is handler for /getEndpointData requests
+func (s *Server) handleEndpointData(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.processRequest(w) {
+		return
+	}
+
+	query := r.URL.Query()
+	host, ip := query.Get("host"), query.Get("s")
+
+	switch {
+	case host == "":
+		writeError(w, http.StatusBadRequest, "host", "Parameter is required")
+		return
+	case ip == "":
+		writeError(w, http.StatusBadRequest, "s", "Parameter is required")
+		return
+	}
+
+	info, a := s.hosts[host], s.assessments[host]
+
+	if info == nil || a == nil || a.polls < s.DNSSteps+s.ProgressSteps {
+		writeError(w, http.StatusBadRequest, "", "Assessment is not ready")
+		return
+	}
+
+	for _, endpoint := range info.Endpoints {
+		if endpoint.IPAdress == ip {
+			writeJSON(w, http.StatusOK, endpoint)
+			return
+		}
+	}
+
+	writeError(w, http.StatusBadRequest, "s", "Endpoint not found")
+}
+
+// processRequest counts request and writes injected error if it's queued
+func (s *Server) processRequest(w http.ResponseWriter) bool {
+	s.requests++
+
+	if len(s.errors) == 0 {
+		return false
+	}
+
+	statusCode := s.errors[0]
+	s.errors = s.errors[1:]
+
+	writeError(w, statusCode, "", http.StatusText(statusCode))
+
+	return true
+}
+
+// getAssessmentState returns current state of assessment
+func (s *Server) getAssessmentState(host string, a *assessment, detailed bool) *sslscan.AnalyzeInfo {
+	final := s.hosts[host]
+
+	info := &sslscan.AnalyzeInfo{
+		Host:            host,
+		Port:            443,
+		Protocol:        "http",
+		StartTime:       a.startTime,
+		EngineVersion:   s.Info.EngineVersion,
+		CriteriaVersion: s.Info.CriteriaVersion,
+	}
+
+	switch {
+	case a.polls < s.DNSSteps:
+		info.Status = sslscan.STATUS_DNS
+		info.StatusMessage = "Resolving domain names"
+		return info
+
+	case final == nil:
+		info.Status = sslscan.STATUS_ERROR
+		info.StatusMessage = "Unable to resolve domain name"
+		return info
+	}
+
+	if final.Port != 0 {
+		info.Port = final.Port
+	}
+
+	if final.Protocol != "" {
+		info.Protocol = final.Protocol
+	}
+
+	info.CertHostnames = final.CertHostnames
+
+	progress := a.polls - s.DNSSteps
+
+	if progress < s.ProgressSteps {
+		info.Status = sslscan.STATUS_IN_PROGRESS
+
+		for _, endpoint := range final.Endpoints {
+			info.Endpoints = append(info.Endpoints, &sslscan.EndpointInfo{
+				IPAdress:             endpoint.IPAdress,
+				ServerName:           endpoint.ServerName,
+				StatusMessage:        "In progress",
+				StatusDetails:        "TESTING_PROTOCOLS",
+				StatusDetailsMessage: "Testing protocols",
+				Progress:             progress * 100 / s.ProgressSteps,
+				ETA:                  s.ProgressSteps - progress,
+				Delegation:           endpoint.Delegation,
+			})
+		}
+
+		return info
+	}
+
+	info.Status = sslscan.STATUS_READY
+	info.TestTime = a.startTime + int64(s.ProgressSteps)*1000
+	info.CacheExpiryTime = info.TestTime + int64(time.Hour/time.Millisecond)
+	info.Certs = final.Certs
+
+	for _, endpoint := range final.Endpoints {
+		e := *endpoint
+		e.StatusMessage = "Ready"
+		e.Progress = 100
+
+		if !detailed {
+			e.Details = nil
+		}
+
+		info.Endpoints = append(info.Endpoints, &e)
+	}
+
+	return info
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// writeJSON encodes data as JSON and writes it to response
+func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(data)
+}
+
+// writeError writes API error to response
+func writeError(w http.ResponseWriter, statusCode int, field, message string) {
+	writeJSON(w, statusCode, apiError{[]apiErrorMessage{{field, message}}})
+}
+
+// now returns current time in milliseconds since 1970
+func now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,243 @@
+package sslscantest
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"testing"
+
+	"pkg.re/essentialkaos/sslscan.v12"
+
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type ServerSuite struct {
+	srv *Server
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = check.Suite(&ServerSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ServerSuite) SetUpTest(c *check.C) {
+	s.srv = NewServer()
+
+	err := s.srv.AddHostFromFiles("essentialkaos.com", "../responses/v3-2.1.3-2009q.json")
+
+	c.Assert(err, check.IsNil)
+}
+
+func (s *ServerSuite) TearDownTest(c *check.C) {
+	s.srv.Close()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ServerSuite) TestInfo(c *check.C) {
+	s.srv.Info.MaxAssessments = 5
+
+	api, err := s.srv.NewAPI("SSLScanTester", "1.0.0")
+
+	c.Assert(err, check.IsNil)
+	c.Assert(api.Info.EngineVersion, check.Equals, "2.1.5")
+	c.Assert(api.Info.MaxAssessments, check.Equals, 5)
+}
+
+func (s *ServerSuite) TestAnalyze(c *check.C) {
+	api, err := s.srv.NewAPI("SSLScanTester", "1.0.0")
+
+	c.Assert(err, check.IsNil)
+
+	progress, err := api.Analyze("essentialkaos.com", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+
+	// Not ready yet, internal poll moves assessment to the first IN_PROGRESS step
+	_, err = progress.GetEndpointInfo("5.79.108.150", false)
+	c.Assert(err, check.NotNil)
+
+	var statuses []string
+	var info *sslscan.AnalyzeInfo
+
+	for i := 0; i < 10; i++ {
+		info, err = progress.Info(false, false)
+
+		c.Assert(err, check.IsNil)
+
+		statuses = append(statuses, info.Status)
+
+		if info.Status == sslscan.STATUS_READY {
+			break
+		}
+
+		c.Assert(info.Endpoints[0].Progress < 100, check.Equals, true)
+	}
+
+	c.Assert(statuses, check.DeepEquals, []string{
+		sslscan.STATUS_IN_PROGRESS, sslscan.STATUS_IN_PROGRESS, sslscan.STATUS_READY,
+	})
+
+	c.Assert(info.Endpoints, check.HasLen, 1)
+	c.Assert(info.Endpoints[0].Grade, check.Equals, "A+")
+	c.Assert(info.Endpoints[0].Progress, check.Equals, 100)
+	c.Assert(info.Endpoints[0].Details, check.IsNil)
+
+	info, err = progress.Info(true, false)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Endpoints[0].Details, check.NotNil)
+
+	endpoint, err := progress.GetEndpointInfo("5.79.108.150", false)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(endpoint.Details, check.NotNil)
+	c.Assert(endpoint.Details.Protocols, check.HasLen, 2)
+
+	_, err = progress.GetEndpointInfo("127.0.0.1", false)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ServerSuite) TestUnknownHost(c *check.C) {
+	s.srv.DNSSteps = 0
+
+	api, err := s.srv.NewAPI("SSLScanTester", "1.0.0")
+
+	c.Assert(err, check.IsNil)
+
+	progress, err := api.Analyze("unknown.com", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+
+	info, err := progress.Info(false, false)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Status, check.Equals, sslscan.STATUS_ERROR)
+	c.Assert(info.StatusMessage, check.Equals, "Unable to resolve domain name")
+}
+
+func (s *ServerSuite) TestErrors(c *check.C) {
+	s.srv.InjectError(529, 1)
+
+	_, err := s.srv.NewAPI("SSLScanTester", "1.0.0")
+	c.Assert(err, check.ErrorMatches, "API return HTTP code 529")
+
+	api, err := s.srv.NewAPI("SSLScanTester", "1.0.0")
+	c.Assert(err, check.IsNil)
+
+	s.srv.InjectError(429, 1)
+	s.srv.InjectError(503, 1)
+
+	_, err = api.Analyze("essentialkaos.com", sslscan.AnalyzeParams{})
+	c.Assert(err, check.ErrorMatches, "API return HTTP code 429")
+
+	_, err = api.Analyze("essentialkaos.com", sslscan.AnalyzeParams{})
+	c.Assert(err, check.ErrorMatches, "API return HTTP code 503")
+
+	_, err = api.Analyze("essentialkaos.com", sslscan.AnalyzeParams{})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *ServerSuite) TestCache(c *check.C) {
+	s.srv.ProgressSteps = 0
+
+	api, err := s.srv.NewAPI("SSLScanTester", "1.0.0")
+
+	c.Assert(err, check.IsNil)
+
+	api.Cache = sslscan.NewMemoryCache(0)
+
+	progress, err := api.Analyze("essentialkaos.com", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+
+	for i := 0; i < 3; i++ {
+		info, err := progress.Info(true, true)
+
+		c.Assert(err, check.IsNil)
+		c.Assert(info.Status, check.Equals, sslscan.STATUS_READY)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := progress.GetEndpointInfo("5.79.108.150", true)
+		c.Assert(err, check.IsNil)
+	}
+
+	c.Assert(s.srv.Requests(), check.Equals, 4)
+	c.Assert(api.Cache.Stats(), check.DeepEquals, sslscan.CacheStats{Hits: 4, Misses: 2})
+}
+
+func (s *ServerSuite) TestCacheBypass(c *check.C) {
+	s.srv.ProgressSteps = 0
+
+	api, err := s.srv.NewAPI("SSLScanTester", "1.0.0")
+
+	c.Assert(err, check.IsNil)
+
+	api.Cache = sslscan.NewMemoryCache(0)
+
+	progress, err := api.Analyze("essentialkaos.com", sslscan.AnalyzeParams{})
+
+	c.Assert(err, check.IsNil)
+
+	_, err = progress.Info(true, true)
+	c.Assert(err, check.IsNil)
+	_, err = progress.GetEndpointInfo("5.79.108.150", true)
+	c.Assert(err, check.IsNil)
+
+	requests := s.srv.Requests()
+
+	for i := 0; i < 2; i++ {
+		_, err = progress.Info(true, false)
+		c.Assert(err, check.IsNil)
+		_, err = progress.GetEndpointInfo("5.79.108.150", false)
+		c.Assert(err, check.IsNil)
+	}
+
+	c.Assert(s.srv.Requests(), check.Equals, requests+4)
+	c.Assert(api.Cache.Stats().Hits, check.Equals, uint64(0))
+
+	scanner := sslscan.NewScanner(api)
+	scanner.Interval = 0
+
+	requests = s.srv.Requests()
+
+	info, err := scanner.Scan("essentialkaos.com", sslscan.AnalyzeParams{StartNew: true})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Status, check.Equals, sslscan.STATUS_READY)
+	c.Assert(s.srv.Requests(), check.Equals, requests+3)
+	c.Assert(api.Cache.Stats().Hits, check.Equals, uint64(0))
+
+	progress, err = api.Analyze("essentialkaos.com", sslscan.AnalyzeParams{StartNew: true})
+
+	c.Assert(err, check.IsNil)
+
+	requests = s.srv.Requests()
+
+	_, err = progress.Info(true, true)
+
+	c.Assert(err, check.IsNil)
+	c.Assert(s.srv.Requests(), check.Equals, requests+1)
+	c.Assert(api.Cache.Stats().Hits, check.Equals, uint64(0))
+
+	requests = s.srv.Requests()
+
+	// Results of new assessment are saved with the same keys which are
+	// used for cached results
+	info, err = scanner.Scan("essentialkaos.com", sslscan.AnalyzeParams{FromCache: true})
+
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Status, check.Equals, sslscan.STATUS_READY)
+	c.Assert(s.srv.Requests(), check.Equals, requests+1)
+	c.Assert(api.Cache.Stats().Hits, check.Equals, uint64(2))
+}
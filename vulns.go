@@ -0,0 +1,200 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Vulnerability contains info about vulnerability found on endpoint
+type Vulnerability struct {
+	Name       string      // vulnerability field name (e.g. "heartbleed")
+	Value      interface{} // vulnerability field value
+	Vulnerable bool        // true if endpoint is vulnerable
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// grades contains all grades from the best to the worst
+var grades = []string{"A+", "A", "A-", "B", "C", "D", "E", "F", "T", "M"}
+
+// vulnFields contains getters and checkers for all vulnerability fields
+var vulnFields = []struct {
+	name       string
+	value      func(d *EndpointDetails) interface{}
+	vulnerable func(d *EndpointDetails) bool
+}{
+	{
+		"vulnBeast",
+		func(d *EndpointDetails) interface{} { return d.VulnBeast },
+		func(d *EndpointDetails) bool { return d.VulnBeast },
+	},
+	{
+		"heartbleed",
+		func(d *EndpointDetails) interface{} { return d.Heartbleed },
+		func(d *EndpointDetails) bool { return d.Heartbleed },
+	},
+	{
+		"heartbeat",
+		func(d *EndpointDetails) interface{} { return d.Heartbeat },
+		nil, // heartbeat extension support is not a vulnerability
+	},
+	{
+		"openSslCcs",
+		func(d *EndpointDetails) interface{} { return d.OpenSSLCCS },
+		func(d *EndpointDetails) bool { return d.OpenSSLCCS == SSLCSC_STATUS_VULNERABLE },
+	},
+	{
+		"openSSLLuckyMinus20",
+		func(d *EndpointDetails) interface{} { return d.OpenSSLLuckyMinus20 },
+		func(d *EndpointDetails) bool { return d.OpenSSLLuckyMinus20 == LUCKY_MINUS_STATUS_VULNERABLE },
+	},
+	{
+		"ticketbleed",
+		func(d *EndpointDetails) interface{} { return d.Ticketbleed },
+		func(d *EndpointDetails) bool { return d.Ticketbleed == TICKETBLEED_STATUS_VULNERABLE },
+	},
+	{
+		"bleichenbacher",
+		func(d *EndpointDetails) interface{} { return d.Bleichenbacher },
+		func(d *EndpointDetails) bool {
+			return d.Bleichenbacher == BLEICHENBACHER_STATUS_VULNERABLE_WEAK ||
+				d.Bleichenbacher == BLEICHENBACHER_STATUS_VULNERABLE_STRONG
+		},
+	},
+	{
+		"zombiePoodle",
+		func(d *EndpointDetails) interface{} { return d.ZombiePoodle },
+		func(d *EndpointDetails) bool {
+			return d.ZombiePoodle == PADDING_ORACLE_STATUS_ZOMBIE_POODLE_VULNERABLE ||
+				d.ZombiePoodle == PADDING_ORACLE_STATUS_ZOMBIE_POODLE_EXPLOITABLE
+		},
+	},
+	{
+		"goldenDoodle",
+		func(d *EndpointDetails) interface{} { return d.GoldenDoodle },
+		func(d *EndpointDetails) bool {
+			return d.GoldenDoodle == PADDING_ORACLE_STATUS_GOLDEN_DOODLE_VULNERABLE ||
+				d.GoldenDoodle == PADDING_ORACLE_STATUS_GOLDEN_DOODLE_EXPLOITABLE
+		},
+	},
+	{
+		"zeroLengthPaddingOracle",
+		func(d *EndpointDetails) interface{} { return d.ZeroLengthPaddingOracle },
+		func(d *EndpointDetails) bool {
+			return d.ZeroLengthPaddingOracle == PADDING_ORACLE_STATUS_ZERO_LENGTH_VULNERABLE ||
+				d.ZeroLengthPaddingOracle == PADDING_ORACLE_STATUS_ZERO_LENGTH_EXPLOITABLE
+		},
+	},
+	{
+		"sleepingPoodle",
+		func(d *EndpointDetails) interface{} { return d.SleepingPoodle },
+		func(d *EndpointDetails) bool {
+			return d.SleepingPoodle == PADDING_ORACLE_STATUS_SLEEPING_POODLE_VULNERABLE ||
+				d.SleepingPoodle == PADDING_ORACLE_STATUS_SLEEPING_POODLE_EXPLOITABLE
+		},
+	},
+	{
+		"poodle",
+		func(d *EndpointDetails) interface{} { return d.Poodle },
+		func(d *EndpointDetails) bool { return d.Poodle },
+	},
+	{
+		"poodleTls",
+		func(d *EndpointDetails) interface{} { return d.PoodleTLS },
+		func(d *EndpointDetails) bool { return d.PoodleTLS == POODLE_STATUS_VULNERABLE },
+	},
+	{
+		"freak",
+		func(d *EndpointDetails) interface{} { return d.Freak },
+		func(d *EndpointDetails) bool { return d.Freak },
+	},
+	{
+		"logjam",
+		func(d *EndpointDetails) interface{} { return d.Logjam },
+		func(d *EndpointDetails) bool { return d.Logjam },
+	},
+	{
+		"drownVulnerable",
+		func(d *EndpointDetails) interface{} { return d.DrownVulnerable },
+		func(d *EndpointDetails) bool { return d.DrownVulnerable },
+	},
+	{
+		"supportsRc4",
+		func(d *EndpointDetails) interface{} { return d.SupportsRC4 },
+		func(d *EndpointDetails) bool { return d.SupportsRC4 },
+	},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// CheckVulnerabilities returns results of all vulnerability tests
+func CheckVulnerabilities(details *EndpointDetails) []*Vulnerability {
+	var result []*Vulnerability
+
+	if details == nil {
+		return result
+	}
+
+	for _, vf := range vulnFields {
+		if vf.vulnerable != nil {
+			result = append(result, &Vulnerability{vf.name, vf.value(details), vf.vulnerable(details)})
+		}
+	}
+
+	return result
+}
+
+// FindVulnerabilities returns list of vulnerabilities found on endpoint
+func FindVulnerabilities(details *EndpointDetails) []*Vulnerability {
+	var result []*Vulnerability
+
+	for _, vuln := range CheckVulnerabilities(details) {
+		if vuln.Vulnerable {
+			result = append(result, vuln)
+		}
+	}
+
+	return result
+}
+
+// CompareGrades compares two grades and returns 1 if first grade is better
+// than second, -1 if it's worse and 0 if grades are equal. Empty and unknown
+// grades are worse than any known grade.
+func CompareGrades(g1, g2 string) int {
+	r1, r2 := getGradeRank(g1), getGradeRank(g2)
+
+	switch {
+	case r1 < r2:
+		return 1
+	case r1 > r2:
+		return -1
+	}
+
+	return 0
+}
+
+// GradeScore returns numeric value of grade from 9 for "A+" to 0 for "M",
+// or -1 for empty or unknown grade
+func GradeScore(grade string) int {
+	return len(grades) - 1 - getGradeRank(grade)
+}
+
+// IsValidGrade returns true if given grade is known grade
+func IsValidGrade(grade string) bool {
+	return getGradeRank(grade) < len(grades)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getGradeRank returns grade position in the list of grades
+func getGradeRank(grade string) int {
+	for i, g := range grades {
+		if g == grade {
+			return i
+		}
+	}
+
+	return len(grades)
+}
@@ -0,0 +1,56 @@
+package sslscan
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                     Copyright (c) 2009-2020 ESSENTIAL KAOS                         //
+//      Apache License, Version 2.0 <http://www.apache.org/licenses/LICENSE-2.0>      //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	check "pkg.re/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *SSLLabsSuite) TestFindVulnerabilities(c *check.C) {
+	c.Assert(FindVulnerabilities(nil), check.HasLen, 0)
+
+	endpoint := loadEndpointFixture(c, "responses/v3-2.1.3-2009q.json")
+
+	c.Assert(FindVulnerabilities(endpoint.Details), check.HasLen, 0)
+
+	endpoint.Details.Heartbeat = true
+	endpoint.Details.Poodle = true
+	endpoint.Details.Bleichenbacher = BLEICHENBACHER_STATUS_INCONSISTENT_RESULTS
+	endpoint.Details.GoldenDoodle = 5
+
+	vulns := FindVulnerabilities(endpoint.Details)
+
+	c.Assert(vulns, check.HasLen, 2)
+	c.Assert(vulns[0], check.DeepEquals, &Vulnerability{"goldenDoodle", 5, true})
+	c.Assert(vulns[1], check.DeepEquals, &Vulnerability{"poodle", true, true})
+
+	vulns = CheckVulnerabilities(endpoint.Details)
+
+	c.Assert(vulns, check.HasLen, len(vulnFields)-1)
+	c.Assert(vulns[0], check.DeepEquals, &Vulnerability{"vulnBeast", false, false})
+}
+
+func (s *SSLLabsSuite) TestCompareGrades(c *check.C) {
+	c.Assert(CompareGrades("A+", "A"), check.Equals, 1)
+	c.Assert(CompareGrades("A-", "A"), check.Equals, -1)
+	c.Assert(CompareGrades("B", "B"), check.Equals, 0)
+	c.Assert(CompareGrades("T", "F"), check.Equals, -1)
+	c.Assert(CompareGrades("", "M"), check.Equals, -1)
+	c.Assert(CompareGrades("", "X"), check.Equals, 0)
+
+	c.Assert(GradeScore("A+"), check.Equals, 9)
+	c.Assert(GradeScore("F"), check.Equals, 2)
+	c.Assert(GradeScore("M"), check.Equals, 0)
+	c.Assert(GradeScore(""), check.Equals, -1)
+
+	c.Assert(IsValidGrade("A-"), check.Equals, true)
+	c.Assert(IsValidGrade("a"), check.Equals, false)
+	c.Assert(IsValidGrade(""), check.Equals, false)
+}